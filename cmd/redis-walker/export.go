@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/dump"
+)
+
+// exporter is a server or an RDB file that can be exported.
+type exporter interface {
+	ExportHeader(dir string) dump.Header
+	Export(ctx context.Context, dir string, fn func(*dump.Record) error) (exported, skipped int, err error)
+}
+
+// runExport writes dir to path ("-" for stdout) in the given format
+// with writeOutput.
+func runExport(m exporter, dir, path, format string) error {
+	return writeOutput(path, func(ctx context.Context, out io.Writer) error {
+		w, err := dump.NewWriter(format, out, m.ExportHeader(dir))
+		if err != nil {
+			return err
+		}
+		n, skipped, err := m.Export(ctx, dir, w.Write)
+		if err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		log.WithFields(log.Fields{"dir": dir, "file": path, "keys": n, "skipped": skipped}).Info("export finished")
+		return nil
+	})
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"context"
+
+	"github.com/nexusriot/redis-walker/pkg/dump"
+	"github.com/nexusriot/redis-walker/pkg/model"
+)
+
+// runImport imports the document at path into the folder to, or under
+// the original key names if to is empty, and logs the outcome with
+// runLoad.
+func runImport(m *model.Model, path, to, policy string, dryRun bool) error {
+	open := func() (dump.Reader, error) { return dump.Open(path) }
+	return runLoad("import", "imported", path, to, policy, dryRun, func(ctx context.Context, opts model.ImportOptions, progress func(done, total int)) (*model.ImportResult, error) {
+		return m.Import(ctx, open, opts, progress)
+	})
+}
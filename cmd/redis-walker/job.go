@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/model"
+)
+
+// writeOutput runs fill on path ("-" for stdout) until it returns or the
+// user interrupts. On failure or interrupt a partially written file is
+// removed.
+func writeOutput(path string, fill func(ctx context.Context, w io.Writer) error) (err error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	out := os.Stdout
+	if path != "-" {
+		if out, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600); err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := out.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(path)
+			}
+		}()
+	}
+	return fill(ctx, out)
+}
+
+// loader is Model.Import or Model.RestoreSnapshot with the file already
+// bound.
+type loader func(ctx context.Context, opts model.ImportOptions, progress func(done, total int)) (*model.ImportResult, error)
+
+// runLoad loads the file at path with load into the folder to, or under
+// the original key names if to is empty, and logs the outcome. verb is
+// "import" or "restore", done its past participle. Keys that fail are
+// logged one by one and make the load fail at the end.
+func runLoad(verb, done, path, to, policy string, dryRun bool, load loader) error {
+	p, err := model.ParseConflictPolicy(policy)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	res, err := load(ctx, model.ImportOptions{To: to, Policy: p, DryRun: dryRun}, func(n, total int) {
+		log.WithFields(log.Fields{"done": n, "total": total}).Debug(verb + " progress")
+	})
+	if res == nil {
+		return err
+	}
+	for _, f := range res.Failed {
+		log.WithError(f.Err).WithField("key", f.Key).Warn("key not " + done)
+	}
+	log.WithFields(log.Fields{
+		"file":     path,
+		"to":       to,
+		"policy":   p.String(),
+		"dry_run":  dryRun,
+		"keys":     res.Keys,
+		"existing": res.Conflicts,
+		"written":  res.Written,
+		"skipped":  res.Skipped,
+		"failed":   len(res.Failed),
+	}).Info(verb + " summary")
+	if err != nil {
+		return err
+	}
+	if n := len(res.Failed); n > 0 {
+		return fmt.Errorf("%d keys not %s", n, done)
+	}
+	return nil
+}
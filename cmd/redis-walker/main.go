@@ -2,14 +2,24 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"io"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/nexusriot/redis-walker/pkg/audit"
 	"github.com/nexusriot/redis-walker/pkg/config"
 	"github.com/nexusriot/redis-walker/pkg/controller"
+	"github.com/nexusriot/redis-walker/pkg/dump"
+	"github.com/nexusriot/redis-walker/pkg/keymap"
+	"github.com/nexusriot/redis-walker/pkg/logging"
 	"github.com/nexusriot/redis-walker/pkg/model"
+	"github.com/nexusriot/redis-walker/pkg/theme"
+	"github.com/nexusriot/redis-walker/pkg/trash"
 )
 
 type stringFlag struct {
@@ -40,15 +50,87 @@ func (f *boolFlag) Set(s string) error {
 	return nil
 }
 
+type intFlag struct {
+	value int
+	set   bool
+}
+
+func (f *intFlag) String() string { return strconv.Itoa(f.value) }
+func (f *intFlag) Set(s string) error {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	if v < 0 {
+		return strconv.ErrRange
+	}
+	f.value = v
+	f.set = true
+	return nil
+}
+
+type durationFlag struct {
+	value time.Duration
+	set   bool
+}
+
+func (f *durationFlag) String() string { return f.value.String() }
+func (f *durationFlag) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if v <= 0 {
+		return strconv.ErrRange
+	}
+	f.value = v
+	f.set = true
+	return nil
+}
+
+// logRingSize is how many recent log entries the in-app log viewer keeps.
+const logRingSize = 2000
+
 func main() {
 	var (
-		hostFlag     = &stringFlag{value: "127.0.0.1"}
-		portFlag     = &stringFlag{value: "6379"}
-		dbFlag       = &stringFlag{value: "0"}
-		debugFlag    = &boolFlag{value: false}
-		usernameFlag = &stringFlag{value: ""} // Redis ACL username
-		passwordFlag = &stringFlag{value: ""} // Redis password
-		excludeFlag  = &stringFlag{value: ""} // comma-separated prefixes
+		hostFlag      = &stringFlag{value: "127.0.0.1"}
+		portFlag      = &stringFlag{value: "6379"}
+		dbFlag        = &stringFlag{value: "0"}
+		debugFlag     = &boolFlag{value: false}
+		usernameFlag  = &stringFlag{value: ""}  // Redis ACL username
+		passwordFlag  = &stringFlag{value: ""}  // Redis password
+		excludeFlag   = &stringFlag{value: ""}  // comma-separated prefixes
+		configFlag    = &stringFlag{value: ""}  // explicit config file path
+		themeFlag     = &stringFlag{value: ""}  // color theme
+		logFileFlag   = &stringFlag{value: ""}  // log file path
+		logSizeFlag   = &intFlag{value: 10}     // MB before rotation
+		logKeepFlag   = &intFlag{value: 3}      // rotated files to keep
+		roFlag        = &boolFlag{value: false} // refuse all writes
+		replicaFlag   = &boolFlag{value: false} // READONLY on connect, implies read-only
+		confirmFlag   = &intFlag{value: controller.DefaultConfirmThreshold}
+		trashFlag     = &boolFlag{value: true}  // keep deleted keys for undo
+		trashFile     = &stringFlag{value: ""}  // trash file path
+		trashOpsFlag  = &intFlag{value: 100}    // operations kept in the trash
+		trashMBFlag   = &intFlag{value: 256}    // largest captured operation, MB
+		auditFlag     = &stringFlag{value: ""}  // audit journal path
+		liveFlag      = &boolFlag{value: false} // refresh from keyspace notifications
+		notifyFlag    = &boolFlag{value: false} // may enable notify-keyspace-events
+		watchFlag     = &durationFlag{value: controller.DefaultWatchInterval}
+		monitorFlag   = &boolFlag{value: false} // permit MONITOR in read-only mode
+		historyFlag   = &stringFlag{value: ""}  // console history path
+		exportFlag    = &stringFlag{value: ""}  // folder to export without the UI
+		exportFile    = &stringFlag{value: "-"} // export destination, "-" = stdout
+		exportFormat  = &stringFlag{value: "json"}
+		importFlag    = &stringFlag{value: ""} // file to import without the UI
+		importTo      = &stringFlag{value: ""} // target folder of -import
+		importPolicy  = &stringFlag{value: "skip"}
+		dryRunFlag    = &boolFlag{value: false} // -import/-restore only report what they would do
+		rdbFlag       = &stringFlag{value: ""}  // RDB file to browse instead of a server
+		snapFlag      = &stringFlag{value: ""}  // folder to snapshot without the UI
+		snapFile      = &stringFlag{value: "-"} // snapshot destination, "-" = stdout
+		restoreFlag   = &stringFlag{value: ""}  // snapshot to restore without the UI
+		restoreTo     = &stringFlag{value: ""}  // target folder of -restore
+		restorePolicy = &stringFlag{value: "skip"}
 	)
 
 	flag.Var(hostFlag, "host", "redis host (default: 127.0.0.1)")
@@ -59,20 +141,63 @@ func main() {
 	flag.Var(passwordFlag, "password", "redis password (optional)")
 	flag.Var(excludeFlag, "exclude-prefixes",
 		"comma-separated list of key prefixes to exclude (e.g. '/pcp:,/metrics:')")
+	flag.Var(configFlag, "config",
+		"path to config file (default: $"+config.EnvConfigPath+", $XDG_CONFIG_HOME/redis-walker/config.json, ~/.config/redis-walker/config.json, "+config.DefaultConfigPath+")")
+	flag.Var(themeFlag, "theme", "color theme: default, light or none (default: none if $NO_COLOR is set)")
+	flag.Var(logFileFlag, "log-file",
+		"write logs to this file (default with -debug: "+config.DefaultLogPath()+")")
+	flag.Var(logSizeFlag, "log-max-size", "rotate the log file after this many MB (default: 10)")
+	flag.Var(logKeepFlag, "log-max-backups", "number of rotated log files to keep (default: 3)")
+	flag.Var(roFlag, "read-only", "read-only safe mode: disable create, edit, rename and delete (true/false)")
+	flag.Var(replicaFlag, "replica", "send READONLY on connect to read from a (cluster) replica; implies -read-only")
+	flag.Var(confirmFlag, "confirm-threshold",
+		"keys in a folder from which delete/rename requires typing its name, 0 = never (default: 100)")
+	flag.Var(trashFlag, "trash", "keep deleted and overwritten keys for undo/restore (true/false, default: true)")
+	flag.Var(trashFile, "trash-file", "trash file path (default: "+config.DefaultTrashPath()+")")
+	flag.Var(trashOpsFlag, "trash-max-ops", "operations kept in the trash, 0 = unlimited (default: 100)")
+	flag.Var(trashMBFlag, "trash-max-size",
+		fmt.Sprintf("largest operation captured in the trash in MB, at most %d; 0 = %d (default: 256)", trash.MaxOpBytes>>20, trash.MaxOpBytes>>20))
+	flag.Var(auditFlag, "audit-file", "append every mutation to this journal (default: "+config.DefaultAuditPath()+")")
+	flag.Var(liveFlag, "live", "refresh the list when other clients change keys, via keyspace notifications (true/false, default: false)")
+	flag.Var(notifyFlag, "enable-keyspace-events",
+		"turn on notify-keyspace-events on the server if it is off, for -live (CONFIG SET; default: false)")
+	flag.Var(watchFlag, "watch-interval", "how often pinned keys in the watch panel are polled (default: 1s)")
+	flag.Var(monitorFlag, "allow-monitor", "permit the MONITOR pane in read-only mode, where it is off by default (true/false)")
+	flag.Var(historyFlag, "history-file", "command console history (default: "+config.DefaultHistoryPath()+")")
+	flag.Var(exportFlag, "export", "export this folder ('/' for the whole database) and exit, without the UI")
+	flag.Var(exportFile, "export-file", "file written by -export, '-' for stdout (default: -)")
+	flag.Var(exportFormat, "export-format", "format of -export: "+strings.Join(dump.Formats, ", ")+" (default: json)")
+	flag.Var(importFlag, "import", "import this exported file and exit, without the UI")
+	flag.Var(importTo, "import-to", "folder -import writes into, replacing the exported folder (default: keep key names)")
+	flag.Var(importPolicy, "import-policy", "what -import does with existing keys: "+strings.Join(model.ConflictPolicies, ", ")+" (default: skip)")
+	flag.Var(dryRunFlag, "dry-run", "with -import or -restore, only report what would be written")
+	flag.Var(rdbFlag, "rdb", "browse this RDB file offline and read-only, without a server (-db selects the database)")
+	flag.Var(snapFlag, "snapshot", "snapshot this folder ('/' for the whole database) with DUMP and exit, without the UI")
+	flag.Var(snapFile, "snapshot-file", "file written by -snapshot, '-' for stdout (default: -)")
+	flag.Var(restoreFlag, "restore", "restore this snapshot file with RESTORE and exit, without the UI")
+	flag.Var(restoreTo, "restore-to", "folder -restore writes into, replacing the snapshotted folder (default: keep key names)")
+	flag.Var(restorePolicy, "restore-policy", "what -restore does with existing keys: "+strings.Join(model.ConflictPolicies, ", ")+" (overwrite uses REPLACE; default: skip)")
 	flag.Parse()
 
-	// Logging setup
-	log.SetOutput(os.Stderr)
-	log.SetFormatter(&log.TextFormatter{
+	// Logging setup. Entries reach stderr (later the log file) through
+	// logOut and the in-app viewer through logRing; the logger runs at the
+	// most verbose level either of them needs, so nothing below it is
+	// formatted at all.
+	logOut := logging.NewOutput(os.Stderr, log.InfoLevel, &log.TextFormatter{
 		FullTimestamp:   true,
 		TimestampFormat: "2006-01-02 15:04:05",
 	})
+	log.SetOutput(io.Discard)
+	log.SetLevel(log.InfoLevel)
+	log.AddHook(logOut)
+	logRing := logging.NewRing(logRingSize)
+	log.AddHook(logRing)
 
-	// Load config (optional)
-	cfg, err := config.Load(config.DefaultConfigPath)
+	// Load config (optional unless given explicitly)
+	cfg, cfgPath, err := config.Discover(configFlag.value)
 	if err != nil {
-		log.WithError(err).Warn("failed to load config file, using flags/defaults only")
-		cfg = &config.Config{}
+		log.WithError(err).Error("failed to load config file")
+		os.Exit(1)
 	}
 
 	// Resolve host: flag wins over config, else default in flag struct.
@@ -126,10 +251,134 @@ func main() {
 		excludePrefixes = cfg.ExcludePrefixes
 	}
 
+	// Resolve read-only / replica mode
+	readOnly := roFlag.value
+	if !roFlag.set && cfg.ReadOnly != nil {
+		readOnly = *cfg.ReadOnly
+	}
+	replica := replicaFlag.value
+	if !replicaFlag.set && cfg.Replica != nil {
+		replica = *cfg.Replica
+	}
+	readOnly = readOnly || replica || rdbFlag.set
+
+	confirmThreshold := confirmFlag.value
+	if !confirmFlag.set && cfg.ConfirmThreshold != nil {
+		confirmThreshold = *cfg.ConfirmThreshold
+	}
+
+	// Resolve trash. It is only opened when writes are possible.
+	useTrash := trashFlag.value
+	if !trashFlag.set && cfg.Trash != nil {
+		useTrash = *cfg.Trash
+	}
+	trashPath := trashFile.value
+	if !trashFile.set {
+		trashPath = cfg.TrashFile
+	}
+	if trashPath == "" {
+		trashPath = config.DefaultTrashPath()
+	}
+	trashOps := trashOpsFlag.value
+	if !trashOpsFlag.set && cfg.TrashMaxOps != nil {
+		trashOps = *cfg.TrashMaxOps
+	}
+	trashMB := trashMBFlag.value
+	if !trashMBFlag.set && cfg.TrashMaxBytes != nil {
+		trashMB = *cfg.TrashMaxBytes
+	}
+	if trashMB < 0 || trashMB > trash.MaxOpBytes>>20 {
+		log.Errorf("-trash-max-size must be between 0 and %d", trash.MaxOpBytes>>20)
+		os.Exit(1)
+	}
+	var trashStore *trash.Store
+	if useTrash && !readOnly {
+		trashStore, err = trash.Open(trashPath, trashOps)
+		if err != nil {
+			log.WithError(err).WithField("trash_file", trashPath).Error("failed to open trash")
+			os.Exit(1)
+		}
+	}
+
+	// Resolve audit journal: flag, then config, then the default path. It
+	// is only opened when writes are possible; -export and -snapshot only
+	// read.
+	auditPath := auditFlag.value
+	if !auditFlag.set {
+		auditPath = cfg.AuditFile
+	}
+	if auditPath == "" {
+		auditPath = config.DefaultAuditPath()
+	}
+	var journal *audit.Journal
+	if !readOnly && !exportFlag.set && !snapFlag.set {
+		journal, err = audit.Open(auditPath)
+		if err != nil {
+			log.WithError(err).WithField("audit_file", auditPath).Error("failed to open audit journal")
+			os.Exit(1)
+		}
+		defer journal.Close()
+	} else {
+		auditPath = ""
+	}
+
+	// Resolve key bindings: defaults with config overrides applied.
+	bindings := make(map[string][]string, len(cfg.Keymap))
+	for action, keys := range cfg.Keymap {
+		bindings[action] = keys
+	}
+	keys, err := keymap.New(bindings)
+	if err != nil {
+		log.WithError(err).WithField("config_path", cfgPath).Error("invalid keymap")
+		os.Exit(1)
+	}
+
+	// Resolve theme: flag, then config, then $NO_COLOR.
+	themeName := themeFlag.value
+	if !themeFlag.set {
+		themeName = cfg.Theme
+		if themeName == "" && os.Getenv("NO_COLOR") != "" {
+			themeName = "none"
+		}
+	}
+	th, err := theme.New(themeName, cfg.ColorRules)
+	if err != nil {
+		log.WithError(err).WithField("config_path", cfgPath).Error("invalid theme")
+		os.Exit(1)
+	}
+
 	if debug {
 		log.SetLevel(log.DebugLevel)
-	} else {
-		log.SetLevel(log.InfoLevel)
+		logOut.SetLevel(log.DebugLevel)
+	}
+
+	// Resolve log file: flag, then config, then a default path when debug
+	// is on. Without a file, logs only reach the in-app viewer while the
+	// TUI is running so they never draw over the screen.
+	logPath := logFileFlag.value
+	if !logFileFlag.set {
+		logPath = cfg.LogFile
+	}
+	if logPath == "" && debug {
+		logPath = config.DefaultLogPath()
+	}
+	logSize := logSizeFlag.value
+	if !logSizeFlag.set && cfg.LogMaxSizeMB != nil {
+		logSize = *cfg.LogMaxSizeMB
+	}
+	logKeep := logKeepFlag.value
+	if !logKeepFlag.set && cfg.LogMaxBackups != nil {
+		logKeep = *cfg.LogMaxBackups
+	}
+	var logFile *logging.RotatingFile
+	if logPath != "" {
+		logFile, err = logging.OpenRotatingFile(logPath, int64(logSize)<<20, logKeep)
+		if err != nil {
+			log.WithError(err).WithField("log_file", logPath).Error("failed to open log file")
+			os.Exit(1)
+		}
+		defer logFile.Close()
+		logOut.SetWriter(io.MultiWriter(os.Stderr, logFile))
 	}
 
 	log.WithFields(log.Fields{
@@ -140,17 +389,155 @@ func main() {
 		"username":         username,
 		"auth_enabled":     password != "",
 		"exclude_prefixes": excludePrefixes,
-		"config_path":      config.DefaultConfigPath,
+		"config_path":      cfgPath,
+		"log_file":         logPath,
+		"read_only":        readOnly,
+		"replica":          replica,
+		"trash":            trashStore != nil,
+		"audit_file":       auditPath,
+		"rdb":              rdbFlag.value,
 	}).Info("Starting redis-walker")
 
-	m, err := model.NewModel(host, port, dbIdx, username, password, excludePrefixes)
-	if err != nil {
+	allowMonitor := monitorFlag.value
+	if !monitorFlag.set && cfg.AllowMonitor != nil {
+		allowMonitor = *cfg.AllowMonitor
+	}
+
+	// The UI browses either a server or, with -rdb, a file.
+	var (
+		m     *model.Model
+		store controller.Store
+	)
+	if rdbFlag.set {
+		if importFlag.set || snapFlag.set || restoreFlag.set {
+			log.Error("-import, -snapshot and -restore need a server; they cannot be combined with -rdb")
+			os.Exit(1)
+		}
+		off, err := model.OpenRDB(rdbFlag.value, dbIdx, excludePrefixes)
+		if err != nil {
+			log.WithError(err).Error("failed to load RDB file")
+			os.Exit(1)
+		}
+		store = off
+	} else if m, err = model.NewModel(model.Options{
+		Host:            host,
+		Port:            port,
+		DB:              dbIdx,
+		Username:        username,
+		Password:        password,
+		ExcludePrefixes: excludePrefixes,
+		ReadOnly:        readOnly,
+		Replica:         replica,
+		Trash:           trashStore,
+		TrashMaxBytes:   int64(trashMB) << 20,
+		Audit:           journal,
+		AllowMonitor:    allowMonitor,
+	}); err != nil {
 		log.WithError(err).Error("failed to create Redis model")
 		os.Exit(1)
+	} else {
+		store = m
+	}
+
+	// Non-interactive export: write the folder and exit without the UI.
+	if exportFlag.set {
+		if err := runExport(store, exportFlag.value, exportFile.value, exportFormat.value); err != nil {
+			log.WithError(err).Error("export failed")
+			os.Exit(1)
+		}
+		return
 	}
 
-	ctrl := controller.NewController(m, host, port, dbIdx, debug)
-	if err := ctrl.Run(); err != nil {
+	// Non-interactive import, likewise.
+	if importFlag.set {
+		if err := runImport(m, importFlag.value, importTo.value, importPolicy.value, dryRunFlag.value); err != nil {
+			log.WithError(err).Error("import failed")
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Non-interactive snapshot and restore, likewise.
+	if snapFlag.set {
+		if err := runSnapshot(m, snapFlag.value, snapFile.value); err != nil {
+			log.WithError(err).Error("snapshot failed")
+			os.Exit(1)
+		}
+		return
+	}
+	if restoreFlag.set {
+		if err := runRestore(m, restoreFlag.value, restoreTo.value, restorePolicy.value, dryRunFlag.value); err != nil {
+			log.WithError(err).Error("restore failed")
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Resolve live refresh. Keyspace notifications are off by default in
+	// Redis; only change the server setting if the user allowed it.
+	live := liveFlag.value
+	if !liveFlag.set && cfg.LiveRefresh != nil {
+		live = *cfg.LiveRefresh
+	}
+	enableNotify := notifyFlag.value
+	if !notifyFlag.set && cfg.EnableKeyspaceEvents != nil {
+		enableNotify = *cfg.EnableKeyspaceEvents
+	}
+	if live && m != nil {
+		flags, err := m.KeyspaceEvents()
+		switch {
+		case err != nil:
+			log.WithError(err).Warn("cannot read notify-keyspace-events; live refresh may not work")
+		case model.KeyspaceEventsUsable(flags):
+		case enableNotify && !readOnly:
+			if err := m.EnableKeyspaceEvents(); err != nil {
+				log.WithError(err).Warn("failed to enable keyspace notifications; live refresh disabled")
+			}
+		default:
+			log.WithField("notify_keyspace_events", flags).
+				Warn("keyspace notifications are off on the server; live refresh needs -enable-keyspace-events")
+		}
+	}
+
+	watchInterval := watchFlag.value
+	if !watchFlag.set && cfg.WatchInterval != "" {
+		watchInterval, _ = time.ParseDuration(cfg.WatchInterval) // checked by Validate
+	}
+
+	// Resolve console history: flag, then config, then the default path.
+	historyPath := historyFlag.value
+	if !historyFlag.set {
+		historyPath = cfg.HistoryFile
+	}
+	if historyPath == "" {
+		historyPath = config.DefaultHistoryPath()
+	}
+
+	ctrl := controller.NewController(store, controller.Options{
+		Debug: debug,
+		Keys:  keys,
+		Theme: th,
+		Logs:  logRing,
+
+		ConfirmThreshold: confirmThreshold,
+		Live:             live,
+		WatchKeys:        cfg.WatchKeys,
+		WatchInterval:    watchInterval,
+		HistoryFile:      historyPath,
+	})
+
+	if logFile != nil {
+		logOut.SetWriter(logFile)
+	} else {
+		logOut.SetWriter(io.Discard)
+	}
+	err = ctrl.Run()
+	if logFile != nil {
+		logOut.SetWriter(io.MultiWriter(os.Stderr, logFile))
+	} else {
+		logOut.SetWriter(os.Stderr)
+	}
+	if err != nil {
 		log.WithError(err).Error("redis-walker exited with error")
 		os.Exit(1)
 	}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/model"
+	"github.com/nexusriot/redis-walker/pkg/snapshot"
+)
+
+// runSnapshot writes a snapshot of dir to path ("-" for stdout) with
+// writeOutput.
+func runSnapshot(m *model.Model, dir, path string) error {
+	return writeOutput(path, func(ctx context.Context, out io.Writer) error {
+		w, err := snapshot.NewWriter(out, m.SnapshotHeader(dir))
+		if err != nil {
+			return err
+		}
+		n, err := m.SnapshotDir(ctx, dir, w.Write)
+		if err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		log.WithFields(log.Fields{"dir": dir, "file": path, "keys": n}).Info("snapshot finished")
+		return nil
+	})
+}
+
+// runRestore restores the snapshot at path into the folder to, or under
+// the original key names if to is empty, like runImport.
+func runRestore(m *model.Model, path, to, policy string, dryRun bool) error {
+	open := func() (*snapshot.Reader, error) { return snapshot.Open(path) }
+	return runLoad("restore", "restored", path, to, policy, dryRun, func(ctx context.Context, opts model.ImportOptions, progress func(done, total int)) (*model.ImportResult, error) {
+		return m.RestoreSnapshot(ctx, open, opts, progress)
+	})
+}
@@ -0,0 +1,155 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Value describes a key's value without storing it.
+type Value struct {
+	Type   string `json:"type,omitempty"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256,omitempty"` // string values, and DUMP payloads
+	Of     string `json:"of,omitempty"`     // "dump" if Size and SHA256 describe the DUMP payload
+}
+
+// Digest describes a string value by its size and SHA-256.
+func Digest(v string) *Value {
+	sum := sha256.Sum256([]byte(v))
+	return &Value{Type: "string", Size: int64(len(v)), SHA256: hex.EncodeToString(sum[:])}
+}
+
+// DumpDigest describes a value of any type by the size and SHA-256 of its
+// DUMP payload.
+func DumpDigest(typ string, payload []byte) *Value {
+	sum := sha256.Sum256(payload)
+	return &Value{Type: typ, Size: int64(len(payload)), SHA256: hex.EncodeToString(sum[:]), Of: "dump"}
+}
+
+// Change is the old and new value of one key of an operation on several
+// keys.
+type Change struct {
+	Key string `json:"key"`
+	Old *Value `json:"old,omitempty"`
+	New *Value `json:"new,omitempty"`
+}
+
+// Entry is one mutation, successful or not.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	User   string    `json:"user"`           // OS user running redis-walker
+	Host   string    `json:"host,omitempty"` // machine running redis-walker
+	Server string    `json:"server"`
+	DB     int       `json:"db"`
+	Op     string    `json:"op"`               // set, del, deldir, mkdir, rename, restore, ...
+	Keys   []string  `json:"keys,omitempty"`   // every key written or deleted
+	Dir    string    `json:"dir,omitempty"`    // folder of recursive operations
+	Target string    `json:"target,omitempty"` // new folder of a rename or restore
+	Count  int       `json:"count,omitempty"`  // number of keys affected
+	Old    *Value    `json:"old,omitempty"`
+	New    *Value    `json:"new,omitempty"`
+	Values []Change  `json:"values,omitempty"` // per key, for recursive operations
+	Error  string    `json:"error,omitempty"`  // set when the mutation failed
+}
+
+// Journal is an append-only JSON-lines file of entries.
+type Journal struct {
+	path string
+	user string
+	host string
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+// Open opens (or creates) the journal at path for appending.
+func Open(path string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create audit dir: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{path: path, f: f}
+	if u, err := user.Current(); err == nil {
+		j.user = u.Username
+	} else {
+		j.user = os.Getenv("USER")
+	}
+	j.host, _ = os.Hostname()
+	return j, nil
+}
+
+// Path returns the journal file.
+func (j *Journal) Path() string { return j.path }
+
+// Record stamps e with the time and user and appends it as one line.
+func (j *Journal) Record(e Entry) error {
+	e.Time = time.Now()
+	e.User = j.user
+	e.Host = j.host
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return j.f.Sync()
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.f.Close()
+}
+
+// ReadFile returns the last limit entries of the journal at path, newest
+// first; limit <= 0 means all. Unparsable lines are skipped.
+func ReadFile(path string, limit int) ([]Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var all []Entry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1<<28)
+	for sc.Scan() {
+		var e Entry
+		if json.Unmarshal(sc.Bytes(), &e) != nil {
+			continue
+		}
+		all = append(all, e)
+		if limit > 0 && len(all) > 2*limit {
+			all = append(all[:0], all[len(all)-limit:]...)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("audit %s: %w", path, err)
+	}
+	if limit > 0 && len(all) > limit {
+		all = all[len(all)-limit:]
+	}
+	out := make([]Entry, 0, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		out = append(out, all[i])
+	}
+	return out, nil
+}
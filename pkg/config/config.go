@@ -1,12 +1,33 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/nexusriot/redis-walker/pkg/trash"
 )
 
-// Config is loaded from /etc/redis-walker/config.json
+// ColorRule colors keys in the list. All criteria that are set must
+// match; rules are evaluated in order and the first match wins.
+type ColorRule struct {
+	Glob      string `json:"glob,omitempty"`  // matched against the base name, or the full path if it contains '/'
+	Regex     string `json:"regex,omitempty"` // matched against the full path
+	Type      string `json:"type,omitempty"`  // Redis type: string, hash, list, set, zset, stream
+	Color     string `json:"color"`           // color name or #rrggbb
+	Bold      bool   `json:"bold,omitempty"`
+	Underline bool   `json:"underline,omitempty"`
+}
+
+// Config is loaded from the first config file found (see SearchPaths).
 // CLI flags override any values defined here.
 type Config struct {
 	Host            string   `json:"host"`
@@ -16,9 +37,160 @@ type Config struct {
 	Username        string   `json:"username"`         // optional Redis ACL username
 	Password        string   `json:"password"`         // optional Redis password
 	ExcludePrefixes []string `json:"exclude_prefixes"` // key prefixes to hide
+
+	// Keymap maps action names (e.g. "jump") to one key or a list of keys.
+	Keymap map[string]StringList `json:"keymap"`
+
+	Theme      string      `json:"theme"`       // default, light or none; checked by the theme package
+	ColorRules []ColorRule `json:"color_rules"` // replaces the built-in '_' rule when set
+
+	LogFile       string `json:"log_file"`        // defaults to DefaultLogPath() when debug is on
+	LogMaxSizeMB  *int   `json:"log_max_size_mb"` // rotate once the file exceeds this size
+	LogMaxBackups *int   `json:"log_max_backups"` // rotated files to keep
+
+	ReadOnly *bool `json:"read_only"` // disable all writes
+	Replica  *bool `json:"replica"`   // send READONLY on connect; implies read_only
+
+	ConfirmThreshold *int `json:"confirm_threshold"` // keys from which recursive ops need the folder name typed
+
+	Trash         *bool  `json:"trash"`              // keep deleted/overwritten keys for undo (default true)
+	TrashFile     string `json:"trash_file"`         // defaults to DefaultTrashPath()
+	TrashMaxOps   *int   `json:"trash_max_ops"`      // operations kept in the trash
+	TrashMaxBytes *int   `json:"trash_max_bytes_mb"` // largest operation captured, in MB
+
+	AuditFile string `json:"audit_file"` // mutation journal, defaults to DefaultAuditPath()
+
+	LiveRefresh          *bool `json:"live_refresh"`           // refresh from keyspace notifications (default false)
+	EnableKeyspaceEvents *bool `json:"enable_keyspace_events"` // allow turning on notify-keyspace-events
+
+	WatchKeys     []string `json:"watch_keys"`     // keys pinned into the watch panel at startup
+	WatchInterval string   `json:"watch_interval"` // poll interval of the watch panel, e.g. "2s"
+
+	AllowMonitor *bool `json:"allow_monitor"` // permit MONITOR in read-only mode
+
+	HistoryFile string `json:"history_file"` // console history, defaults to DefaultHistoryPath()
+}
+
+const (
+	DefaultConfigPath = "/etc/redis-walker/config.json"
+
+	// EnvConfigPath names the environment variable that points to a config file.
+	EnvConfigPath = "REDIS_WALKER_CONFIG"
+
+	appDir     = "redis-walker"
+	configFile = "config.json"
+)
+
+// StateDir returns the per-user directory for logs and other runtime
+// state: $XDG_STATE_HOME/redis-walker or ~/.local/state/redis-walker.
+func StateDir() string {
+	if xdg := os.Getenv("XDG_STATE_HOME"); xdg != "" {
+		return filepath.Join(xdg, appDir)
+	}
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		return filepath.Join(home, ".local", "state", appDir)
+	}
+	return filepath.Join(os.TempDir(), appDir)
+}
+
+// DefaultLogPath is where logs go when debug is on and no log file is set.
+func DefaultLogPath() string {
+	return filepath.Join(StateDir(), "redis-walker.log")
+}
+
+// DefaultTrashPath is where deleted and overwritten keys are kept for undo.
+func DefaultTrashPath() string {
+	return filepath.Join(StateDir(), "trash.jsonl")
+}
+
+// DefaultAuditPath is the journal of every mutation made from redis-walker.
+func DefaultAuditPath() string {
+	return filepath.Join(StateDir(), "audit.jsonl")
+}
+
+// DefaultHistoryPath is where the command console keeps its history.
+func DefaultHistoryPath() string {
+	return filepath.Join(StateDir(), "console_history")
+}
+
+// SearchPaths returns the config locations probed when no -config flag is
+// given, in lookup order: $REDIS_WALKER_CONFIG, $XDG_CONFIG_HOME,
+// ~/.config and finally /etc.
+func SearchPaths() []string {
+	var paths []string
+	if p := os.Getenv(EnvConfigPath); p != "" {
+		paths = append(paths, p)
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		paths = append(paths, filepath.Join(xdg, appDir, configFile))
+	}
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		p := filepath.Join(home, ".config", appDir, configFile)
+		if len(paths) == 0 || paths[len(paths)-1] != p {
+			paths = append(paths, p)
+		}
+	}
+	return append(paths, DefaultConfigPath)
+}
+
+// StringList accepts either a single JSON string or an array of strings.
+type StringList []string
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*l = StringList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return &json.UnmarshalTypeError{Value: jsonKind(data), Type: reflect.TypeOf(l).Elem()}
+	}
+	*l = many
+	return nil
+}
+
+func jsonKind(data []byte) string {
+	switch b := bytes.TrimSpace(data); {
+	case len(b) == 0:
+		return "value"
+	case b[0] == '{':
+		return "object"
+	case b[0] == '[':
+		return "array"
+	case b[0] == 't' || b[0] == 'f':
+		return "bool"
+	case b[0] == 'n':
+		return "null"
+	}
+	return "number"
 }
 
-const DefaultConfigPath = "/etc/redis-walker/config.json"
+// Discover loads the config file to use. An explicit path (from -config)
+// or $REDIS_WALKER_CONFIG must exist; the other locations are optional.
+// It returns the path actually loaded, or "" if no file was found.
+func Discover(explicit string) (*Config, string, error) {
+	if explicit != "" {
+		cfg, err := loadFile(explicit)
+		if err != nil {
+			return nil, explicit, err
+		}
+		return cfg, explicit, nil
+	}
+
+	env := os.Getenv(EnvConfigPath)
+	for _, p := range SearchPaths() {
+		cfg, err := loadFile(p)
+		if errors.Is(err, os.ErrNotExist) && p != env {
+			continue
+		}
+		if err != nil {
+			return nil, p, err
+		}
+		return cfg, p, nil
+	}
+	return &Config{}, "", nil
+}
 
 // Load reads config from the given path. If the file does not exist,
 // it returns an empty config and no error.
@@ -26,24 +198,138 @@ func Load(path string) (*Config, error) {
 	if path == "" {
 		path = DefaultConfigPath
 	}
-
-	f, err := os.Open(path)
-	if os.IsNotExist(err) {
+	cfg, err := loadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
 		// Config is optional
 		return &Config{}, nil
 	}
+	return cfg, err
+}
+
+func loadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse decodes and validates a config document. Unknown fields and type
+// mismatches are reported with the name of the offending field.
+func Parse(data []byte) (*Config, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
 
 	var cfg Config
-	if err := json.NewDecoder(f).Decode(&cfg); err != nil {
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, describeDecodeError(data, err)
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the top-level object")
+	}
+	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
 	return &cfg, nil
 }
 
+// Validate checks field values that decode fine but make no sense.
+func (c *Config) Validate() error {
+	if c.Port != "" {
+		p, err := strconv.Atoi(c.Port)
+		if err != nil || p < 1 || p > 65535 {
+			return fmt.Errorf("field %q: invalid port %q", "port", c.Port)
+		}
+	}
+	if c.DB != nil && *c.DB < 0 {
+		return fmt.Errorf("field %q: must not be negative", "db")
+	}
+	for name, keys := range c.Keymap {
+		for i, k := range keys {
+			if strings.TrimSpace(k) == "" {
+				return fmt.Errorf("field %q: entry %d is empty", "keymap."+name, i)
+			}
+		}
+	}
+	if c.LogMaxSizeMB != nil && *c.LogMaxSizeMB < 0 {
+		return fmt.Errorf("field %q: must not be negative", "log_max_size_mb")
+	}
+	if c.LogMaxBackups != nil && *c.LogMaxBackups < 0 {
+		return fmt.Errorf("field %q: must not be negative", "log_max_backups")
+	}
+	if c.ConfirmThreshold != nil && *c.ConfirmThreshold < 0 {
+		return fmt.Errorf("field %q: must not be negative", "confirm_threshold")
+	}
+	if c.TrashMaxOps != nil && *c.TrashMaxOps < 0 {
+		return fmt.Errorf("field %q: must not be negative", "trash_max_ops")
+	}
+	if c.TrashMaxBytes != nil && (*c.TrashMaxBytes < 0 || *c.TrashMaxBytes > trash.MaxOpBytes>>20) {
+		return fmt.Errorf("field %q: must be between 0 and %d", "trash_max_bytes_mb", trash.MaxOpBytes>>20)
+	}
+	if c.WatchInterval != "" {
+		d, err := time.ParseDuration(c.WatchInterval)
+		if err != nil {
+			return fmt.Errorf("field %q: %w", "watch_interval", err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("field %q: must be positive", "watch_interval")
+		}
+	}
+	for i, p := range c.ExcludePrefixes {
+		if strings.TrimSpace(p) == "" {
+			return fmt.Errorf("field %q: entry %d is empty", "exclude_prefixes", i)
+		}
+	}
+	return nil
+}
+
+func describeDecodeError(data []byte, err error) error {
+	var (
+		typeErr   *json.UnmarshalTypeError
+		syntaxErr *json.SyntaxError
+	)
+	switch {
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if typeErr.Type == reflect.TypeOf(StringList{}) {
+			// map values carry no field context; StringList is only used by keymap
+			return fmt.Errorf("field %q: expected a key or a list of keys, got JSON %s", "keymap", typeErr.Value)
+		}
+		if field == "" {
+			field = "(top level)"
+		}
+		return fmt.Errorf("field %q: expected %s, got JSON %s", field, typeErr.Type, typeErr.Value)
+	case errors.As(err, &syntaxErr):
+		line, col := lineCol(data, syntaxErr.Offset)
+		return fmt.Errorf("syntax error at line %d, column %d: %v", line, col, err)
+	case errors.Is(err, io.EOF):
+		return fmt.Errorf("file is empty")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return fmt.Errorf("unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	}
+	return err
+}
+
+func lineCol(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	line, col := 1, 1
+	for _, b := range data[:offset] {
+		if b == '\n' {
+			line++
+			col = 1
+			continue
+		}
+		col++
+	}
+	return line, col
+}
+
 // ParseExcludeList parses comma-separated prefixes.
 func ParseExcludeList(raw string) []string {
 	raw = strings.TrimSpace(raw)
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nexusriot/redis-walker/pkg/audit"
+)
+
+// auditLimit is the number of journal entries loaded into the viewer.
+const auditLimit = 1000
+
+// showAudit opens the audit journal viewer, newest entries first.
+func (c *Controller) showAudit() *tcell.EventKey {
+	j := c.model.Audit()
+	if j == nil {
+		c.error("Audit", fmt.Errorf("audit journal is disabled"), false)
+		return nil
+	}
+	entries, err := audit.ReadFile(j.Path(), auditLimit)
+	if err != nil {
+		c.error("Failed to read audit journal", err, false)
+		return nil
+	}
+
+	table := c.view.NewTable(
+		fmt.Sprintf(" Audit journal: %s  Enter=Details  Esc=Close ", tview.Escape(j.Path())),
+		"Time", "User", "Op", "Keys", "Old", "New", "Server/DB", "Result",
+	)
+	for i, e := range entries {
+		row := i + 1
+		result := "ok"
+		color := c.theme.Text
+		if e.Error != "" {
+			result = "error"
+			color = c.theme.Error
+		}
+		cells := []string{
+			e.Time.Format("2006-01-02 15:04:05"),
+			e.User,
+			e.Op,
+			auditKeys(e),
+			auditValue(e.Old),
+			auditValue(e.New),
+			fmt.Sprintf("%s/%d", e.Server, e.DB),
+			result,
+		}
+		for col, text := range cells {
+			table.SetCell(row, col, tview.NewTableCell(tview.Escape(text)).
+				SetTextColor(color).
+				SetExpansion(1))
+		}
+	}
+	if len(entries) > 0 {
+		table.Select(1, 0)
+	}
+
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case ev.Key() == tcell.KeyEsc, ev.Key() == tcell.KeyRune && ev.Rune() == 'q':
+			c.closePane("audit")
+			return nil
+		case ev.Key() == tcell.KeyEnter:
+			row, _ := table.GetSelection()
+			if row >= 1 && row <= len(entries) {
+				c.showAuditEntry(entries[row-1])
+			}
+			return nil
+		}
+		return ev
+	})
+
+	c.view.Pages.AddPage("audit", table, true, true)
+	c.view.App.SetFocus(table)
+	return nil
+}
+
+// showAuditEntry shows one journal entry as indented JSON.
+func (c *Controller) showAuditEntry(e audit.Entry) {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		c.error("Audit", err, false)
+		return
+	}
+	tv := tview.NewTextView().
+		SetText(string(data)).
+		SetScrollable(true)
+	tv.SetBorder(true).SetTitle(" Audit entry (Esc to close) ")
+	tv.SetDoneFunc(func(tcell.Key) {
+		c.view.Pages.RemovePage("modal")
+	})
+	height := min(strings.Count(string(data), "\n")+3, 30)
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(tv, 90, height), true, true)
+}
+
+func auditKeys(e audit.Entry) string {
+	switch {
+	case e.Dir != "" && e.Target != "":
+		return fmt.Sprintf("%s/ -> %s/ (%d)", e.Dir, e.Target, e.Count)
+	case e.Dir != "" && e.Op != "mkdir":
+		return fmt.Sprintf("%s/ (%d)", e.Dir, e.Count)
+	case len(e.Keys) == 1:
+		return e.Keys[0]
+	default:
+		return fmt.Sprintf("%d keys", e.Count)
+	}
+}
+
+func auditValue(v *audit.Value) string {
+	if v == nil {
+		return "-"
+	}
+	if v.SHA256 != "" {
+		return fmt.Sprintf("%s %s", humanBytes(v.Size), v.SHA256[:12])
+	}
+	return fmt.Sprintf("%s %s", v.Type, humanBytes(v.Size))
+}
@@ -0,0 +1,226 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nexusriot/redis-walker/pkg/model"
+)
+
+// clientColumns are the CLIENT LIST pane columns, in sort-cycle order.
+var clientColumns = []struct {
+	title string
+	text  func(model.ClientInfo) string
+	less  func(a, b model.ClientInfo) bool
+}{
+	{"ID", func(ci model.ClientInfo) string { return strconv.FormatInt(ci.ID, 10) },
+		func(a, b model.ClientInfo) bool { return a.ID < b.ID }},
+	{"Address", func(ci model.ClientInfo) string { return ci.Addr },
+		func(a, b model.ClientInfo) bool { return a.Addr < b.Addr }},
+	{"Name", func(ci model.ClientInfo) string { return ci.Name },
+		func(a, b model.ClientInfo) bool { return a.Name < b.Name }},
+	{"Age", func(ci model.ClientInfo) string { return ci.Age.String() },
+		func(a, b model.ClientInfo) bool { return a.Age < b.Age }},
+	{"Idle", func(ci model.ClientInfo) string { return ci.Idle.String() },
+		func(a, b model.ClientInfo) bool { return a.Idle < b.Idle }},
+	{"DB", func(ci model.ClientInfo) string { return strconv.Itoa(ci.DB) },
+		func(a, b model.ClientInfo) bool { return a.DB < b.DB }},
+	{"Last cmd", func(ci model.ClientInfo) string { return ci.Cmd },
+		func(a, b model.ClientInfo) bool { return a.Cmd < b.Cmd }},
+	{"Out buf", func(ci model.ClientInfo) string { return humanBytes(ci.OutputMem) },
+		func(a, b model.ClientInfo) bool { return a.OutputMem < b.OutputMem }},
+	{"Memory", func(ci model.ClientInfo) string { return humanBytes(ci.TotalMem) },
+		func(a, b model.ClientInfo) bool { return a.TotalMem < b.TotalMem }},
+}
+
+// showClients opens the CLIENT LIST pane.
+func (c *Controller) showClients() *tcell.EventKey {
+	if c.noServer("Clients") {
+		return nil
+	}
+	titles := make([]string, len(clientColumns))
+	for i, col := range clientColumns {
+		titles[i] = col.title
+	}
+	table := c.view.NewTable("", titles...)
+
+	var (
+		all, shown []model.ClientInfo
+		sortCol    int
+		desc       bool
+		filter     string
+	)
+	render := func() {
+		f := strings.ToLower(filter)
+		shown = shown[:0]
+		for _, ci := range all {
+			hay := strings.ToLower(strings.Join([]string{ci.Addr, ci.Name, ci.User, ci.Cmd, ci.Flags}, " "))
+			if f == "" || strings.Contains(hay, f) {
+				shown = append(shown, ci)
+			}
+		}
+		less := clientColumns[sortCol].less
+		sort.SliceStable(shown, func(i, j int) bool {
+			if desc {
+				return less(shown[j], shown[i])
+			}
+			return less(shown[i], shown[j])
+		})
+
+		for row := table.GetRowCount() - 1; row > 0; row-- {
+			table.RemoveRow(row)
+		}
+		for col, cc := range clientColumns {
+			title := cc.title
+			if col == sortCol {
+				title += map[bool]string{false: " ▲", true: " ▼"}[desc]
+			}
+			table.GetCell(0, col).SetText(title)
+		}
+		for i, ci := range shown {
+			color := c.theme.Text
+			if ci.Name == model.ClientName {
+				color = c.theme.Secondary
+			}
+			for col, cc := range clientColumns {
+				table.SetCell(i+1, col, tview.NewTableCell(tview.Escape(cc.text(ci))).
+					SetTextColor(color).
+					SetExpansion(1))
+			}
+		}
+		title := fmt.Sprintf(" Clients (%d of %d", len(shown), len(all))
+		if filter != "" {
+			title += fmt.Sprintf(", filter %q", filter)
+		}
+		table.SetTitle(tview.Escape(title + ")  s/S=Sort  /=Filter  r=Refresh  k=Kill  P=Pause writes  U=Unpause  Esc=Close "))
+	}
+	load := func() {
+		var err error
+		all, err = c.server.Clients()
+		if err != nil {
+			c.error("Failed to list clients", err, false)
+			return
+		}
+		render()
+		if row, _ := table.GetSelection(); row < 1 || row > len(shown) {
+			table.Select(min(1, len(shown)), 0)
+		}
+	}
+	selected := func() (model.ClientInfo, bool) {
+		row, _ := table.GetSelection()
+		if row < 1 || row > len(shown) {
+			return model.ClientInfo{}, false
+		}
+		return shown[row-1], true
+	}
+	confirm := func(header, text, verb string, action func() error) {
+		q := c.view.NewConfirmForm(header, text, "", strings.Count(text, "\n")+1)
+		q.AddButton(verb, func() {
+			c.view.Pages.RemovePage("modal")
+			if err := action(); err != nil {
+				c.error(header, err, false)
+				return
+			}
+			load()
+		})
+		q.AddButton("Cancel", func() {
+			c.view.Pages.RemovePage("modal")
+		})
+		c.view.Pages.AddPage("modal", c.view.ModalEdit(q, 64, strings.Count(text, "\n")+8), true, true)
+	}
+
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() == tcell.KeyEsc {
+			c.closePane("clients")
+			return nil
+		}
+		if ev.Key() == tcell.KeyDelete {
+			ev = tcell.NewEventKey(tcell.KeyRune, 'k', tcell.ModNone)
+		}
+		if ev.Key() != tcell.KeyRune {
+			return ev
+		}
+		switch ev.Rune() {
+		case 'q':
+			c.closePane("clients")
+		case 'r':
+			load()
+		case 's':
+			sortCol = (sortCol + 1) % len(clientColumns)
+			render()
+		case 'S':
+			desc = !desc
+			render()
+		case '/':
+			inp := c.view.NewInputDialog(" Filter (address, name, user, command, flags) ", filter)
+			inp.SetDoneFunc(func(key tcell.Key) {
+				c.view.Pages.RemovePage("modal")
+				if key == tcell.KeyEnter {
+					filter = strings.TrimSpace(inp.GetText())
+					render()
+				}
+			})
+			c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 60, 3), true, true)
+		case 'k':
+			ci, ok := selected()
+			if !ok || c.refuseWrite() {
+				return nil
+			}
+			text := fmt.Sprintf("Close connection id=%d addr=%s", ci.ID, tview.Escape(ci.Addr))
+			if ci.Name != "" {
+				text += fmt.Sprintf(" name=%s", tview.Escape(ci.Name))
+			}
+			text += fmt.Sprintf("\nlast command %s, idle %s?", tview.Escape(ci.Cmd), ci.Idle)
+			confirm(" Kill client ", text, "Kill", func() error { return c.server.KillClient(ci) })
+		case 'P':
+			if c.refuseWrite() {
+				return nil
+			}
+			c.pauseClients(func(d time.Duration) {
+				text := fmt.Sprintf("Hold the write commands of every client on %s for %s?\n"+
+					"Reads go on. The server lifts the pause by itself after %s; U ends it early.",
+					tview.Escape(c.model.Server()), d, d)
+				confirm(" Pause writes ", text, "Pause", func() error { return c.server.PauseClients(d) })
+			})
+		case 'U':
+			if c.refuseWrite() {
+				return nil
+			}
+			confirm(" Unpause clients ", "End the current CLIENT PAUSE?", "Unpause", c.server.UnpauseClients)
+		default:
+			return ev
+		}
+		return nil
+	})
+
+	load()
+	c.view.Pages.AddPage("clients", table, true, true)
+	c.view.App.SetFocus(table)
+	return nil
+}
+
+// pauseClients asks how long to pause writes for, up to
+// model.MaxClientPause, and passes it to next.
+func (c *Controller) pauseClients(next func(time.Duration)) {
+	inp := c.view.NewInputDialog(fmt.Sprintf(" Pause writes for (at most %s) ", model.MaxClientPause), "5s")
+	inp.SetDoneFunc(func(key tcell.Key) {
+		if key != tcell.KeyEnter {
+			c.view.Pages.RemovePage("modal")
+			return
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(inp.GetText()))
+		if err != nil || d <= 0 || d > model.MaxClientPause {
+			inp.SetTitle(fmt.Sprintf(" Enter a duration such as 5s, at most %s ", model.MaxClientPause))
+			return
+		}
+		c.view.Pages.RemovePage("modal")
+		next(d)
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 60, 3), true, true)
+}
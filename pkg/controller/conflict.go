@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nexusriot/redis-walker/pkg/model"
+)
+
+// diffMaxLines bounds the line diff; beyond it the LCS table gets too big.
+const diffMaxLines = 2000
+
+// saveValue writes value to key if the key still holds base, the value
+// the editor was opened with. saved runs after a successful write. On a
+// conflict the user can overwrite, reload (reload receives the current
+// server value) or view a diff first.
+func (c *Controller) saveValue(key, base, value string, saved func(), reload func(current string)) {
+	err := c.model.SetIfUnchanged(key, base, value)
+	var conflict *model.ConflictError
+	switch {
+	case err == nil:
+		saved()
+	case errors.As(err, &conflict):
+		c.resolveConflict(conflict, value, saved, reload)
+	default:
+		c.overlayError("Failed to save value", err)
+	}
+}
+
+func (c *Controller) resolveConflict(conflict *model.ConflictError, value string, saved func(), reload func(string)) {
+	pages := c.view.Overlay()
+	text := conflict.Error() + " while you were editing.\n\n" +
+		"Overwrite: save your version anyway.\n" +
+		"Reload: discard your edits and load the current value.\n" +
+		"Diff: compare the current value with your version."
+	q := c.view.NewConflictQ(text)
+	q.SetDoneFunc(func(_ int, label string) {
+		pages.RemovePage("conflict")
+		switch label {
+		case "Overwrite":
+			if err := c.model.Set(conflict.Key, value); err != nil {
+				c.overlayError("Failed to save value", err)
+				return
+			}
+			saved()
+		case "Reload":
+			reload(conflict.Current)
+		case "Diff":
+			c.showDiff(conflict, value, func() {
+				c.resolveConflict(conflict, value, saved, reload)
+			})
+		}
+	})
+	pages.AddPage("conflict", q, true, true)
+}
+
+// showDiff shows the server value against the edited one; back reopens
+// the conflict choice.
+func (c *Controller) showDiff(conflict *model.ConflictError, value string, back func()) {
+	pages := c.view.Overlay()
+	tv := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(false)
+	tv.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Diff %s: -server +yours (Esc to go back) ", tview.Escape(conflict.Key))).
+		SetTitleAlign(tview.AlignLeft)
+
+	var b strings.Builder
+	if !conflict.Exists {
+		b.WriteString(c.theme.Tag(c.theme.Error) + "(key no longer exists on the server)[-]\n")
+	}
+	for _, l := range lineDiff(conflict.Current, value) {
+		line := tview.Escape(l.text)
+		switch l.op {
+		case '-':
+			fmt.Fprintf(&b, "%s- %s[-]\n", c.theme.Tag(c.theme.Error), line)
+		case '+':
+			fmt.Fprintf(&b, "%s+ %s[-]\n", c.theme.Tag(c.theme.Highlight), line)
+		default:
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	tv.SetText(b.String())
+	tv.SetDoneFunc(func(tcell.Key) {
+		pages.RemovePage("diff")
+		back()
+	})
+	pages.AddPage("diff", tv, true, true)
+}
+
+// overlayError shows an error on top of whatever is open, editor included.
+func (c *Controller) overlayError(header string, err error) {
+	pages := c.view.Overlay()
+	q := c.view.NewErrorMessageQ(header, err.Error())
+	q.SetDoneFunc(func(int, string) {
+		pages.RemovePage("error")
+	})
+	pages.AddPage("error", q, true, true)
+}
+
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+// lineDiff returns a line diff of a and b based on the longest common
+// subsequence. Very long inputs are shown as a full replacement.
+func lineDiff(a, b string) []diffLine {
+	al, bl := strings.Split(a, "\n"), strings.Split(b, "\n")
+	if a == "" {
+		al = nil
+	}
+	var out []diffLine
+	if len(al) > diffMaxLines || len(bl) > diffMaxLines {
+		for _, l := range al {
+			out = append(out, diffLine{'-', l})
+		}
+		for _, l := range bl {
+			out = append(out, diffLine{'+', l})
+		}
+		return out
+	}
+
+	// lcs[i][j] is the LCS length of al[i:] and bl[j:].
+	lcs := make([][]int, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(al) && j < len(bl) {
+		switch {
+		case al[i] == bl[j]:
+			out = append(out, diffLine{' ', al[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, diffLine{'-', al[i]})
+			i++
+		default:
+			out = append(out, diffLine{'+', bl[j]})
+			j++
+		}
+	}
+	for ; i < len(al); i++ {
+		out = append(out, diffLine{'-', al[i]})
+	}
+	for ; j < len(bl); j++ {
+		out = append(out, diffLine{'+', bl[j]})
+	}
+	return out
+}
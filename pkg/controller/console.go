@@ -0,0 +1,625 @@
+package controller
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/keymap"
+	"github.com/nexusriot/redis-walker/pkg/model"
+)
+
+const (
+	consoleHistoryMax = 1000 // commands kept in the history file
+	consoleMaxLines   = 5000 // output lines kept on screen
+	consoleCompletion = 50   // autocomplete entries shown at most
+)
+
+// console is the raw command console. It is created on first use and
+// kept, with its output and history, while hidden.
+type console struct {
+	root *tview.Flex
+	out  *tview.TextView
+	hint *tview.TextView
+	in   *tview.InputField
+
+	open    bool
+	history []string
+	pos     int    // history entry being shown; len(history) for the draft
+	draft   string // line being typed before browsing history
+
+	candidates []string // entries for the autocomplete list
+	listOpen   bool     // autocomplete list is shown
+}
+
+// toggleConsole shows the console, or hides it if it is shown.
+func (c *Controller) toggleConsole() *tcell.EventKey {
+	if c.noServer("Console") {
+		return nil
+	}
+	if c.console == nil {
+		c.console = c.newConsole()
+	}
+	if c.console.open {
+		c.hideConsole()
+		return nil
+	}
+	c.console.open = true
+	c.view.Pages.ShowPage("console").SendToFront("console")
+	c.view.App.SetFocus(c.console.in)
+	return nil
+}
+
+func (c *Controller) hideConsole() {
+	c.console.open = false
+	c.view.Pages.HidePage("console")
+	c.view.App.SetFocus(c.view.List)
+}
+
+func (c *Controller) newConsole() *console {
+	cs := &console{history: c.loadHistory()}
+	cs.pos = len(cs.history)
+
+	cs.out = tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(true).
+		SetMaxLines(consoleMaxLines)
+	cs.out.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetTitle(tview.Escape(fmt.Sprintf(" Console  Tab=Complete  ↑/↓=History  PgUp/PgDn=Scroll  %s/Esc=Close ",
+			c.keys.Label(keymap.Console))))
+	fmt.Fprintf(cs.out, "[::d]Commands run on the current connection (db %d). Replies are shown as a tree.[::-]\n", c.model.DB())
+
+	cs.hint = tview.NewTextView().SetDynamicColors(true)
+	cs.in = tview.NewInputField().
+		SetLabel("> ").
+		SetLabelColor(c.theme.Highlight).
+		SetFieldTextColor(c.theme.Text).
+		SetFieldBackgroundColor(tcell.ColorDefault)
+
+	cs.in.SetChangedFunc(func(text string) {
+		c.consoleHint(cs, text)
+	})
+	cs.in.SetAutocompleteFunc(func(string) []string {
+		return cs.candidates
+	})
+	cs.in.SetAutocompletedFunc(func(text string, _ int, source int) bool {
+		if source == tview.AutocompletedNavigate {
+			return false
+		}
+		cs.in.SetText(text)
+		cs.candidates, cs.listOpen = nil, false
+		return true
+	})
+	cs.in.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if c.keys.Match(keymap.List, ev) == keymap.Console {
+			c.hideConsole()
+			return nil
+		}
+		if cs.listOpen {
+			switch ev.Key() {
+			case tcell.KeyUp, tcell.KeyDown, tcell.KeyPgUp, tcell.KeyPgDn, tcell.KeyEnter, tcell.KeyTab:
+				return ev
+			}
+			cs.candidates, cs.listOpen = nil, false
+			if ev.Key() == tcell.KeyEsc {
+				return ev
+			}
+		}
+		switch ev.Key() {
+		case tcell.KeyEsc:
+			c.hideConsole()
+		case tcell.KeyEnter:
+			c.runConsole(cs)
+		case tcell.KeyTab:
+			c.completeConsole(cs)
+		case tcell.KeyUp:
+			c.browseHistory(cs, -1)
+		case tcell.KeyDown:
+			c.browseHistory(cs, 1)
+		case tcell.KeyPgUp, tcell.KeyPgDn:
+			_, _, _, h := cs.out.GetInnerRect()
+			row, _ := cs.out.GetScrollOffset()
+			if ev.Key() == tcell.KeyPgUp {
+				row -= h
+			} else {
+				row += h
+			}
+			cs.out.ScrollTo(max(row, 0), 0)
+		default:
+			return ev
+		}
+		return nil
+	})
+
+	cs.root = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(cs.out, 0, 1, false).
+		AddItem(cs.hint, 1, 0, false).
+		AddItem(cs.in, 1, 0, true)
+	c.view.Pages.AddPage("console", cs.root, true, false)
+	return cs
+}
+
+// runConsole executes the typed line and appends the reply.
+func (c *Controller) runConsole(cs *console) {
+	line := strings.TrimSpace(cs.in.GetText())
+	if line == "" {
+		return
+	}
+	cs.in.SetText("")
+	cs.draft = ""
+	prompt := c.theme.Tag(c.theme.Highlight) + "> [-]" + tview.Escape(line) + "\n"
+	args, err := splitArgs(line)
+	if err != nil {
+		fmt.Fprint(cs.out, prompt+c.consoleError(err))
+		cs.out.ScrollToEnd()
+		return
+	}
+	if !sensitiveCommand(args) {
+		c.addHistory(cs, line)
+	}
+	cs.pos = len(cs.history)
+
+	res, err := c.server.Exec(args)
+	var b strings.Builder
+	if err != nil {
+		b.WriteString(c.consoleError(err))
+	} else {
+		writeReply(&b, res, "")
+		b.WriteByte('\n')
+	}
+	fmt.Fprint(cs.out, prompt+b.String())
+	cs.out.ScrollToEnd()
+	if err == nil && c.server.IsWrite(args) {
+		c.updateList()
+	}
+}
+
+func (c *Controller) consoleError(err error) string {
+	var ce *model.ConsoleError
+	if errors.As(err, &ce) {
+		return c.theme.Tag(c.theme.Error) + tview.Escape(err.Error()) + "[-]\n"
+	}
+	return c.theme.Tag(c.theme.Error) + "(error) " + tview.Escape(err.Error()) + "[-]\n"
+}
+
+// writeReply renders a reply the way redis-cli does: arrays as numbered
+// items, RESP3 maps as "key => value" entries, nested replies indented
+// under their parent item.
+func writeReply(b *strings.Builder, v interface{}, indent string) {
+	var items [][2]string // marker, rendered item
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("(nil)")
+		return
+	case string:
+		b.WriteString(tview.Escape(strconv.Quote(v)))
+		return
+	case int64:
+		fmt.Fprintf(b, "(integer) %d", v)
+		return
+	case float64:
+		fmt.Fprintf(b, "(double) %s", strconv.FormatFloat(v, 'g', -1, 64))
+		return
+	case bool:
+		fmt.Fprintf(b, "(%t)", v)
+		return
+	case error:
+		b.WriteString("(error) " + tview.Escape(v.Error()))
+		return
+	case []interface{}:
+		if len(v) == 0 {
+			b.WriteString("(empty array)")
+			return
+		}
+		width := len(strconv.Itoa(len(v)))
+		for i, item := range v {
+			marker := fmt.Sprintf("%*d) ", width, i+1)
+			var sub strings.Builder
+			writeReply(&sub, item, indent+strings.Repeat(" ", len(marker)))
+			items = append(items, [2]string{marker, sub.String()})
+		}
+	case map[interface{}]interface{}:
+		if len(v) == 0 {
+			b.WriteString("(empty map)")
+			return
+		}
+		keys := make([]interface{}, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		width := len(strconv.Itoa(len(v)))
+		for i, k := range keys {
+			marker := fmt.Sprintf("%*d# ", width, i+1)
+			var key, val strings.Builder
+			writeReply(&key, k, "")
+			key.WriteString(" => ")
+			pad := len(marker) + utf8.RuneCountInString(strings.ReplaceAll(key.String(), "[]", "]")) // unescaped
+			writeReply(&val, v[k], indent+strings.Repeat(" ", pad))
+			items = append(items, [2]string{marker, key.String() + val.String()})
+		}
+	default:
+		b.WriteString(tview.Escape(fmt.Sprint(v)))
+		return
+	}
+	for i, it := range items {
+		if i > 0 {
+			b.WriteString("\n" + indent)
+		}
+		b.WriteString(it[0] + it[1])
+	}
+}
+
+// splitArgs splits a command line like redis-cli: arguments are
+// separated by spaces, "double quotes" support backslash escapes
+// (\n, \t, \xHH, ...) and 'single quotes' only \'.
+func splitArgs(line string) ([]string, error) {
+	var (
+		args []string
+		cur  strings.Builder
+		in   bool // inside an argument
+	)
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case ch == ' ' || ch == '\t':
+			if in {
+				args = append(args, cur.String())
+				cur.Reset()
+				in = false
+			}
+		case (ch == '"' || ch == '\'') && !in:
+			j := i + 1
+			for ; j < len(line) && line[j] != ch; j++ {
+				if line[j] != '\\' || j+1 >= len(line) {
+					cur.WriteByte(line[j])
+					continue
+				}
+				j++
+				if ch == '\'' {
+					if line[j] != '\'' {
+						cur.WriteByte('\\')
+					}
+					cur.WriteByte(line[j])
+					continue
+				}
+				switch line[j] {
+				case 'n':
+					cur.WriteByte('\n')
+				case 'r':
+					cur.WriteByte('\r')
+				case 't':
+					cur.WriteByte('\t')
+				case 'b':
+					cur.WriteByte('\b')
+				case 'a':
+					cur.WriteByte('\a')
+				case 'x':
+					if j+2 < len(line) {
+						if n, err := strconv.ParseUint(line[j+1:j+3], 16, 8); err == nil {
+							cur.WriteByte(byte(n))
+							j += 2
+							continue
+						}
+					}
+					cur.WriteByte('x')
+				default:
+					cur.WriteByte(line[j])
+				}
+			}
+			if j >= len(line) {
+				return nil, errors.New("unbalanced quotes in command line")
+			}
+			if j+1 < len(line) && line[j+1] != ' ' && line[j+1] != '\t' {
+				return nil, errors.New("closing quote must be followed by a space")
+			}
+			args = append(args, cur.String())
+			cur.Reset()
+			i = j
+		default:
+			cur.WriteByte(ch)
+			in = true
+		}
+	}
+	if in {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// sensitiveCommand reports whether a command may carry a password and
+// must stay out of the history file.
+func sensitiveCommand(args []string) bool {
+	name := strings.ToLower(args[0])
+	switch name {
+	case "auth", "hello", "migrate":
+		return true
+	case "acl", "config":
+		if len(args) < 2 {
+			return false
+		}
+		sub := strings.ToLower(args[1])
+		if name == "acl" {
+			return sub == "setuser"
+		}
+		if sub == "set" {
+			for i := 2; i < len(args); i += 2 {
+				if model.SecretParameter(args[i]) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// completeConsole completes the word under the cursor from COMMAND DOCS
+// (command names, subcommands, argument tokens) and, where the command
+// takes keys, from the keys of the current folder.
+func (c *Controller) completeConsole(cs *console) {
+	text := cs.in.GetText()
+	docs, err := c.server.CommandDocs()
+	if err != nil {
+		cs.hint.SetText(c.theme.Tag(c.theme.Error) + tview.Escape(err.Error()) + "[-]")
+		return
+	}
+	words := strings.Fields(text)
+	word := ""
+	if len(words) > 0 && !strings.HasSuffix(text, " ") {
+		word, words = words[len(words)-1], words[:len(words)-1]
+	}
+	head := strings.TrimSuffix(text, word)
+
+	var opts []string
+	add := func(s string) {
+		if strings.HasPrefix(strings.ToLower(s), strings.ToLower(word)) {
+			opts = append(opts, s)
+		}
+	}
+	switch {
+	case len(words) == 0:
+		for name := range docs {
+			if !strings.Contains(name, "|") {
+				add(strings.ToUpper(name))
+			}
+		}
+	default:
+		cmd := strings.ToLower(words[0])
+		doc := docs[cmd]
+		if doc == nil {
+			return
+		}
+		if len(doc.Subcommands) > 0 {
+			if len(words) == 1 {
+				for _, s := range doc.Subcommands {
+					add(strings.ToUpper(s))
+				}
+				break
+			}
+			if d := docs[cmd+"|"+strings.ToLower(words[1])]; d != nil {
+				doc = d
+			}
+		}
+		var tokens []string
+		if collectTokens(doc.Args, &tokens) {
+			for _, n := range c.currentNodes {
+				if !n.node.IsDir {
+					add(n.node.Name)
+				}
+			}
+		}
+		for _, t := range tokens {
+			add(t)
+		}
+	}
+	if len(opts) == 0 {
+		return
+	}
+	sort.Strings(opts)
+	opts = dedup(opts)
+	if len(opts) == 1 {
+		cs.in.SetText(head + opts[0] + " ")
+		return
+	}
+	if p := commonPrefix(opts); len(p) > len(word) {
+		cs.in.SetText(head + p)
+	}
+	if len(opts) > consoleCompletion {
+		opts = opts[:consoleCompletion]
+	}
+	cs.candidates = make([]string, len(opts))
+	for i, o := range opts {
+		cs.candidates[i] = head + o
+	}
+	cs.listOpen = true
+	cs.in.Autocomplete()
+}
+
+// collectTokens appends the literal tokens of args, recursively, and
+// reports whether any argument is a key.
+func collectTokens(args []model.ArgDoc, out *[]string) bool {
+	keys := false
+	for _, a := range args {
+		if a.Token != "" {
+			*out = append(*out, a.Token)
+		}
+		if a.Type == "key" {
+			keys = true
+		}
+		if collectTokens(a.Args, out) {
+			keys = true
+		}
+	}
+	return keys
+}
+
+func dedup(sorted []string) []string {
+	out := sorted[:0]
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func commonPrefix(list []string) string {
+	p := list[0]
+	for _, s := range list[1:] {
+		for !strings.HasPrefix(strings.ToLower(s), strings.ToLower(p)) {
+			p = p[:len(p)-1]
+		}
+	}
+	for !utf8.ValidString(p) {
+		p = p[:len(p)-1]
+	}
+	return p
+}
+
+// consoleHint shows the synopsis of the command being typed.
+func (c *Controller) consoleHint(cs *console, text string) {
+	words := strings.Fields(text)
+	cs.hint.SetText("")
+	if len(words) == 0 {
+		return
+	}
+	docs, _ := c.server.CommandDocs()
+	name := strings.ToLower(words[0])
+	doc := docs[name]
+	if doc == nil {
+		return
+	}
+	if len(words) > 1 {
+		if d := docs[name+"|"+strings.ToLower(words[1])]; d != nil {
+			doc, name = d, name+" "+strings.ToLower(words[1])
+		}
+	}
+	parts := []string{strings.ToUpper(name)}
+	for _, a := range doc.Args {
+		parts = append(parts, argSynopsis(a))
+	}
+	cs.hint.SetText("[::d]" + tview.Escape(strings.Join(parts, " ")+"  — "+doc.Summary) + "[::-]")
+}
+
+// argSynopsis renders an argument the way the Redis docs do, e.g.
+// "[EX seconds | PX milliseconds]" or "key [key ...]".
+func argSynopsis(a model.ArgDoc) string {
+	var s string
+	switch a.Type {
+	case "pure-token":
+		s = a.Token
+	case "oneof", "block":
+		sep := " "
+		if a.Type == "oneof" {
+			sep = " | "
+		}
+		parts := make([]string, len(a.Args))
+		for i, sub := range a.Args {
+			parts[i] = argSynopsis(sub)
+		}
+		s = strings.Join(parts, sep)
+		if a.Token != "" {
+			s = a.Token + " " + s
+		}
+	default:
+		s = a.Name
+		if a.Token != "" {
+			s = a.Token + " " + s
+		}
+	}
+	if a.Multiple {
+		s += " [" + s + " ...]"
+	}
+	switch {
+	case a.Optional:
+		s = "[" + s + "]"
+	case a.Type == "oneof":
+		s = "<" + s + ">"
+	}
+	return s
+}
+
+func (c *Controller) browseHistory(cs *console, step int) {
+	if len(cs.history) == 0 {
+		return
+	}
+	if cs.pos == len(cs.history) {
+		cs.draft = cs.in.GetText()
+	}
+	cs.pos = min(max(cs.pos+step, 0), len(cs.history))
+	if cs.pos == len(cs.history) {
+		cs.in.SetText(cs.draft)
+		return
+	}
+	cs.in.SetText(cs.history[cs.pos])
+}
+
+func (c *Controller) loadHistory() []string {
+	if c.historyFile == "" {
+		return nil
+	}
+	f, err := os.Open(c.historyFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.WithError(err).Warn("failed to read console history")
+		}
+		return nil
+	}
+	defer f.Close()
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if l := sc.Text(); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) > consoleHistoryMax {
+		lines = lines[len(lines)-consoleHistoryMax:]
+	}
+	return lines
+}
+
+// addHistory appends line to the in-memory history and to the history
+// file, rewriting the file when it grows past the limit.
+func (c *Controller) addHistory(cs *console, line string) {
+	if n := len(cs.history); n > 0 && cs.history[n-1] == line {
+		return
+	}
+	cs.history = append(cs.history, line)
+	if len(cs.history) > consoleHistoryMax {
+		cs.history = cs.history[len(cs.history)-consoleHistoryMax:]
+	}
+	if c.historyFile == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.historyFile), 0o700); err != nil {
+		log.WithError(err).Warn("failed to save console history")
+		return
+	}
+	flags, data := os.O_CREATE|os.O_WRONLY|os.O_APPEND, line+"\n"
+	if len(cs.history) == consoleHistoryMax {
+		flags, data = os.O_CREATE|os.O_WRONLY|os.O_TRUNC, strings.Join(cs.history, "\n")+"\n"
+	}
+	f, err := os.OpenFile(c.historyFile, flags, 0o600)
+	if err == nil {
+		_, err = f.WriteString(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		log.WithError(err).Warn("failed to save console history")
+	}
+}
@@ -4,11 +4,15 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/nexusriot/redis-walker/pkg/keymap"
+	"github.com/nexusriot/redis-walker/pkg/logging"
 	"github.com/nexusriot/redis-walker/pkg/model"
+	"github.com/nexusriot/redis-walker/pkg/theme"
 	"github.com/nexusriot/redis-walker/pkg/view"
 	"github.com/rivo/tview"
 )
@@ -16,10 +20,59 @@ import (
 type Controller struct {
 	debug        bool
 	view         *view.View
-	model        *model.Model
+	keys         *keymap.Keymap
+	theme        *theme.Theme
+	model        Store
+	server       Server // nil while browsing an RDB file
 	currentDir   string
 	currentNodes map[string]*Node
 	position     map[string]int
+
+	confirmThreshold int // key count above which folder name must be typed
+
+	live  bool       // refresh the list from keyspace notifications
+	watch *liveWatch // subscription for the current folder; UI goroutine only
+
+	pins          *pinSet
+	watchInterval time.Duration
+	watchTable    *tview.Table // non-nil while the watch panel is open
+
+	graph  *graphRun          // key being graphed in Details; UI goroutine only
+	series map[string]*series // sample windows by key and zset member
+
+	logs     *logging.Ring
+	logView  *tview.TextView // non-nil while the log pane is open
+	logLevel log.Level
+
+	console     *console // raw command console, created on first use
+	historyFile string   // console history; "" keeps it in memory only
+
+	script string // text of the scripting screen's editor
+}
+
+// Options configures a Controller.
+type Options struct {
+	Debug bool
+	Keys  *keymap.Keymap
+	Theme *theme.Theme
+	Logs  *logging.Ring // recent log entries for the log pane; may be nil
+
+	// ConfirmThreshold is the number of keys from which a recursive delete
+	// or rename requires typing the folder name; 0 never requires it.
+	ConfirmThreshold int
+
+	// Live subscribes to keyspace notifications for the current folder
+	// and refreshes the list when other clients change it.
+	Live bool
+
+	// WatchKeys are pinned into the watch panel at startup; WatchInterval
+	// is how often pinned keys are polled (default DefaultWatchInterval).
+	WatchKeys     []string
+	WatchInterval time.Duration
+
+	// HistoryFile keeps the console history across sessions; "" keeps
+	// it in memory only.
+	HistoryFile string
 }
 
 type Node struct {
@@ -28,20 +81,59 @@ type Node struct {
 
 func splitFunc(r rune) bool { return r == '/' }
 
-func NewController(m *model.Model, host, port string, db int, debug bool) *Controller {
-	v := view.NewView()
+func NewController(m Store, opts Options) *Controller {
+	if opts.Keys == nil {
+		opts.Keys = keymap.Default()
+	}
+	if opts.Theme == nil {
+		opts.Theme, _ = theme.New("", nil)
+	}
+	if opts.Logs == nil {
+		opts.Logs = logging.NewRing(1)
+	}
+	if opts.WatchInterval <= 0 {
+		opts.WatchInterval = DefaultWatchInterval
+	}
+	pins := &pinSet{}
+	for _, k := range opts.WatchKeys {
+		if k = normAbs(k); !pins.has(k) {
+			pins.toggle(k)
+		}
+	}
+	v := view.NewView(opts.Keys, opts.Theme)
 	v.Frame.AddText(
-		fmt.Sprintf("Redis-walker v.0.0.2 (preview) (on %s:%s, db=%d)", host, port, db),
-		true, tview.AlignCenter, tcell.ColorGreen,
+		fmt.Sprintf("Redis-walker v.0.0.2 (preview) (on %s, db=%d)", m.Server(), m.DB()),
+		true, tview.AlignCenter, opts.Theme.Header,
 	)
+	server, _ := m.(Server)
+	if server == nil {
+		v.Frame.AddText("*** OFFLINE: browsing an RDB file, read-only ***", true, tview.AlignCenter, opts.Theme.Error)
+	} else if m.ReadOnly() {
+		banner := "*** READ-ONLY MODE: create, edit, rename and delete are disabled ***"
+		if role := server.Role(); role != "" {
+			banner = fmt.Sprintf("*** READ-ONLY MODE (server role: %s): create, edit, rename and delete are disabled ***", role)
+		}
+		v.Frame.AddText(banner, true, tview.AlignCenter, opts.Theme.Error)
+	}
 
 	return &Controller{
-		debug:        debug,
-		view:         v,
-		model:        m,
-		currentDir:   "/",
-		currentNodes: make(map[string]*Node),
-		position:     make(map[string]int),
+		debug:            opts.Debug,
+		view:             v,
+		keys:             opts.Keys,
+		theme:            opts.Theme,
+		model:            m,
+		server:           server,
+		currentDir:       "/",
+		currentNodes:     make(map[string]*Node),
+		position:         make(map[string]int),
+		logs:             opts.Logs,
+		confirmThreshold: opts.ConfirmThreshold,
+		live:             opts.Live && server != nil,
+		pins:             pins,
+		watchInterval:    opts.WatchInterval,
+		series:           make(map[string]*series),
+		historyFile:      opts.HistoryFile,
+		logLevel:         log.InfoLevel,
 	}
 }
 
@@ -60,6 +152,12 @@ func makeMapKey(base string, isDir bool) string {
 	return base + "|file"
 }
 
+// baseName is the last path element of a node name.
+func baseName(name string) string {
+	fields := strings.FieldsFunc(strings.TrimSpace(name), splitFunc)
+	return fields[len(fields)-1]
+}
+
 func displayName(base string, isDir bool) string {
 	if isDir {
 		return base + "/"
@@ -117,9 +215,7 @@ func (c *Controller) makeNodeMap() error {
 		return err
 	}
 	for _, n := range list {
-		rawName := n.Name
-		fields := strings.FieldsFunc(strings.TrimSpace(rawName), splitFunc)
-		base := fields[len(fields)-1]
+		base := baseName(n.Name)
 		mapKey := makeMapKey(base, n.IsDir)
 		cNode := Node{node: n}
 		m[mapKey] = &cNode
@@ -135,21 +231,24 @@ func (c *Controller) makeNodeMap() error {
 	return nil
 }
 
-func (c *Controller) colorize(base string, isDir bool, label string) string {
-	if strings.HasPrefix(base, "_") {
-		return "[yellow]" + label + "[-]"
-	}
-	return label
+func (c *Controller) colorize(n *model.Node, base string, label string) string {
+	return c.theme.Colorize(label, n.Name, base, n.Type)
 }
 
 func (c *Controller) updateList() []string {
 	c.dbg("updateList", log.Fields{"dir": c.currentDir})
-	c.view.List.Clear()
-	c.view.List.SetTitle("[ [::b]" + c.currentDir + "[::-] ]")
-
 	if err := c.makeNodeMap(); err != nil {
 		c.error("failed to load keys", err, true)
 	}
+	c.watchDir(c.currentDir)
+	return c.renderList()
+}
+
+// renderList rebuilds the list widget from currentNodes and returns the
+// display names in list order (without [..]).
+func (c *Controller) renderList() []string {
+	c.view.List.Clear()
+	c.view.List.SetTitle("[ [::b]" + c.currentDir + "[::-] ]")
 
 	// [..] always on top
 	c.view.List.AddItem("[..]", "..", 0, func() {
@@ -173,7 +272,7 @@ func (c *Controller) updateList() []string {
 		fields := strings.FieldsFunc(n.Name, splitFunc)
 		base := fields[len(fields)-1]
 		rawLabel := "📁 " + displayName(base, true)
-		label := c.colorize(base, true, rawLabel)
+		label := c.colorize(n, base, rawLabel)
 		c.view.List.AddItem(label, mk, 0, func() {
 			i := c.view.List.GetCurrentItem()
 			_, curMK := c.view.List.GetItemText(i)
@@ -188,12 +287,7 @@ func (c *Controller) updateList() []string {
 	}
 
 	for _, mk := range fileKeys {
-		n := c.currentNodes[mk].node
-		fields := strings.FieldsFunc(n.Name, splitFunc)
-		base := fields[len(fields)-1]
-		rawLabel := "   " + displayName(base, false)
-		label := c.colorize(base, false, rawLabel)
-		c.view.List.AddItem(label, mk, 0, func() {
+		c.view.List.AddItem(c.fileLabel(c.currentNodes[mk].node), mk, 0, func() {
 			// no-op, details are updated via SetChangedFunc
 		})
 	}
@@ -219,14 +313,33 @@ func (c *Controller) updateList() []string {
 
 func (c *Controller) fillDetails(mapKey string) {
 	c.view.Details.Clear()
+	if val, ok := c.currentNodes[mapKey]; !ok || (c.graph != nil && c.graph.s.key != val.node.Name) {
+		c.stopGraph()
+	}
 	if val, ok := c.currentNodes[mapKey]; ok {
 		log.Debugf("Node details name: %s, isDir: %t", val.node.Name, val.node.IsDir)
-		fmt.Fprintf(c.view.Details, "[green] Full name: [white] %s\n", val.node.Name)
-		fmt.Fprintf(c.view.Details, "[green] Is directory: [white] %t\n\n", val.node.IsDir)
+		label, value := c.theme.Tag(c.theme.Label), c.theme.Tag(c.theme.Value)
+		fmt.Fprintf(c.view.Details, "%s Full name: %s %s\n", label, value, val.node.Name)
+		fmt.Fprintf(c.view.Details, "%s Is directory: %s %t\n", label, value, val.node.IsDir)
 		if !val.node.IsDir {
-			fmt.Fprintf(c.view.Details, "[green] Value: [white]\n%s\n", val.node.Value)
+			fmt.Fprintf(c.view.Details, "%s Type: %s %s\n", label, value, val.node.Type)
 		}
+		fmt.Fprintln(c.view.Details)
+		if !val.node.IsDir {
+			c.writeGraph(val.node.Name)
+			fmt.Fprintf(c.view.Details, "%s Value: %s\n%s\n", label, value, val.node.Value)
+		}
+	}
+}
+
+// refuseWrite shows an error and returns true when the model is read-only,
+// so write actions bail out before opening any form.
+func (c *Controller) refuseWrite() bool {
+	if !c.model.ReadOnly() {
+		return false
 	}
+	c.error("Read-only mode", model.ErrReadOnly, false)
+	return true
 }
 
 func (c *Controller) getPosition(element string, slice []string) int {
@@ -242,7 +355,8 @@ func (c *Controller) getPosition(element string, slice []string) int {
 func (c *Controller) showHelp() *tcell.EventKey {
 	help := c.view.NewHotkeysModal()
 
-	modal := c.view.ModalEdit(help, 70, 18)
+	height := strings.Count(help.GetText(false), "\n") + 3
+	modal := c.view.ModalEdit(help, 70, height)
 
 	// Close on any key and restore focus to the list
 	help.SetInputCapture(func(_ *tcell.EventKey) *tcell.EventKey {
@@ -258,8 +372,8 @@ func (c *Controller) showHelp() *tcell.EventKey {
 
 func (c *Controller) setInput() {
 	c.view.App.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyCtrlQ:
+		switch c.keys.Match(keymap.Global, event) {
+		case keymap.Quit:
 			c.Stop()
 			return nil
 		}
@@ -267,30 +381,60 @@ func (c *Controller) setInput() {
 	})
 
 	c.view.List.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyCtrlN:
+		switch c.keys.Match(keymap.List, event) {
+		case keymap.Create:
 			return c.create()
-		case tcell.KeyDelete:
+		case keymap.Delete:
 			return c.delete()
-		case tcell.KeyCtrlE:
+		case keymap.Edit:
 			return c.editMultiline()
-		case tcell.KeyCtrlS:
+		case keymap.Search:
 			return c.search()
-		case tcell.KeyCtrlJ:
+		case keymap.Jump:
 			return c.jump()
-		case tcell.KeyF1:
+		case keymap.Help:
 			return c.showHelp()
-		case tcell.KeyBackspace, tcell.KeyBackspace2:
+		case keymap.Logs:
+			return c.showLogs()
+		case keymap.Trash:
+			return c.showTrash()
+		case keymap.Graph:
+			return c.toggleGraph()
+		case keymap.Pin:
+			return c.togglePin()
+		case keymap.Watch:
+			return c.showWatch()
+		case keymap.Export:
+			return c.exportFolder()
+		case keymap.Import:
+			return c.importFile()
+		case keymap.Snapshot:
+			return c.snapshotFolder()
+		case keymap.Restore:
+			return c.restoreSnapshot()
+		case keymap.Scripts:
+			return c.showScripts()
+		case keymap.Console:
+			return c.toggleConsole()
+		case keymap.PubSub:
+			return c.showPubSub()
+		case keymap.ServerConfig:
+			return c.showServerConfig()
+		case keymap.Clients:
+			return c.showClients()
+		case keymap.Dashboard:
+			return c.showDashboard()
+		case keymap.SlowLog:
+			return c.showSlowLog()
+		case keymap.Monitor:
+			return c.showMonitor()
+		case keymap.Audit:
+			return c.showAudit()
+		case keymap.Undo:
+			return c.undo()
+		case keymap.Up:
 			c.Up()
 			return nil
-		case tcell.KeyRune:
-			switch event.Rune() {
-			case '/':
-				return c.search()
-			case '?':
-				// Reliable help key
-				return c.showHelp()
-			}
 		}
 		return event
 	})
@@ -326,6 +470,7 @@ func (c *Controller) Cd(path string) { c.updateList() }
 
 func (c *Controller) Stop() {
 	log.Debug("exit...")
+	c.stopWatch()
 	c.view.App.Stop()
 }
 
@@ -336,6 +481,8 @@ func (c *Controller) Run() error {
 	})
 	c.updateList()
 	c.setInput()
+	go c.watchLogs()
+	go c.pollPins()
 	return c.view.App.Run()
 }
 
@@ -386,7 +533,7 @@ func (c *Controller) search() *tcell.EventKey {
 }
 
 func (c *Controller) delete() *tcell.EventKey {
-	if c.view.List.GetItemCount() == 0 {
+	if c.view.List.GetItemCount() == 0 || c.refuseWrite() {
 		return nil
 	}
 	i := c.view.List.GetCurrentItem()
@@ -397,11 +544,21 @@ func (c *Controller) delete() *tcell.EventKey {
 	}
 
 	if val, ok := c.currentNodes[mapKey]; ok {
-		base := displayName(baseOf(val.node.Name), val.node.IsDir)
-		elem := base
 		if val.node.IsDir {
-			elem = elem + " (recursive)"
+			header := fmt.Sprintf("Delete %s (recursive)", displayName(baseOf(val.node.Name), true))
+			c.confirmRecursive(header, "Delete", val.node.Name, "", func() {
+				if err := c.model.DelDir(val.node.Name); err != nil {
+					log.WithError(err).Error("delete failed")
+					c.error("Error deleting key", err, false)
+					return
+				}
+				c.view.Details.Clear()
+				c.updateList()
+			})
+			return nil
 		}
+		base := displayName(baseOf(val.node.Name), val.node.IsDir)
+		elem := base
 		delQ := c.view.NewDeleteQ(elem)
 		delQ.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
 			if buttonLabel == "ok" {
@@ -428,6 +585,9 @@ func (c *Controller) delete() *tcell.EventKey {
 }
 
 func (c *Controller) create() *tcell.EventKey {
+	if c.refuseWrite() {
+		return nil
+	}
 	pos := 0
 	createForm := c.view.NewCreateForm(fmt.Sprintf("Create Key: %s", c.currentDir))
 	createForm.AddButton("Save", func() {
@@ -465,6 +625,9 @@ func (c *Controller) create() *tcell.EventKey {
 }
 
 func (c *Controller) edit() *tcell.EventKey {
+	if c.refuseWrite() {
+		return nil
+	}
 	i := c.view.List.GetCurrentItem()
 	_, mapKey := c.view.List.GetItemText(i)
 	mapKey = strings.TrimSpace(mapKey)
@@ -474,19 +637,27 @@ func (c *Controller) edit() *tcell.EventKey {
 
 	if val, ok := c.currentNodes[mapKey]; ok {
 		if !val.node.IsDir {
-			editValueForm := c.view.NewEditValueForm(fmt.Sprintf("Edit: %s", val.node.Name), val.node.Value)
-			editValueForm.AddButton("Save", func() {
-				value := editValueForm.GetFormItem(0).(*tview.InputField).GetText()
-				if err := c.model.Set(val.node.Name, value); err != nil {
-					c.view.Pages.RemovePage("modal")
-					c.error(fmt.Sprintf("Failed to edit %s", val.node.Name), err, false)
-					return
-				}
+			node, err := c.model.Get(val.node.Name)
+			if err != nil {
+				c.error("Failed to read value", err, false)
+				return nil
+			}
+			base := node.Value
+			editValueForm := c.view.NewEditValueForm(fmt.Sprintf("Edit: %s", val.node.Name), base)
+			field := editValueForm.GetFormItem(0).(*tview.InputField)
+			saved := func() {
 				ordered := c.updateList()
-				base := displayName(baseOf(val.node.Name), false)
-				pos := c.getPosition(base, ordered) + 1
+				name := displayName(baseOf(val.node.Name), false)
+				pos := c.getPosition(name, ordered) + 1
 				c.view.Pages.RemovePage("modal")
 				c.view.List.SetCurrentItem(pos)
+			}
+			reload := func(current string) {
+				base = current
+				field.SetText(current)
+			}
+			editValueForm.AddButton("Save", func() {
+				c.saveValue(val.node.Name, base, field.GetText(), saved, reload)
 			})
 			editValueForm.AddButton("Quit", func() {
 				c.view.Pages.RemovePage("modal")
@@ -507,19 +678,21 @@ func (c *Controller) edit() *tcell.EventKey {
 			}
 			oldPath := val.node.Name
 			newPath := normAbs(c.currentDir + newName)
+			c.view.Pages.RemovePage("modal")
 			if newPath == oldPath {
-				c.view.Pages.RemovePage("modal")
-				return
-			}
-			if err := c.model.RenameDir(oldPath, newPath); err != nil {
-				c.view.Pages.RemovePage("modal")
-				c.error("Failed to rename folder", err, false)
 				return
 			}
-			ordered := c.updateList()
-			pos := c.getPosition(newName+"/", ordered) + 1
-			c.view.Pages.RemovePage("modal")
-			c.view.List.SetCurrentItem(pos)
+			header := fmt.Sprintf("Rename %s", displayName(baseOf(oldPath), true))
+			extra := fmt.Sprintf("New name: %s/\n", tview.Escape(newPath))
+			c.confirmRecursive(header, "Rename", oldPath, extra, func() {
+				if err := c.model.RenameDir(oldPath, newPath); err != nil {
+					c.error("Failed to rename folder", err, false)
+					return
+				}
+				ordered := c.updateList()
+				pos := c.getPosition(newName+"/", ordered) + 1
+				c.view.List.SetCurrentItem(pos)
+			})
 		})
 		editDirForm.AddButton("Quit", func() {
 			c.view.Pages.RemovePage("modal")
@@ -530,7 +703,7 @@ func (c *Controller) edit() *tcell.EventKey {
 }
 
 func (c *Controller) editMultiline() *tcell.EventKey {
-	if c.view.List.GetItemCount() == 0 {
+	if c.view.List.GetItemCount() == 0 || c.refuseWrite() {
 		return nil
 	}
 	i := c.view.List.GetCurrentItem()
@@ -547,29 +720,40 @@ func (c *Controller) editMultiline() *tcell.EventKey {
 		return c.edit()
 	}
 
+	// Re-read the value: the listing may be stale, and saving checks
+	// that the key still holds what the editor was opened with.
+	node, err := c.model.Get(val.node.Name)
+	if err != nil {
+		c.error("Failed to read value", err, false)
+		return nil
+	}
+	base := node.Value
+
 	title := fmt.Sprintf(" Edit (multiline): %s ", val.node.Name)
-	ta := c.view.NewMultilineEditor(title, val.node.Value)
+	ta := c.view.NewMultilineEditor(title, base)
+
+	saved := func() {
+		c.view.CloseEditor()
+		ordered := c.updateList()
+		name := displayName(baseOf(val.node.Name), false)
+		pos := c.getPosition(name, ordered) + 1
+		c.view.List.SetCurrentItem(pos)
+		// refresh details
+		i := c.view.List.GetCurrentItem()
+		_, mk := c.view.List.GetItemText(i)
+		c.fillDetails(strings.TrimSpace(mk))
+	}
+	reload := func(current string) {
+		base = current
+		ta.SetText(current, false)
+	}
 
 	ta.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
-		switch ev.Key() {
-		case tcell.KeyCtrlS:
-			value := ta.GetText()
-			if err := c.model.Set(val.node.Name, value); err != nil {
-				c.view.CloseEditor()
-				c.error("Failed to save value", err, false)
-				return nil
-			}
-			c.view.CloseEditor()
-			ordered := c.updateList()
-			base := displayName(baseOf(val.node.Name), false)
-			pos := c.getPosition(base, ordered) + 1
-			c.view.List.SetCurrentItem(pos)
-			// refresh details
-			i := c.view.List.GetCurrentItem()
-			_, mk := c.view.List.GetItemText(i)
-			c.fillDetails(strings.TrimSpace(mk))
+		switch c.keys.Match(keymap.Editor, ev) {
+		case keymap.Save:
+			c.saveValue(val.node.Name, base, ta.GetText(), saved, reload)
 			return nil
-		case tcell.KeyEsc, tcell.KeyCtrlQ:
+		case keymap.Cancel:
 			c.view.CloseEditor()
 			return nil
 		}
@@ -591,81 +775,100 @@ func (c *Controller) error(header string, err error, fatal bool) {
 	c.view.Pages.AddPage("modal", c.view.ModalEdit(errMsg, 8, 3), true, true)
 }
 
+// info shows a message that needs no decision.
+func (c *Controller) info(header, msg string) {
+	q := c.view.NewInfoMessage(header, msg)
+	q.SetDoneFunc(func(int, string) {
+		c.view.Pages.RemovePage("modal")
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(q, 8, 3), true, true)
+}
+
+// closePane removes a full-screen pane and returns focus to the list.
+func (c *Controller) closePane(name string) {
+	c.view.Pages.RemovePage(name)
+	c.view.App.SetFocus(c.view.List)
+}
+
 func (c *Controller) jump() *tcell.EventKey {
 	inp := c.view.NewJump()
 	inp.SetDoneFunc(func(key tcell.Key) {
-		defer c.view.Pages.RemovePage("modal")
+		c.view.Pages.RemovePage("modal")
 		if key != tcell.KeyEnter {
 			return
 		}
-		raw := strings.TrimSpace(inp.GetText())
-		if raw == "" {
-			return
-		}
+		c.jumpTo(strings.TrimSpace(inp.GetText()))
+	})
 
-		isDirHint := strings.HasSuffix(raw, "/")
-		var target string
-		if strings.HasPrefix(raw, "/") {
-			target = normAbs(raw)
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 60, 5), true, true)
+	return nil
+}
+
+// jumpTo navigates to a key or folder, given as an absolute path or one
+// relative to the current folder; a trailing '/' requires a folder.
+func (c *Controller) jumpTo(raw string) {
+	if raw == "" {
+		return
+	}
+	isDirHint := strings.HasSuffix(raw, "/")
+	var target string
+	if strings.HasPrefix(raw, "/") {
+		target = normAbs(raw)
+	} else {
+		cur := normAbs(c.currentDir)
+		if cur != "/" {
+			target = normAbs(cur + "/" + raw)
 		} else {
-			cur := normAbs(c.currentDir)
-			if cur != "/" {
-				target = normAbs(cur + "/" + raw)
-			} else {
-				target = normAbs("/" + raw)
-			}
+			target = normAbs("/" + raw)
 		}
+	}
 
-		nd, err := c.model.Get(target)
-		if err != nil {
-			c.error("Not found", fmt.Errorf("%s", target), false)
-			return
-		}
-		if isDirHint && !nd.IsDir {
-			c.error("Not a folder", fmt.Errorf("%s", target), false)
-			return
-		}
+	nd, err := c.model.Get(target)
+	if err != nil {
+		c.error("Not found", fmt.Errorf("%s", target), false)
+		return
+	}
+	if isDirHint && !nd.IsDir {
+		c.error("Not a folder", fmt.Errorf("%s", target), false)
+		return
+	}
 
-		if nd.IsDir {
-			c.currentDir = normAbs(nd.Name) + "/"
-			c.Cd(c.currentDir)
-			return
-		}
+	if nd.IsDir {
+		c.currentDir = normAbs(nd.Name) + "/"
+		c.Cd(c.currentDir)
+		return
+	}
 
-		parent := parentOf(nd.Name)
-		base := baseOf(nd.Name)
-		if !strings.HasSuffix(parent, "/") {
-			parent += "/"
-		}
-		c.currentDir = parent
-		ordered := c.updateList()
+	parent := parentOf(nd.Name)
+	base := baseOf(nd.Name)
+	if !strings.HasSuffix(parent, "/") {
+		parent += "/"
+	}
+	c.currentDir = parent
+	ordered := c.updateList()
 
-		findIndex := func(name string, list []string) int {
-			for i, v := range list {
-				if v == name {
-					return i
-				}
+	findIndex := func(name string, list []string) int {
+		for i, v := range list {
+			if v == name {
+				return i
 			}
-			return -1
 		}
+		return -1
+	}
 
-		if pos := findIndex(base, ordered); pos >= 0 {
-			c.view.List.SetCurrentItem(pos + 1)
-			i := c.view.List.GetCurrentItem()
-			_, mk := c.view.List.GetItemText(i)
-			c.fillDetails(strings.TrimSpace(mk))
-			return
-		}
-		if pos := findIndex(base+"/", ordered); pos >= 0 {
-			c.view.List.SetCurrentItem(pos + 1)
-			i := c.view.List.GetCurrentItem()
-			_, mk := c.view.List.GetItemText(i)
-			c.fillDetails(strings.TrimSpace(mk))
-			return
-		}
-		c.error("Not found", fmt.Errorf("%s", target), false)
-	})
-
-	c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 60, 5), true, true)
-	return nil
+	if pos := findIndex(base, ordered); pos >= 0 {
+		c.view.List.SetCurrentItem(pos + 1)
+		i := c.view.List.GetCurrentItem()
+		_, mk := c.view.List.GetItemText(i)
+		c.fillDetails(strings.TrimSpace(mk))
+		return
+	}
+	if pos := findIndex(base+"/", ordered); pos >= 0 {
+		c.view.List.SetCurrentItem(pos + 1)
+		i := c.view.List.GetCurrentItem()
+		_, mk := c.view.List.GetItemText(i)
+		c.fillDetails(strings.TrimSpace(mk))
+		return
+	}
+	c.error("Not found", fmt.Errorf("%s", target), false)
 }
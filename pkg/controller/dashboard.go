@@ -0,0 +1,215 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/view"
+)
+
+// dashHistory is the number of samples kept per dashboard trend.
+const dashHistory = 60
+
+// dashboard is the state of an open INFO dashboard. UI goroutine only.
+type dashboard struct {
+	tv   *tview.TextView
+	stop chan struct{}
+
+	info     map[string]string
+	prev     map[string]string // previous poll, for per-second rates
+	prevTime time.Time
+	hist     map[string][]float64
+	err      error
+}
+
+// showDashboard opens the server dashboard and polls INFO while it is open.
+func (c *Controller) showDashboard() *tcell.EventKey {
+	if c.noServer("Dashboard") {
+		return nil
+	}
+	tv := c.view.NewLogView()
+	tv.SetTitle(fmt.Sprintf(" Server dashboard (INFO every %s)  Esc=Close ", c.watchInterval))
+	d := &dashboard{tv: tv, stop: make(chan struct{}), hist: make(map[string][]float64)}
+
+	poll := func() {
+		info, err := c.server.Info()
+		if err != nil {
+			log.WithError(err).Debug("dashboard poll failed")
+		}
+		c.view.App.QueueUpdateDraw(func() {
+			c.updateDashboard(d, info, err)
+		})
+	}
+	go func() {
+		t := time.NewTicker(c.watchInterval)
+		defer t.Stop()
+		poll()
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-t.C:
+				poll()
+			}
+		}
+	}()
+
+	tv.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() == tcell.KeyEsc || ev.Key() == tcell.KeyRune && ev.Rune() == 'q' {
+			close(d.stop)
+			c.closePane("dashboard")
+			return nil
+		}
+		return ev
+	})
+	tv.SetText("Loading INFO...")
+	c.view.Pages.AddPage("dashboard", tv, true, true)
+	c.view.App.SetFocus(tv)
+	return nil
+}
+
+func (c *Controller) updateDashboard(d *dashboard, info map[string]string, err error) {
+	d.err = err
+	if err != nil {
+		c.renderDashboard(d)
+		return
+	}
+	now := time.Now()
+	d.prev, d.info = d.info, info
+	elapsed := now.Sub(d.prevTime).Seconds()
+	d.prevTime = now
+
+	d.push("used_memory", d.num("used_memory"))
+	d.push("connected_clients", d.num("connected_clients"))
+	d.push("blocked_clients", d.num("blocked_clients"))
+	d.push("ops", d.num("instantaneous_ops_per_sec"))
+	if d.prev != nil && elapsed > 0 {
+		hits := d.num("keyspace_hits") - d.prevNum("keyspace_hits")
+		misses := d.num("keyspace_misses") - d.prevNum("keyspace_misses")
+		if hits+misses > 0 {
+			d.push("hit_ratio", 100*hits/(hits+misses))
+		}
+		d.push("evicted", (d.num("evicted_keys")-d.prevNum("evicted_keys"))/elapsed)
+		d.push("expired", (d.num("expired_keys")-d.prevNum("expired_keys"))/elapsed)
+	}
+	c.renderDashboard(d)
+}
+
+func (d *dashboard) num(field string) float64 {
+	v, _ := strconv.ParseFloat(d.info[field], 64)
+	return v
+}
+
+func (d *dashboard) prevNum(field string) float64 {
+	v, _ := strconv.ParseFloat(d.prev[field], 64)
+	return v
+}
+
+func (d *dashboard) push(name string, v float64) {
+	h := append(d.hist[name], v)
+	if len(h) > dashHistory {
+		h = h[len(h)-dashHistory:]
+	}
+	d.hist[name] = h
+}
+
+// last returns the newest sample of a trend, or ok=false if there is none.
+func (d *dashboard) last(name string) (float64, bool) {
+	h := d.hist[name]
+	if len(h) == 0 {
+		return 0, false
+	}
+	return h[len(h)-1], true
+}
+
+func (c *Controller) renderDashboard(d *dashboard) {
+	if d.info == nil {
+		if d.err != nil {
+			d.tv.SetText(c.theme.Tag(c.theme.Error) + tview.Escape(d.err.Error()) + "[-]")
+		}
+		return
+	}
+	label, value := c.theme.Tag(c.theme.Label), c.theme.Tag(c.theme.Value)
+	spark := c.theme.Tag(c.theme.Highlight)
+	get := func(k string) string { return tview.Escape(d.info[k]) }
+	trend := func(name string) string {
+		return spark + view.Sparkline(d.hist[name]) + "[-]"
+	}
+	bytesOf := func(k string) string {
+		n, err := strconv.ParseInt(d.info[k], 10, 64)
+		if err != nil {
+			return get(k)
+		}
+		return humanBytes(n)
+	}
+
+	var b strings.Builder
+	row := func(name, format string, args ...any) {
+		fmt.Fprintf(&b, "%s %-10s%s %s\n", label, name, value, fmt.Sprintf(format, args...))
+	}
+	if d.err != nil {
+		fmt.Fprintf(&b, "%s(last poll failed: %s)[-]\n\n", c.theme.Tag(c.theme.Error), tview.Escape(d.err.Error()))
+	}
+
+	uptime := time.Duration(d.num("uptime_in_seconds")) * time.Second
+	row("Server", "Redis %s, %s, role %s, up %s", get("redis_version"), get("redis_mode"), get("role"), formatUptime(uptime))
+	b.WriteString("\n")
+
+	maxmem := "unlimited"
+	if d.num("maxmemory") > 0 {
+		maxmem = bytesOf("maxmemory")
+	}
+	row("Memory", "used %s  peak %s  fragmentation %s", bytesOf("used_memory"), bytesOf("used_memory_peak"), get("mem_fragmentation_ratio"))
+	row("", "%s", trend("used_memory"))
+	row("Limit", "maxmemory %s  policy %s", maxmem, get("maxmemory_policy"))
+	b.WriteString("\n")
+
+	row("Clients", "connected %s  %s", get("connected_clients"), trend("connected_clients"))
+	row("", "blocked %s  %s", get("blocked_clients"), trend("blocked_clients"))
+	b.WriteString("\n")
+
+	row("Ops/sec", "%s  %s", get("instantaneous_ops_per_sec"), trend("ops"))
+	b.WriteString("\n")
+
+	hits, misses := d.num("keyspace_hits"), d.num("keyspace_misses")
+	total := "n/a"
+	if hits+misses > 0 {
+		total = fmt.Sprintf("%.1f%%", 100*hits/(hits+misses))
+	}
+	now := "n/a"
+	if v, ok := d.last("hit_ratio"); ok {
+		now = fmt.Sprintf("%.1f%%", v)
+	}
+	row("Hit ratio", "%s since start, %s now  %s", total, now, trend("hit_ratio"))
+	rate := func(name string) string {
+		if v, ok := d.last(name); ok {
+			return fmt.Sprintf("%.1f/s", v)
+		}
+		return "n/a"
+	}
+	row("Evicted", "%s total, %s  %s", get("evicted_keys"), rate("evicted"), trend("evicted"))
+	row("Expired", "%s total, %s  %s", get("expired_keys"), rate("expired"), trend("expired"))
+	b.WriteString("\n")
+
+	for i := 0; i < 16; i++ {
+		if ks, ok := d.info[fmt.Sprintf("db%d", i)]; ok {
+			row(fmt.Sprintf("db%d", i), "%s", tview.Escape(ks))
+		}
+	}
+
+	d.tv.SetText(b.String())
+}
+
+func formatUptime(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh", days, int(d.Hours())%24)
+	}
+	return d.String()
+}
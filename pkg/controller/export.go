@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nexusriot/redis-walker/pkg/dump"
+)
+
+// exportProgressEvery is how many keys pass between progress redraws.
+const exportProgressEvery = 100
+
+// exportFolder exports the selected folder, or the current one if a key
+// is selected, to a file.
+func (c *Controller) exportFolder() *tcell.EventKey {
+	dir := c.targetFolder()
+	file := tview.NewInputField().
+		SetLabel("File").
+		SetText(c.suggestFile(dir, ".json")).
+		SetFieldWidth(50)
+	form := tview.NewForm().
+		AddFormItem(file).
+		AddDropDown("Format", dump.Formats, 0, func(format string, _ int) {
+			// keep the suggested extension in line with the format
+			text := file.GetText()
+			if ext := filepath.Ext(text); slices.Contains(dump.Formats, strings.TrimPrefix(ext, ".")) {
+				file.SetText(strings.TrimSuffix(text, ext) + "." + format)
+			}
+		})
+	c.showJobForm(form, "Export "+tview.Escape(dir), "Export", 72, 9, func() {
+		path := strings.TrimSpace(file.GetText())
+		_, format := form.GetFormItem(1).(*tview.DropDown).GetCurrentOption()
+		if path == "" {
+			return
+		}
+		c.view.Pages.RemovePage("modal")
+		c.runExport(dir, path, format)
+	})
+	return nil
+}
+
+// runExport writes dir to path in the background with writeFile.
+func (c *Controller) runExport(dir, path, format string) {
+	var skipped int
+	c.writeFile("Export", "Exporting", dir, path, func(ctx context.Context, f *os.File, count func()) error {
+		w, err := dump.NewWriter(format, f, c.model.ExportHeader(dir))
+		if err != nil {
+			return err
+		}
+		_, skipped, err = c.model.Export(ctx, dir, func(r *dump.Record) error {
+			if err := w.Write(r); err != nil {
+				return err
+			}
+			count()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return w.Close()
+	}, func(n int) string {
+		if skipped > 0 {
+			return fmt.Sprintf("Wrote %d keys to %s; %d keys of unsupported types were skipped (see log)", n, path, skipped)
+		}
+		return fmt.Sprintf("Wrote %d keys to %s", n, path)
+	})
+}
@@ -0,0 +1,194 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/view"
+)
+
+// graphWindow is the number of samples kept per graphed key.
+const graphWindow = 600
+
+// series is a rolling window of samples of one numeric key. The sampler
+// appends while the UI reads, hence the mutex.
+type series struct {
+	key    string
+	member string // zset member, or "" for a numeric string
+
+	mu    sync.Mutex
+	times []time.Time
+	vals  []float64
+	err   error // last sampling error
+}
+
+func (s *series) add(t time.Time, v float64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+	if err != nil {
+		return
+	}
+	s.times = append(s.times, t)
+	s.vals = append(s.vals, v)
+	if n := len(s.vals); n > graphWindow {
+		s.times = append(s.times[:0], s.times[n-graphWindow:]...)
+		s.vals = append(s.vals[:0], s.vals[n-graphWindow:]...)
+	}
+}
+
+// graphRun is the sampler of the key currently graphed in Details.
+type graphRun struct {
+	s    *series
+	stop chan struct{}
+}
+
+// toggleGraph starts graphing the selected key in Details, or stops it.
+// For zsets the member whose score is graphed is asked first.
+func (c *Controller) toggleGraph() *tcell.EventKey {
+	i := c.view.List.GetCurrentItem()
+	_, mapKey := c.view.List.GetItemText(i)
+	mapKey = strings.TrimSpace(mapKey)
+	val, ok := c.currentNodes[mapKey]
+	if !ok {
+		return nil
+	}
+	if c.graph != nil && c.graph.s.key == val.node.Name {
+		c.stopGraph()
+		c.fillDetails(mapKey)
+		return nil
+	}
+	switch {
+	case val.node.IsDir:
+		c.error("Graph", fmt.Errorf("only numeric keys can be graphed"), false)
+	case val.node.Type == "zset":
+		inp := c.view.NewInputDialog(" Graph the score of member ", "")
+		inp.SetDoneFunc(func(key tcell.Key) {
+			c.view.Pages.RemovePage("modal")
+			member := inp.GetText()
+			if key == tcell.KeyEnter && member != "" {
+				c.startGraph(val.node.Name, member, mapKey)
+			}
+		})
+		c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 60, 3), true, true)
+	default:
+		if _, err := c.model.Number(val.node.Name, ""); err != nil {
+			c.error("Graph", err, false)
+			return nil
+		}
+		c.startGraph(val.node.Name, "", mapKey)
+	}
+	return nil
+}
+
+func (c *Controller) startGraph(key, member, mapKey string) {
+	c.stopGraph()
+	id := key + "\x00" + member
+	s, ok := c.series[id]
+	if !ok {
+		s = &series{key: key, member: member}
+		c.series[id] = s
+	}
+	run := &graphRun{s: s, stop: make(chan struct{})}
+	c.graph = run
+	go c.sampleGraph(run)
+	c.fillDetails(mapKey)
+}
+
+// stopGraph stops the sampler; the samples stay in memory for the next
+// time the key is graphed. UI goroutine only.
+func (c *Controller) stopGraph() {
+	if c.graph == nil {
+		return
+	}
+	close(c.graph.stop)
+	c.graph = nil
+}
+
+func (c *Controller) sampleGraph(run *graphRun) {
+	t := time.NewTicker(c.watchInterval)
+	defer t.Stop()
+	sample := func() {
+		v, err := c.model.Number(run.s.key, run.s.member)
+		if err != nil {
+			log.WithError(err).WithField("key", run.s.key).Debug("graph sample failed")
+		}
+		run.s.add(time.Now(), v, err)
+	}
+	sample()
+	for {
+		select {
+		case <-run.stop:
+			return
+		case <-t.C:
+			sample()
+			c.view.App.QueueUpdateDraw(func() {
+				if c.graph == run {
+					c.redrawDetails()
+				}
+			})
+		}
+	}
+}
+
+// redrawDetails refreshes Details for the selected item.
+func (c *Controller) redrawDetails() {
+	_, mk := c.view.List.GetItemText(c.view.List.GetCurrentItem())
+	c.fillDetails(strings.TrimSpace(mk))
+}
+
+// writeGraph appends the sparkline and rate of the graphed key to Details.
+func (c *Controller) writeGraph(key string) {
+	if c.graph == nil || c.graph.s.key != key {
+		return
+	}
+	s := c.graph.s
+	s.mu.Lock()
+	times := append([]time.Time(nil), s.times...)
+	vals := append([]float64(nil), s.vals...)
+	sampleErr := s.err
+	s.mu.Unlock()
+
+	label, value := c.theme.Tag(c.theme.Label), c.theme.Tag(c.theme.Value)
+	w := c.view.Details
+	title := "Graph"
+	if s.member != "" {
+		title = fmt.Sprintf("Graph of score %q", s.member)
+	}
+	fmt.Fprintf(w, "%s %s (every %s, %d samples): %s\n", label, tview.Escape(title), c.watchInterval, len(vals), value)
+	if sampleErr != nil {
+		fmt.Fprintf(w, "%s%s[-]\n", c.theme.Tag(c.theme.Error), tview.Escape(sampleErr.Error()))
+	}
+	if len(vals) == 0 {
+		fmt.Fprintln(w, "(waiting for samples)")
+		return
+	}
+
+	_, _, width, _ := w.GetInnerRect()
+	shown := vals[max(0, len(vals)-max(width-1, 10)):]
+	lo, hi := shown[0], shown[0]
+	for _, v := range shown {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	n := len(vals)
+	fmt.Fprintf(w, "%s%s[-]\n", c.theme.Tag(c.theme.Highlight), view.Sparkline(shown))
+	fmt.Fprintf(w, "%s Current: %s %s   %s Min: %s %s   %s Max: %s %s\n",
+		label, value, formatNumber(vals[n-1]), label, value, formatNumber(lo), label, value, formatNumber(hi))
+	if n >= 2 {
+		last := (vals[n-1] - vals[n-2]) / times[n-1].Sub(times[n-2]).Seconds()
+		avg := (vals[n-1] - vals[0]) / times[n-1].Sub(times[0]).Seconds()
+		fmt.Fprintf(w, "%s Rate: %s %s/s   %s Avg rate: %s %s/s over %s\n",
+			label, value, formatNumber(last), label, value, formatNumber(avg), times[n-1].Sub(times[0]).Round(time.Second))
+	}
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'g', 10, 64)
+}
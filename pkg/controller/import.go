@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/dump"
+	"github.com/nexusriot/redis-walker/pkg/model"
+)
+
+// importFailuresShown is how many per-key errors the import summary lists.
+const importFailuresShown = 5
+
+// importFile asks for an exported document and where to put its keys,
+// then shows what the import would do before doing it.
+func (c *Controller) importFile() *tcell.EventKey {
+	if c.noServer("Import") {
+		return nil
+	}
+	c.loadFile("Import", "folder it was exported from", c.runImport)
+	return nil
+}
+
+// runImport runs an import, or its dry run, with runLoad. A dry run ends
+// in a summary that offers to run the import for real.
+func (c *Controller) runImport(path string, opts model.ImportOptions) {
+	open := func() (dump.Reader, error) { return dump.Open(path) }
+	c.runLoad("Import", "Importing", path, opts, func(ctx context.Context, opts model.ImportOptions, progress func(done, total int)) (*model.ImportResult, error) {
+		return c.server.Import(ctx, open, opts, progress)
+	}, c.runImport)
+}
+
+// showImportResult shows what an import (or, with title "Restore", a
+// snapshot restore) did or, after a dry run, would do. A dry run that can
+// go ahead offers a button that calls run.
+func (c *Controller) showImportResult(title, path string, opts model.ImportOptions, res *model.ImportResult, err error, run func(string, model.ImportOptions)) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "File:      %s\n", tview.Escape(path))
+	if opts.To != "" {
+		from := opts.From
+		if from == "" {
+			from = "(original folder)"
+		}
+		fmt.Fprintf(&b, "Remap:     %s -> %s\n", tview.Escape(from), tview.Escape(opts.To))
+	}
+	fmt.Fprintf(&b, "Keys:      %d\n", res.Keys)
+	if len(res.Types) > 0 {
+		fmt.Fprintf(&b, "Types:     %s\n", formatTypes(res.Types))
+	}
+	fmt.Fprintf(&b, "Existing:  %d (%s)\n", res.Conflicts, opts.Policy)
+	if opts.DryRun {
+		fmt.Fprintf(&b, "To write:  %d\n", res.Written)
+	} else {
+		fmt.Fprintf(&b, "Written:   %d\n", res.Written)
+	}
+	if res.Skipped > 0 {
+		fmt.Fprintf(&b, "Skipped:   %d\n", res.Skipped)
+	}
+	if err != nil {
+		fmt.Fprintf(&b, "\nError: %s\n", tview.Escape(err.Error()))
+	}
+	if len(res.Sample) > 0 {
+		b.WriteString("\nExisting keys:\n")
+		for _, k := range res.Sample {
+			fmt.Fprintf(&b, "  %s\n", tview.Escape(k))
+		}
+		if more := res.Conflicts - len(res.Sample); more > 0 {
+			fmt.Fprintf(&b, "  ... and %d more\n", more)
+		}
+	}
+	if len(res.Failed) > 0 {
+		fmt.Fprintf(&b, "\nFailed: %d (see log)\n", len(res.Failed))
+		for _, f := range res.Failed[:min(importFailuresShown, len(res.Failed))] {
+			fmt.Fprintf(&b, "  %s: %s\n", tview.Escape(f.Key), tview.Escape(f.Err.Error()))
+		}
+		if more := len(res.Failed) - importFailuresShown; more > 0 {
+			fmt.Fprintf(&b, "  ... and %d more\n", more)
+		}
+	}
+	for _, f := range res.Failed {
+		log.WithError(f.Err).WithField("key", f.Key).Warn(strings.ToLower(title) + " failed for key")
+	}
+	summary := strings.TrimRight(b.String(), "\n")
+	lines := strings.Count(summary, "\n") + 1
+
+	header := " " + title + " "
+	if opts.DryRun {
+		header = " " + title + " preview "
+	}
+	form := c.view.NewConfirmForm(header, summary, "", lines)
+	canRun := opts.DryRun && err == nil && res.Written > 0 && !c.model.ReadOnly() &&
+		!(opts.Policy == model.ConflictFail && res.Conflicts > 0)
+	if canRun {
+		form.AddButton(title, func() {
+			c.view.Pages.RemovePage("modal")
+			opts.DryRun = false
+			run(path, opts)
+		})
+	}
+	form.AddButton("Close", func() { c.view.Pages.RemovePage("modal") })
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(form, 80, lines+7), true, true)
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/model"
+)
+
+// Export, import, snapshot and restore all ask for a file in a form, then
+// work through a folder in the background behind a dialog that shows
+// progress and can cancel. The pieces they share are here.
+
+// targetFolder returns the selected folder, or the current one if a key
+// is selected.
+func (c *Controller) targetFolder() string {
+	if t := c.selectedTarget(); strings.HasSuffix(t, "/") {
+		return t
+	}
+	return c.currentDir
+}
+
+// suggestFile returns a file name for dir, stamped with the current time.
+func (c *Controller) suggestFile(dir, ext string) string {
+	name := strings.Trim(strings.ReplaceAll(dir, "/", "_"), "_")
+	if name == "" {
+		name = fmt.Sprintf("db%d", c.model.DB())
+	}
+	return fmt.Sprintf("redis-%s-%s%s", name, time.Now().Format("20060102-150405"), ext)
+}
+
+// showJobForm styles form, gives it an action button that calls submit
+// and a Cancel button, and shows it height rows high. Esc cancels too.
+func (c *Controller) showJobForm(form *tview.Form, title, action string, width, height int, submit func()) {
+	form.SetBorder(true).
+		SetTitle(" " + title + " ").
+		SetTitleAlign(tview.AlignLeft)
+	form.SetBorderPadding(1, 1, 2, 2)
+	form.SetLabelColor(c.theme.Secondary)
+	form.SetFieldTextColor(c.theme.Text)
+	form.SetButtonsAlign(tview.AlignCenter)
+	form.AddButton(action, submit)
+	form.AddButton("Cancel", func() { c.view.Pages.RemovePage("modal") })
+	form.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() == tcell.KeyEsc {
+			c.view.Pages.RemovePage("modal")
+			return nil
+		}
+		return ev
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(form, width, height), true, true)
+}
+
+// runJob runs work in the background behind a dialog reading text, whose
+// Cancel button cancels work's context. work may replace the text with
+// status, from any goroutine. Once work returns, the dialog is removed
+// and done runs on the UI goroutine, told whether the job was cancelled.
+func (c *Controller) runJob(text string, work func(ctx context.Context, status func(string)), done func(cancelled bool)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	progress := tview.NewModal().
+		SetText(text).
+		AddButtons([]string{"Cancel"}).
+		SetDoneFunc(func(int, string) { cancel() })
+	c.view.Pages.AddPage("modal", progress, true, true)
+
+	status := func(s string) {
+		c.view.App.QueueUpdateDraw(func() { progress.SetText(s) })
+	}
+	go func() {
+		work(ctx, status)
+		cancelled := ctx.Err() != nil
+		cancel()
+		c.view.App.QueueUpdateDraw(func() {
+			c.view.Pages.RemovePage("modal")
+			done(cancelled)
+		})
+	}()
+}
+
+// writeFile creates path and fills it in the background with runJob.
+// fill calls count once per key written, which redraws the progress
+// every exportProgressEvery keys. A cancelled or failed job leaves no
+// file; a finished one is reported with the message summary returns for
+// the number of keys. title names the job in dialogs and the log, verb
+// in the progress text.
+func (c *Controller) writeFile(title, verb, dir, path string, fill func(ctx context.Context, f *os.File, count func()) error, summary func(n int) string) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		c.error(title+" failed", err, false)
+		return
+	}
+	var n int
+	c.runJob(fmt.Sprintf("%s %s ...", verb, dir), func(ctx context.Context, status func(string)) {
+		err = fill(ctx, f, func() {
+			if n++; n%exportProgressEvery == 0 {
+				status(fmt.Sprintf("%s %s ... %d keys", verb, dir, n))
+			}
+		})
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(path)
+			log.WithError(err).WithField("path", path).Warn(strings.ToLower(title) + " failed")
+		}
+	}, func(cancelled bool) {
+		switch {
+		case cancelled && err != nil:
+			c.info(title, "Cancelled")
+		case err != nil:
+			c.error(title+" failed", err, false)
+		default:
+			c.info(title, summary(n))
+		}
+	})
+}
+
+// loadFile asks for a file to import or restore and where to put its
+// keys, then previews the load with run. from describes what "From
+// folder" means for this kind of file.
+func (c *Controller) loadFile(title, from string, run func(string, model.ImportOptions)) {
+	form := tview.NewForm().
+		AddInputField("File", "", 50, nil, nil).
+		AddInputField("From folder", "", 50, nil, nil).
+		AddInputField("To folder", c.targetFolder(), 50, nil, nil).
+		AddDropDown("If a key exists", model.ConflictPolicies, 0, nil)
+	form.GetFormItem(1).(*tview.InputField).SetPlaceholder(from)
+	form.GetFormItem(2).(*tview.InputField).SetPlaceholder("empty = original key names")
+	c.showJobForm(form, title, "Preview", 76, 15, func() {
+		path := strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText())
+		if path == "" {
+			return
+		}
+		policy, _ := form.GetFormItem(3).(*tview.DropDown).GetCurrentOption()
+		opts := model.ImportOptions{
+			From:   strings.TrimSpace(form.GetFormItem(1).(*tview.InputField).GetText()),
+			To:     strings.TrimSpace(form.GetFormItem(2).(*tview.InputField).GetText()),
+			Policy: model.ConflictPolicy(policy),
+			DryRun: true,
+		}
+		c.view.Pages.RemovePage("modal")
+		run(path, opts)
+	})
+}
+
+// loadFunc is Server.Import or Server.RestoreSnapshot with the file
+// already bound.
+type loadFunc func(ctx context.Context, opts model.ImportOptions, progress func(done, total int)) (*model.ImportResult, error)
+
+// runLoad runs an import or restore, or its dry run, with runJob and
+// summarizes it with showImportResult, whose button calls run to go
+// ahead after a dry run.
+func (c *Controller) runLoad(title, verb, path string, opts model.ImportOptions, load loadFunc, run func(string, model.ImportOptions)) {
+	if opts.DryRun {
+		verb = "Checking"
+	}
+	var (
+		res *model.ImportResult
+		err error
+	)
+	c.runJob(fmt.Sprintf("%s %s ...", verb, path), func(ctx context.Context, status func(string)) {
+		res, err = load(ctx, opts, func(done, total int) {
+			status(fmt.Sprintf("%s %s ... %d of %d keys", verb, path, done, total))
+		})
+	}, func(cancelled bool) {
+		switch {
+		case cancelled && opts.DryRun && err != nil:
+			c.info(title, "Cancelled")
+		case res == nil || (err != nil && res.Written == 0 && !errors.Is(err, model.ErrTargetExists)):
+			c.error(title+" failed", err, false)
+		default:
+			// a partial load is summarized with its error
+			c.showImportResult(title, path, opts, res, err, run)
+		}
+		if !opts.DryRun {
+			c.updateList()
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/model"
+)
+
+// liveDebounce collects a burst of notifications into one refresh.
+const liveDebounce = 250 * time.Millisecond
+
+type liveWatch struct {
+	dir string
+	w   *model.Watcher
+}
+
+// watchDir subscribes to keyspace notifications for dir, replacing the
+// subscription of the previous folder.
+func (c *Controller) watchDir(dir string) {
+	if !c.live || (c.watch != nil && c.watch.dir == dir) {
+		return
+	}
+	c.stopWatch()
+	w, err := c.server.WatchPrefix(dir)
+	if err != nil {
+		log.WithError(err).WithField("dir", dir).Warn("live refresh unavailable")
+		return
+	}
+	c.watch = &liveWatch{dir: dir, w: w}
+	go c.liveLoop(dir, w)
+}
+
+func (c *Controller) stopWatch() {
+	if c.watch == nil {
+		return
+	}
+	if err := c.watch.w.Close(); err != nil {
+		log.WithError(err).Debug("closing keyspace subscription")
+	}
+	c.watch = nil
+}
+
+// liveLoop batches notifications and applies them on the UI goroutine.
+// If the watcher had to drop notifications, the batch is incomplete and
+// the folder is re-read instead.
+func (c *Controller) liveLoop(dir string, w *model.Watcher) {
+	changed := map[string]bool{}
+	var flush <-chan time.Time
+	var dropped int64
+	for {
+		select {
+		case ev, ok := <-w.Events():
+			if !ok {
+				return
+			}
+			changed[ev.Key] = true
+			if flush == nil {
+				flush = time.After(liveDebounce)
+			}
+		case <-flush:
+			batch := changed
+			changed = map[string]bool{}
+			flush = nil
+			lost := w.Dropped() != dropped
+			dropped = w.Dropped()
+			c.view.App.QueueUpdateDraw(func() {
+				if c.currentDir == dir {
+					c.applyChanges(batch, lost)
+				}
+			})
+		}
+	}
+}
+
+// applyChanges updates the current folder after keys in changed were
+// touched by other clients, reading only those keys; reread re-lists the
+// whole folder instead. If the entries are the same, only Details of an
+// affected selected key are redrawn; otherwise the list is rebuilt with
+// the cursor kept on the same entry.
+func (c *Controller) applyChanges(changed map[string]bool, reread bool) {
+	idx := c.view.List.GetCurrentItem()
+	sel := ""
+	if idx >= 0 && idx < c.view.List.GetItemCount() {
+		_, sel = c.view.List.GetItemText(idx)
+		sel = strings.TrimSpace(sel)
+	}
+
+	var same bool
+	if reread {
+		old := c.currentNodes
+		if err := c.makeNodeMap(); err != nil {
+			log.WithError(err).Warn("live refresh failed")
+			return
+		}
+		same = sameEntries(old, c.currentNodes)
+	} else {
+		keys := make([]string, 0, len(changed))
+		for k := range changed {
+			keys = append(keys, k)
+		}
+		nodes, gone, err := c.server.LsKeys(c.currentDir, keys)
+		if err != nil {
+			log.WithError(err).Warn("live refresh failed")
+			return
+		}
+		same = c.mergeNodes(nodes, gone)
+	}
+
+	if same {
+		if n, ok := c.currentNodes[sel]; ok && !n.node.IsDir && changed[n.node.Name] {
+			c.fillDetails(sel)
+		}
+		return
+	}
+
+	c.renderList()
+	pos := min(idx, c.view.List.GetItemCount()-1)
+	for i := 0; i < c.view.List.GetItemCount(); i++ {
+		if _, mk := c.view.List.GetItemText(i); strings.TrimSpace(mk) == sel {
+			pos = i
+			break
+		}
+	}
+	c.view.List.SetCurrentItem(pos)
+	_, mk := c.view.List.GetItemText(c.view.List.GetCurrentItem())
+	c.fillDetails(strings.TrimSpace(mk))
+	c.dbg("live refresh", log.Fields{"dir": c.currentDir, "changed": len(changed), "reread": reread})
+}
+
+// mergeNodes adds or replaces the entries in nodes and removes the key
+// entries named in gone. It reports whether the set of entries and their
+// types stayed the same.
+func (c *Controller) mergeNodes(nodes []*model.Node, gone []string) bool {
+	same := true
+	for _, name := range gone {
+		mk := makeMapKey(baseName(name), false)
+		if _, ok := c.currentNodes[mk]; ok {
+			delete(c.currentNodes, mk)
+			same = false
+		}
+	}
+	for _, n := range nodes {
+		mk := makeMapKey(baseName(n.Name), n.IsDir)
+		old, ok := c.currentNodes[mk]
+		if !ok || old.node.Type != n.Type {
+			same = false
+		}
+		c.currentNodes[mk] = &Node{node: n}
+	}
+	return same
+}
+
+func sameEntries(a, b map[string]*Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for mk := range a {
+		if n, ok := b[mk]; !ok || n.node.Type != a[mk].node.Type {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+)
+
+// logLevelKeys maps the log pane's filter keys to the minimum level shown.
+var logLevelKeys = map[rune]log.Level{
+	'e': log.ErrorLevel,
+	'w': log.WarnLevel,
+	'i': log.InfoLevel,
+	'd': log.DebugLevel,
+	't': log.TraceLevel,
+}
+
+// showLogs opens the log pane on top of the main page.
+func (c *Controller) showLogs() *tcell.EventKey {
+	tv := c.view.NewLogView()
+	tv.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Key() {
+		case tcell.KeyEsc:
+			c.closeLogs()
+			return nil
+		case tcell.KeyRune:
+			r := ev.Rune()
+			if lvl, ok := logLevelKeys[r]; ok {
+				c.logLevel = lvl
+				c.renderLogs()
+				return nil
+			}
+			switch r {
+			case 'q':
+				c.closeLogs()
+				return nil
+			case 'c':
+				c.logs.Clear()
+				c.renderLogs()
+				return nil
+			}
+		}
+		return ev
+	})
+
+	c.logView = tv
+	c.renderLogs()
+	c.view.Pages.AddPage("logs", tv, true, true)
+	c.view.App.SetFocus(tv)
+	return nil
+}
+
+func (c *Controller) closeLogs() {
+	c.logView = nil
+	c.view.Pages.RemovePage("logs")
+	c.view.App.SetFocus(c.view.List)
+}
+
+// renderLogs redraws the log pane from the ring buffer. It must not log:
+// that would trigger another refresh.
+func (c *Controller) renderLogs() {
+	tv := c.logView
+	if tv == nil {
+		return
+	}
+	tv.SetTitle(fmt.Sprintf(" Logs (level >= %s)  [e]rror [w]arn [i]nfo [d]ebug [t]race  [c]lear  Esc=Close ",
+		c.logLevel))
+
+	var b strings.Builder
+	for _, e := range c.logs.Entries(c.logLevel) {
+		color := c.theme.Tag(c.theme.Value)
+		switch e.Level {
+		case log.PanicLevel, log.FatalLevel, log.ErrorLevel:
+			color = c.theme.Tag(c.theme.Error)
+		case log.WarnLevel:
+			color = c.theme.Tag(c.theme.Highlight)
+		case log.DebugLevel, log.TraceLevel:
+			color += "[::d]"
+		}
+		fmt.Fprintf(&b, "%s%s %-5s %s", color, e.Time.Format("15:04:05"),
+			strings.ToUpper(e.Level.String()), tview.Escape(e.Message))
+		if e.Fields != "" {
+			fmt.Fprintf(&b, "  %s", tview.Escape(e.Fields))
+		}
+		b.WriteString("[-::-]\n")
+	}
+	tv.SetText(b.String())
+	tv.ScrollToEnd()
+}
+
+// watchLogs refreshes the log pane whenever new entries arrive.
+func (c *Controller) watchLogs() {
+	for range c.logs.Updates() {
+		c.view.App.QueueUpdateDraw(func() {
+			c.renderLogs()
+		})
+	}
+}
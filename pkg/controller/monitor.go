@@ -0,0 +1,293 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/model"
+)
+
+const (
+	monitorBuffer  = 10000                  // captured commands kept in memory
+	monitorRefresh = 200 * time.Millisecond // batching of pane redraws
+)
+
+// monitorPane is the state of an open MONITOR pane. UI goroutine only,
+// except lines, which the reader appends to through QueueUpdateDraw.
+type monitorPane struct {
+	tv     *tview.TextView
+	cancel context.CancelFunc
+
+	lines   []model.MonitorLine
+	prefix  string // folder the pane was opened in; "" for the root
+	db      int    // database the folder is in
+	usePfx  bool   // show only commands touching keys under prefix in db
+	filter  string // case-insensitive substring of the raw line
+	paused  bool
+	unseen  int    // lines captured while paused
+	dropped int    // lines that fell out of the capped buffer
+	stopped string // why the stream ended, if it did
+}
+
+// showMonitor warns about the cost of MONITOR and opens the pane.
+func (c *Controller) showMonitor() *tcell.EventKey {
+	if c.noServer("Monitor") {
+		return nil
+	}
+	if !c.server.MonitorAllowed() {
+		c.error("Monitor", model.ErrMonitorDisabled, false)
+		return nil
+	}
+	q := c.view.NewConfirmForm(" MONITOR ",
+		"MONITOR streams every command the server executes to this client.\n"+
+			"On a busy server it can cut throughput substantially and use a lot\n"+
+			"of bandwidth. Avoid it on production servers and keep sessions short.",
+		"", 3)
+	q.AddButton("Start", func() {
+		c.view.Pages.RemovePage("modal")
+		c.startMonitor()
+	})
+	q.AddButton("Cancel", func() {
+		c.view.Pages.RemovePage("modal")
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(q, 76, 10), true, true)
+	return nil
+}
+
+func (c *Controller) startMonitor() {
+	tv := c.view.NewLogView()
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &monitorPane{tv: tv, cancel: cancel, db: c.model.DB(), usePfx: c.currentDir != "/"}
+	if p.usePfx {
+		p.prefix = strings.TrimSuffix(c.currentDir, "/") + "/"
+	}
+
+	ch := make(chan model.MonitorLine, 1024)
+	go func() {
+		err := c.server.Monitor(ctx, ch)
+		close(ch)
+		if err != nil {
+			log.WithError(err).Warn("monitor stopped")
+			c.view.App.QueueUpdateDraw(func() {
+				p.stopped = err.Error()
+				c.renderMonitor(p)
+			})
+		}
+	}()
+	go func() {
+		var batch []model.MonitorLine
+		tick := time.NewTicker(monitorRefresh)
+		defer tick.Stop()
+		for {
+			select {
+			case l, ok := <-ch:
+				if !ok {
+					// keep what arrived just before the stop
+					if len(batch) > 0 {
+						c.view.App.QueueUpdateDraw(func() {
+							c.appendMonitor(p, batch)
+						})
+					}
+					return
+				}
+				batch = append(batch, l)
+			case <-tick.C:
+				if len(batch) == 0 {
+					continue
+				}
+				b := batch
+				batch = nil
+				c.view.App.QueueUpdateDraw(func() {
+					c.appendMonitor(p, b)
+				})
+			}
+		}
+	}()
+
+	tv.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() == tcell.KeyEsc {
+			c.closeMonitor(p)
+			return nil
+		}
+		if ev.Key() != tcell.KeyRune {
+			return ev
+		}
+		switch ev.Rune() {
+		case 'q':
+			c.closeMonitor(p)
+		case ' ':
+			p.paused = !p.paused
+			p.unseen = 0
+			c.renderMonitor(p)
+		case 'p':
+			p.usePfx = !p.usePfx && p.prefix != ""
+			c.renderMonitor(p)
+		case 'c':
+			p.lines, p.unseen, p.dropped = nil, 0, 0
+			c.renderMonitor(p)
+		case '/':
+			inp := c.view.NewInputDialog(" Filter (substring, empty = none) ", p.filter)
+			inp.SetDoneFunc(func(key tcell.Key) {
+				c.view.Pages.RemovePage("modal")
+				if key == tcell.KeyEnter {
+					p.filter = strings.TrimSpace(inp.GetText())
+					c.renderMonitor(p)
+				}
+			})
+			c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 60, 3), true, true)
+		case 'e':
+			name := "redis-monitor-" + time.Now().Format("20060102-150405") + ".log"
+			inp := c.view.NewInputDialog(" Export shown commands to file ", name)
+			inp.SetDoneFunc(func(key tcell.Key) {
+				c.view.Pages.RemovePage("modal")
+				path := strings.TrimSpace(inp.GetText())
+				if key != tcell.KeyEnter || path == "" {
+					return
+				}
+				n, err := c.exportMonitor(p, path)
+				if err != nil {
+					c.error("Export failed", err, false)
+					return
+				}
+				c.info("Export", fmt.Sprintf("Wrote %d commands to %s", n, path))
+			})
+			c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 70, 3), true, true)
+		default:
+			return ev
+		}
+		return nil
+	})
+
+	c.renderMonitor(p)
+	c.view.Pages.AddPage("monitor", tv, true, true)
+	c.view.App.SetFocus(tv)
+}
+
+func (c *Controller) closeMonitor(p *monitorPane) {
+	p.cancel()
+	c.closePane("monitor")
+}
+
+func (c *Controller) appendMonitor(p *monitorPane, batch []model.MonitorLine) {
+	p.lines = append(p.lines, batch...)
+	if over := len(p.lines) - monitorBuffer; over > 0 {
+		p.lines = append(p.lines[:0], p.lines[over:]...)
+		p.dropped += over
+	}
+	if p.paused {
+		p.unseen += len(batch)
+		c.renderTitle(p)
+		return
+	}
+	c.renderMonitor(p)
+}
+
+// shown returns the captured lines passing the prefix and text filters.
+func (p *monitorPane) shown() []model.MonitorLine {
+	filter := strings.ToLower(p.filter)
+	out := make([]model.MonitorLine, 0, len(p.lines))
+	for _, l := range p.lines {
+		if p.usePfx && !touchesPrefix(l, p.db, p.prefix) {
+			continue
+		}
+		if filter != "" && !strings.Contains(strings.ToLower(l.Raw), filter) {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+// touchesPrefix reports whether the command ran in db and any argument
+// after the command name is a key under prefix.
+func touchesPrefix(l model.MonitorLine, db int, prefix string) bool {
+	if l.DB != db {
+		return false
+	}
+	for _, a := range l.Args[min(1, len(l.Args)):] {
+		if strings.HasPrefix(a, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Controller) renderTitle(p *monitorPane) {
+	var parts []string
+	if p.usePfx {
+		parts = append(parts, fmt.Sprintf("keys under %s in db %d", p.prefix, p.db))
+	} else {
+		parts = append(parts, "all keys")
+	}
+	if p.filter != "" {
+		parts = append(parts, fmt.Sprintf("filter %q", p.filter))
+	}
+	parts = append(parts, fmt.Sprintf("%d captured", len(p.lines)))
+	if p.dropped > 0 {
+		parts = append(parts, fmt.Sprintf("%d dropped", p.dropped))
+	}
+	if p.paused {
+		parts = append(parts, fmt.Sprintf("PAUSED, %d new", p.unseen))
+	}
+	if p.stopped != "" {
+		parts = append(parts, "STOPPED: "+p.stopped)
+	}
+	p.tv.SetTitle(tview.Escape(fmt.Sprintf(
+		" MONITOR (%s)  Space=Pause  /=Filter  p=Prefix on/off  c=Clear  e=Export  Esc=Close ",
+		strings.Join(parts, ", "))))
+}
+
+func (c *Controller) renderMonitor(p *monitorPane) {
+	c.renderTitle(p)
+	if p.paused {
+		return
+	}
+	var b strings.Builder
+	ts, cmd := c.theme.Tag(c.theme.Secondary), c.theme.Tag(c.theme.Highlight)
+	for _, l := range p.shown() {
+		fmt.Fprintf(&b, "%s%s [%d %s][-] %s%s[-]",
+			ts, l.Time.Format("15:04:05.000"), l.DB, tview.Escape(l.Client), cmd, tview.Escape(strings.ToUpper(l.Args[0])))
+		for _, a := range l.Args[1:] {
+			b.WriteString(" " + tview.Escape(quoteArg(a)))
+		}
+		b.WriteByte('\n')
+	}
+	p.tv.SetText(b.String())
+	p.tv.ScrollToEnd()
+}
+
+// quoteArg quotes an argument only if it would be ambiguous unquoted.
+func quoteArg(a string) string {
+	if a == "" || strings.ContainsAny(a, " \"\\") || strconv.Quote(a) != `"`+a+`"` {
+		return strconv.Quote(a)
+	}
+	return a
+}
+
+// exportMonitor writes the shown commands in MONITOR's own format.
+func (c *Controller) exportMonitor(p *monitorPane, path string) (int, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	lines := p.shown()
+	for _, l := range lines {
+		if _, err := fmt.Fprintln(f, l.Raw); err != nil {
+			f.Close()
+			return 0, err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	log.WithFields(log.Fields{"path": path, "commands": len(lines)}).Info("exported monitor capture")
+	return len(lines), nil
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// DefaultConfirmThreshold is the key count from which recursive operations
+// require typing the folder name.
+const DefaultConfirmThreshold = 100
+
+// confirmRecursive previews the keys under dir and asks for confirmation
+// before running action. extra is shown above the summary (e.g. the rename
+// target). Above the confirm threshold the user must type the folder name.
+func (c *Controller) confirmRecursive(header, verb, dir, extra string, action func()) {
+	st, err := c.model.PreviewDir(dir)
+	if err != nil {
+		c.error("Failed to scan folder", err, false)
+		return
+	}
+
+	var b strings.Builder
+	if extra != "" {
+		b.WriteString(extra + "\n")
+	}
+	fmt.Fprintf(&b, "Folder: %s/\n", tview.Escape(st.Dir))
+	fmt.Fprintf(&b, "Keys:   %d\n", st.Keys)
+	switch {
+	case st.Keys == 0:
+	case st.Measured == 0:
+		b.WriteString("Memory: unknown (MEMORY USAGE unavailable)\n")
+	case st.Estimated():
+		fmt.Fprintf(&b, "Memory: ~%s (estimated from %d keys)\n", humanBytes(st.Bytes), st.Measured)
+	default:
+		fmt.Fprintf(&b, "Memory: %s\n", humanBytes(st.Bytes))
+	}
+	if len(st.ByType) > 0 {
+		fmt.Fprintf(&b, "Types:  %s\n", formatTypes(st.ByType))
+	}
+	if len(st.Sample) > 0 {
+		b.WriteString("\nSample:\n")
+		for _, k := range st.Sample {
+			fmt.Fprintf(&b, "  %s\n", tview.Escape(k))
+		}
+		if more := st.Keys - len(st.Sample); more > 0 {
+			fmt.Fprintf(&b, "  ... and %d more\n", more)
+		}
+	}
+	summary := strings.TrimRight(b.String(), "\n")
+	lines := strings.Count(summary, "\n") + 1
+
+	confirmText := ""
+	if c.confirmThreshold > 0 && st.Keys >= c.confirmThreshold {
+		confirmText = baseOf(dir)
+	}
+
+	form := c.view.NewConfirmForm(header, summary, confirmText, lines)
+	form.AddButton(verb, func() {
+		if confirmText != "" {
+			typed := strings.TrimSpace(form.GetFormItem(1).(*tview.InputField).GetText())
+			if typed != confirmText {
+				form.SetTitle(fmt.Sprintf("%s - type %q exactly to confirm", header, confirmText))
+				return
+			}
+		}
+		c.view.Pages.RemovePage("modal")
+		action()
+	})
+	form.AddButton("Cancel", func() {
+		c.view.Pages.RemovePage("modal")
+	})
+
+	height := lines + 7
+	if confirmText != "" {
+		height += 2
+	}
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(form, 72, height), true, true)
+}
+
+// formatTypes lists key counts per type, most frequent first.
+func formatTypes(byType map[string]int) string {
+	types := make([]string, 0, len(byType))
+	for t := range byType {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if byType[types[i]] != byType[types[j]] {
+			return byType[types[i]] > byType[types[j]]
+		}
+		return types[i] < types[j]
+	})
+	parts := make([]string, 0, len(types))
+	for _, t := range types {
+		parts = append(parts, fmt.Sprintf("%s %d", t, byType[t]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,324 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nexusriot/redis-walker/pkg/model"
+)
+
+const pubsubBuffer = 5000 // received events kept in memory
+
+// pubsubPane is the state of an open pub/sub console. UI goroutine only.
+type pubsubPane struct {
+	tv  *tview.TextView
+	sub *model.Subscriber
+
+	events  []model.PubSubEvent
+	paused  bool
+	unseen  int   // events received while paused
+	dropped int   // events that fell out of the capped buffer
+	lost    int64 // Subscriber.Dropped when the pane was last cleared
+}
+
+// showPubSub opens the pub/sub console.
+func (c *Controller) showPubSub() *tcell.EventKey {
+	if c.noServer("Pub/Sub") {
+		return nil
+	}
+	tv := c.view.NewLogView()
+	p := &pubsubPane{tv: tv, sub: c.server.NewSubscriber()}
+
+	go func() {
+		var batch []model.PubSubEvent
+		tick := time.NewTicker(monitorRefresh)
+		defer tick.Stop()
+		for {
+			select {
+			case ev, ok := <-p.sub.Events():
+				if !ok {
+					return
+				}
+				batch = append(batch, ev)
+			case <-tick.C:
+				if len(batch) == 0 {
+					continue
+				}
+				b := batch
+				batch = nil
+				c.view.App.QueueUpdateDraw(func() {
+					c.appendPubSub(p, b)
+				})
+			}
+		}
+	}()
+
+	tv.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() == tcell.KeyEsc {
+			c.closePubSub(p)
+			return nil
+		}
+		if ev.Key() != tcell.KeyRune {
+			return ev
+		}
+		switch ev.Rune() {
+		case 'q':
+			c.closePubSub(p)
+		case 's':
+			c.pubsubSubscribe(p, model.SubChannel)
+		case 'p':
+			c.pubsubSubscribe(p, model.SubPattern)
+		case 'S':
+			c.pubsubSubscribe(p, model.SubShard)
+		case 'u':
+			c.pubsubUnsubscribe(p)
+		case 'P':
+			c.pubsubPublish(p)
+		case 'l':
+			c.pubsubChannels(p)
+		case ' ':
+			p.paused = !p.paused
+			p.unseen = 0
+			c.renderPubSub(p)
+		case 'c':
+			p.events, p.unseen, p.dropped, p.lost = nil, 0, 0, p.sub.Dropped()
+			c.renderPubSub(p)
+		default:
+			return ev
+		}
+		return nil
+	})
+
+	c.renderPubSub(p)
+	c.view.Pages.AddPage("pubsub", tv, true, true)
+	c.view.App.SetFocus(tv)
+	return nil
+}
+
+func (c *Controller) closePubSub(p *pubsubPane) {
+	p.sub.Close()
+	c.closePane("pubsub")
+}
+
+// pubsubSubscribe asks for space-separated channel names (or patterns)
+// and subscribes to them.
+func (c *Controller) pubsubSubscribe(p *pubsubPane, kind model.SubKind) {
+	title := map[model.SubKind]string{
+		model.SubChannel: " SUBSCRIBE channels (space-separated) ",
+		model.SubPattern: " PSUBSCRIBE patterns (space-separated) ",
+		model.SubShard:   " SSUBSCRIBE shard channels (space-separated, Redis 7+) ",
+	}[kind]
+	inp := c.view.NewInputDialog(title, "")
+	inp.SetDoneFunc(func(key tcell.Key) {
+		c.view.Pages.RemovePage("modal")
+		names := strings.Fields(inp.GetText())
+		if key != tcell.KeyEnter || len(names) == 0 {
+			return
+		}
+		if err := p.sub.Subscribe(kind, names...); err != nil {
+			c.error("Subscribe failed", err, false)
+			return
+		}
+		c.renderPubSub(p)
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 70, 3), true, true)
+}
+
+// pubsubUnsubscribe asks which subscriptions to drop; empty drops all.
+func (c *Controller) pubsubUnsubscribe(p *pubsubPane) {
+	inp := c.view.NewInputDialog(" Unsubscribe (channels or patterns, empty = all) ", "")
+	inp.SetDoneFunc(func(key tcell.Key) {
+		c.view.Pages.RemovePage("modal")
+		if key != tcell.KeyEnter {
+			return
+		}
+		names := strings.Fields(inp.GetText())
+		var err error
+		if len(names) == 0 {
+			err = p.sub.UnsubscribeAll()
+		}
+		for _, n := range names {
+			for _, kind := range []model.SubKind{model.SubChannel, model.SubPattern, model.SubShard} {
+				if err == nil && slices.Contains(p.sub.Subscriptions(kind), n) {
+					err = p.sub.Unsubscribe(kind, n)
+				}
+			}
+		}
+		if err != nil {
+			c.error("Unsubscribe failed", err, false)
+		}
+		c.renderPubSub(p)
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 70, 3), true, true)
+}
+
+// pubsubPublish asks for a channel and a message and publishes it.
+func (c *Controller) pubsubPublish(p *pubsubPane) {
+	if c.refuseWrite() {
+		return
+	}
+	channel := ""
+	if subs := p.sub.Subscriptions(model.SubChannel); len(subs) > 0 {
+		channel = subs[0]
+	}
+	form := tview.NewForm().
+		AddInputField("Channel", channel, 40, nil, nil).
+		AddInputField("Message", "", 40, nil, nil).
+		AddCheckbox("Sharded (SPUBLISH)", false, nil)
+	form.SetBorder(true).
+		SetTitle(" PUBLISH ").
+		SetTitleAlign(tview.AlignLeft)
+	form.SetBorderPadding(1, 1, 2, 2)
+	form.SetLabelColor(c.theme.Secondary)
+	form.SetFieldTextColor(c.theme.Text)
+	form.SetButtonsAlign(tview.AlignCenter)
+	form.AddButton("Publish", func() {
+		ch := strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText())
+		msg := form.GetFormItem(1).(*tview.InputField).GetText()
+		shard := form.GetFormItem(2).(*tview.Checkbox).IsChecked()
+		if ch == "" {
+			return
+		}
+		c.view.Pages.RemovePage("modal")
+		n, err := c.server.Publish(ch, msg, shard)
+		if err != nil {
+			c.error("Publish failed", err, false)
+			return
+		}
+		p.tv.SetTitle(c.pubsubTitle(p, fmt.Sprintf("published to %s, %d receivers", ch, n)))
+	})
+	form.AddButton("Cancel", func() {
+		c.view.Pages.RemovePage("modal")
+	})
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			c.view.Pages.RemovePage("modal")
+			return nil
+		}
+		return event
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(form, 64, 11), true, true)
+}
+
+// pubsubChannels lists active channels with subscriber counts; Enter
+// subscribes to the selected one.
+func (c *Controller) pubsubChannels(p *pubsubPane) {
+	chans, numPat, err := c.server.PubSubChannels("")
+	if err != nil {
+		c.error("PUBSUB", err, false)
+		return
+	}
+	table := c.view.NewTable(
+		fmt.Sprintf(" Active channels (%d, %d pattern subscriptions)  Enter=Subscribe  Esc=Close ", len(chans), numPat),
+		"Channel", "Subscribers", "Type")
+	for i, ch := range chans {
+		typ := "channel"
+		if ch.Shard {
+			typ = "shard"
+		}
+		table.SetCell(i+1, 0, tview.NewTableCell(tview.Escape(ch.Name)).SetExpansion(1))
+		table.SetCell(i+1, 1, tview.NewTableCell(strconv.FormatInt(ch.Subscribers, 10)).SetAlign(tview.AlignRight))
+		table.SetCell(i+1, 2, tview.NewTableCell(typ))
+	}
+	table.SetSelectedFunc(func(row, _ int) {
+		if row < 1 || row > len(chans) {
+			return
+		}
+		ch := chans[row-1]
+		kind := model.SubChannel
+		if ch.Shard {
+			kind = model.SubShard
+		}
+		c.view.Pages.RemovePage("modal")
+		if err := p.sub.Subscribe(kind, ch.Name); err != nil {
+			c.error("Subscribe failed", err, false)
+			return
+		}
+		c.renderPubSub(p)
+	})
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() == tcell.KeyEsc || (ev.Key() == tcell.KeyRune && ev.Rune() == 'q') {
+			c.view.Pages.RemovePage("modal")
+			return nil
+		}
+		return ev
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(table, 80, 20), true, true)
+}
+
+func (c *Controller) appendPubSub(p *pubsubPane, batch []model.PubSubEvent) {
+	p.events = append(p.events, batch...)
+	if over := len(p.events) - pubsubBuffer; over > 0 {
+		p.events = append(p.events[:0], p.events[over:]...)
+		p.dropped += over
+	}
+	if p.paused {
+		p.unseen += len(batch)
+		p.tv.SetTitle(c.pubsubTitle(p, ""))
+		return
+	}
+	c.renderPubSub(p)
+}
+
+func (c *Controller) pubsubTitle(p *pubsubPane, note string) string {
+	var parts []string
+	for _, kind := range []model.SubKind{model.SubChannel, model.SubPattern, model.SubShard} {
+		if subs := p.sub.Subscriptions(kind); len(subs) > 0 {
+			parts = append(parts, kind.String()+": "+strings.Join(subs, " "))
+		}
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "not subscribed")
+	}
+	if p.dropped > 0 {
+		parts = append(parts, fmt.Sprintf("%d dropped", p.dropped))
+	}
+	if lost := p.sub.Dropped() - p.lost; lost > 0 {
+		parts = append(parts, fmt.Sprintf("%d lost", lost))
+	}
+	if p.paused {
+		parts = append(parts, fmt.Sprintf("PAUSED, %d new", p.unseen))
+	}
+	if note != "" {
+		parts = append(parts, note)
+	}
+	return tview.Escape(fmt.Sprintf(
+		" Pub/Sub (%s)  s=Subscribe  p=Pattern  S=Shard  u=Unsubscribe  P=Publish  l=Channels  Space=Pause  c=Clear  Esc=Close ",
+		strings.Join(parts, ", ")))
+}
+
+func (c *Controller) renderPubSub(p *pubsubPane) {
+	p.tv.SetTitle(c.pubsubTitle(p, ""))
+	if p.paused {
+		return
+	}
+	var b strings.Builder
+	ts, ch := c.theme.Tag(c.theme.Secondary), c.theme.Tag(c.theme.Highlight)
+	for _, ev := range p.events {
+		fmt.Fprintf(&b, "%s%s[-] ", ts, ev.Time.Format("15:04:05.000"))
+		switch ev.Kind {
+		case "message", "smessage", "pmessage":
+			fmt.Fprintf(&b, "%s%s[-]", ch, tview.Escape(ev.Channel))
+			if ev.Pattern != "" {
+				fmt.Fprintf(&b, " (%s)", tview.Escape(ev.Pattern))
+			}
+			b.WriteString(" " + tview.Escape(ev.Payload))
+		case "error":
+			fmt.Fprintf(&b, "%serror: %s[-]", c.theme.Tag(c.theme.Error), tview.Escape(ev.Err.Error()))
+		default:
+			fmt.Fprintf(&b, "[::d]%s %s (%d active)[::-]", ev.Kind, tview.Escape(ev.Channel), ev.Count)
+		}
+		b.WriteByte('\n')
+	}
+	if len(p.events) == 0 {
+		b.WriteString("[::d]Press s to SUBSCRIBE, p to PSUBSCRIBE, l to list active channels.[::-]\n")
+	}
+	p.tv.SetText(b.String())
+	p.tv.ScrollToEnd()
+}
@@ -0,0 +1,378 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nexusriot/redis-walker/pkg/model"
+)
+
+// scriptPane is the state of the scripting screen. The script text is
+// kept in c.script between openings.
+type scriptPane struct {
+	editor *tview.TextArea
+	params *tview.Form
+	result *tview.TextView
+}
+
+// selectedTarget returns the selected key, or the selected (or current)
+// folder with a trailing '/', as the default KEYS of a script.
+func (c *Controller) selectedTarget() string {
+	_, mapKey := c.view.List.GetItemText(c.view.List.GetCurrentItem())
+	if val, ok := c.currentNodes[strings.TrimSpace(mapKey)]; ok {
+		if val.node.IsDir {
+			return strings.TrimSuffix(val.node.Name, "/") + "/"
+		}
+		return val.node.Name
+	}
+	return c.currentDir
+}
+
+// showScripts opens the Lua scripting screen.
+func (c *Controller) showScripts() *tcell.EventKey {
+	if c.noServer("Scripts") {
+		return nil
+	}
+	p := &scriptPane{}
+	p.editor = tview.NewTextArea().
+		SetText(c.script, false).
+		SetPlaceholder("-- Lua script, e.g.\nreturn redis.call('TYPE', KEYS[1])")
+	p.editor.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetTitle(" Script  Ctrl+R=Run  Ctrl+O=Open file  Ctrl+F=Functions  Ctrl+W=Switch focus  Esc=Close ")
+
+	p.params = tview.NewForm().
+		AddInputField("KEYS", quoteArgs([]string{c.selectedTarget()}), 0, nil, nil).
+		AddInputField("ARGV", "", 0, nil, nil).
+		AddCheckbox("Read-only (EVAL_RO)", c.model.ReadOnly(), nil)
+	p.params.SetBorder(true).
+		SetTitle(" Parameters (space-separated, quote as in the console) ").
+		SetTitleAlign(tview.AlignLeft)
+	p.params.SetLabelColor(c.theme.Secondary)
+	p.params.SetFieldTextColor(c.theme.Text)
+	p.params.SetFieldBackgroundColor(tcell.ColorDefault)
+	p.params.AddButton("Run", func() { c.runScript(p) })
+
+	p.result = tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true)
+	p.result.SetBorder(true).
+		SetTitle(" Result ").
+		SetTitleAlign(tview.AlignLeft)
+
+	bottom := tview.NewFlex().
+		AddItem(p.params, 0, 1, false).
+		AddItem(p.result, 0, 1, false)
+	root := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(p.editor, 0, 3, true).
+		AddItem(bottom, 9, 0, false)
+
+	root.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Key() {
+		case tcell.KeyEsc:
+			c.script = p.editor.GetText()
+			c.closePane("scripts")
+		case tcell.KeyCtrlR:
+			c.runScript(p)
+		case tcell.KeyCtrlO:
+			c.openScript(p)
+		case tcell.KeyCtrlF:
+			c.script = p.editor.GetText()
+			c.showFunctions()
+		case tcell.KeyCtrlW:
+			if p.editor.HasFocus() {
+				c.view.App.SetFocus(p.params)
+			} else {
+				c.view.App.SetFocus(p.editor)
+			}
+		default:
+			return ev
+		}
+		return nil
+	})
+
+	c.view.Pages.AddPage("scripts", root, true, true)
+	c.view.App.SetFocus(p.editor)
+	return nil
+}
+
+// quoteArgs joins arguments so that splitArgs gives them back.
+func quoteArgs(args []string) string {
+	out := make([]string, len(args))
+	for i, a := range args {
+		out[i] = quoteArg(a)
+	}
+	return strings.Join(out, " ")
+}
+
+// scriptParams reads KEYS, ARGV and the read-only flag from a form laid
+// out as in showScripts.
+func scriptParams(form *tview.Form) (keys, args []string, readOnly bool, err error) {
+	if keys, err = splitArgs(form.GetFormItem(0).(*tview.InputField).GetText()); err != nil {
+		return nil, nil, false, fmt.Errorf("KEYS: %w", err)
+	}
+	if args, err = splitArgs(form.GetFormItem(1).(*tview.InputField).GetText()); err != nil {
+		return nil, nil, false, fmt.Errorf("ARGV: %w", err)
+	}
+	return keys, args, form.GetFormItem(2).(*tview.Checkbox).IsChecked(), nil
+}
+
+func (c *Controller) runScript(p *scriptPane) {
+	c.script = p.editor.GetText()
+	if strings.TrimSpace(c.script) == "" {
+		return
+	}
+	keys, args, ro, err := scriptParams(p.params)
+	if err == nil {
+		var res interface{}
+		res, err = c.server.RunScript(c.script, keys, args, ro)
+		if err == nil {
+			c.showResult(p.result, res)
+			if !ro {
+				c.updateList()
+			}
+			return
+		}
+	}
+	p.result.SetText(c.consoleError(err))
+}
+
+func (c *Controller) showResult(tv *tview.TextView, res interface{}) {
+	var b strings.Builder
+	writeReply(&b, res, "")
+	tv.SetText(b.String())
+	tv.ScrollToBeginning()
+}
+
+// openScript asks for a file and loads it into the editor.
+func (c *Controller) openScript(p *scriptPane) {
+	inp := c.view.NewInputDialog(" Open Lua script file ", "")
+	inp.SetDoneFunc(func(key tcell.Key) {
+		c.view.Pages.RemovePage("modal")
+		c.view.App.SetFocus(p.editor)
+		path := strings.TrimSpace(inp.GetText())
+		if key != tcell.KeyEnter || path == "" {
+			return
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			c.error("Open failed", err, false)
+			return
+		}
+		p.editor.SetText(string(data), false)
+		p.editor.SetTitle(" " + tview.Escape(path) + "  Ctrl+R=Run  Ctrl+O=Open file  Ctrl+F=Functions  Ctrl+W=Switch focus  Esc=Close ")
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 70, 3), true, true)
+}
+
+// showFunctions opens the FUNCTION LIST manager: one row per function,
+// grouped by library.
+func (c *Controller) showFunctions() {
+	table := c.view.NewTable(
+		" Functions  Enter=Call  l=Load from script  L=Load file  d/Del=Delete library  v=View code  r=Refresh  Esc=Close ",
+		"Library", "Function", "Flags", "Description")
+
+	type row struct {
+		lib model.FunctionLibrary
+		fn  *model.FunctionInfo
+	}
+	var rows []row
+	load := func() {
+		libs, err := c.server.Functions()
+		if err != nil {
+			c.error("FUNCTION LIST", err, false)
+			return
+		}
+		for r := table.GetRowCount() - 1; r > 0; r-- {
+			table.RemoveRow(r)
+		}
+		rows = rows[:0]
+		for _, l := range libs {
+			if len(l.Functions) == 0 {
+				rows = append(rows, row{lib: l})
+			}
+			for i := range l.Functions {
+				rows = append(rows, row{lib: l, fn: &l.Functions[i]})
+			}
+		}
+		for i, r := range rows {
+			fn, flags, desc := "", "", ""
+			if r.fn != nil {
+				fn, flags, desc = r.fn.Name, strings.Join(r.fn.Flags, ","), r.fn.Description
+			}
+			table.SetCell(i+1, 0, tview.NewTableCell(tview.Escape(r.lib.Name)).SetTextColor(c.theme.Highlight))
+			table.SetCell(i+1, 1, tview.NewTableCell(tview.Escape(fn)))
+			table.SetCell(i+1, 2, tview.NewTableCell(flags))
+			table.SetCell(i+1, 3, tview.NewTableCell(tview.Escape(desc)).SetExpansion(1))
+		}
+	}
+	selected := func() (row, bool) {
+		r, _ := table.GetSelection()
+		if r < 1 || r > len(rows) {
+			return row{}, false
+		}
+		return rows[r-1], true
+	}
+	loadCode := func(code string) {
+		if c.refuseWrite() {
+			return
+		}
+		q := c.view.NewConfirmForm(" FUNCTION LOAD ",
+			"Load this library? With Replace, a library of the same name is\nreplaced; otherwise loading it fails if the name is taken.", "", 2)
+		do := func(replace bool) {
+			c.view.Pages.RemovePage("modal")
+			name, err := c.server.LoadFunctions(code, replace)
+			if err != nil {
+				c.error("FUNCTION LOAD failed", err, false)
+				return
+			}
+			load()
+			c.info("FUNCTION LOAD", "Loaded library "+name)
+		}
+		q.AddButton("Load", func() { do(false) })
+		q.AddButton("Replace", func() { do(true) })
+		q.AddButton("Cancel", func() { c.view.Pages.RemovePage("modal") })
+		c.view.Pages.AddPage("modal", c.view.ModalEdit(q, 74, 9), true, true)
+	}
+
+	table.SetSelectedFunc(func(int, int) {
+		if r, ok := selected(); ok && r.fn != nil {
+			c.callFunction(*r.fn)
+		}
+	})
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case ev.Key() == tcell.KeyEsc || (ev.Key() == tcell.KeyRune && ev.Rune() == 'q'):
+			c.view.Pages.RemovePage("functions")
+			return nil
+		case ev.Key() == tcell.KeyDelete || (ev.Key() == tcell.KeyRune && ev.Rune() == 'd'):
+			r, ok := selected()
+			if !ok || c.refuseWrite() {
+				return nil
+			}
+			q := c.view.NewConfirmForm(" FUNCTION DELETE ",
+				fmt.Sprintf("Delete library %q and its %d functions?", r.lib.Name, len(r.lib.Functions)), "", 1)
+			q.AddButton("Delete", func() {
+				c.view.Pages.RemovePage("modal")
+				if err := c.server.DeleteFunctions(r.lib.Name); err != nil {
+					c.error("FUNCTION DELETE failed", err, false)
+					return
+				}
+				load()
+			})
+			q.AddButton("Cancel", func() { c.view.Pages.RemovePage("modal") })
+			c.view.Pages.AddPage("modal", c.view.ModalEdit(q, 70, 7), true, true)
+			return nil
+		case ev.Key() != tcell.KeyRune:
+			return ev
+		}
+		switch ev.Rune() {
+		case 'r':
+			load()
+		case 'l':
+			if strings.TrimSpace(c.script) == "" {
+				c.error("FUNCTION LOAD", fmt.Errorf("the script editor is empty"), false)
+				return nil
+			}
+			loadCode(c.script)
+		case 'L':
+			inp := c.view.NewInputDialog(" Load library from file ", "")
+			inp.SetDoneFunc(func(key tcell.Key) {
+				c.view.Pages.RemovePage("modal")
+				path := strings.TrimSpace(inp.GetText())
+				if key != tcell.KeyEnter || path == "" {
+					return
+				}
+				data, err := os.ReadFile(path)
+				if err != nil {
+					c.error("Open failed", err, false)
+					return
+				}
+				loadCode(string(data))
+			})
+			c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 70, 3), true, true)
+		case 'v':
+			if r, ok := selected(); ok {
+				tv := c.view.NewLogView()
+				tv.SetDynamicColors(false).
+					SetText(r.lib.Code).
+					SetTitle(" " + r.lib.Name + " (" + r.lib.Engine + ")  Esc=Close ")
+				tv.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+					if ev.Key() == tcell.KeyEsc || (ev.Key() == tcell.KeyRune && ev.Rune() == 'q') {
+						c.view.Pages.RemovePage("modal")
+						return nil
+					}
+					return ev
+				})
+				c.view.Pages.AddPage("modal", c.view.ModalEdit(tv, 100, 30), true, true)
+			}
+		default:
+			return ev
+		}
+		return nil
+	})
+
+	load()
+	c.view.Pages.AddPage("functions", table, true, true)
+	c.view.App.SetFocus(table)
+}
+
+// callFunction asks for KEYS and ARGV and calls fn with FCALL (or
+// FCALL_RO), showing the reply in a dialog.
+func (c *Controller) callFunction(fn model.FunctionInfo) {
+	noWrites := false
+	for _, f := range fn.Flags {
+		noWrites = noWrites || f == "no-writes"
+	}
+	form := tview.NewForm().
+		AddInputField("KEYS", quoteArgs([]string{c.selectedTarget()}), 0, nil, nil).
+		AddInputField("ARGV", "", 0, nil, nil).
+		AddCheckbox("Read-only (FCALL_RO)", noWrites || c.model.ReadOnly(), nil)
+	form.SetBorder(true).
+		SetTitle(" FCALL " + tview.Escape(fn.Name) + " ").
+		SetTitleAlign(tview.AlignLeft)
+	form.SetBorderPadding(1, 1, 2, 2)
+	form.SetLabelColor(c.theme.Secondary)
+	form.SetFieldTextColor(c.theme.Text)
+	form.SetButtonsAlign(tview.AlignCenter)
+	form.AddButton("Call", func() {
+		keys, args, ro, err := scriptParams(form)
+		if err != nil {
+			c.error("FCALL", err, false)
+			return
+		}
+		c.view.Pages.RemovePage("modal")
+		res, err := c.server.CallFunction(fn.Name, keys, args, ro)
+		if err != nil {
+			c.error("FCALL "+fn.Name, err, false)
+			return
+		}
+		if !ro {
+			c.updateList()
+		}
+		tv := c.view.NewLogView()
+		tv.SetTitle(" FCALL " + tview.Escape(fn.Name) + "  Esc=Close ")
+		c.showResult(tv, res)
+		tv.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+			if ev.Key() == tcell.KeyEsc || (ev.Key() == tcell.KeyRune && ev.Rune() == 'q') {
+				c.view.Pages.RemovePage("modal")
+				return nil
+			}
+			return ev
+		})
+		c.view.Pages.AddPage("modal", c.view.ModalEdit(tv, 90, 24), true, true)
+	})
+	form.AddButton("Cancel", func() { c.view.Pages.RemovePage("modal") })
+	form.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() == tcell.KeyEsc {
+			c.view.Pages.RemovePage("modal")
+			return nil
+		}
+		return ev
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(form, 70, 11), true, true)
+}
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// showServerConfig opens the CONFIG GET/SET browser.
+func (c *Controller) showServerConfig() *tcell.EventKey {
+	if c.noServer("Server config") {
+		return nil
+	}
+	table := c.view.NewTable("", "Parameter", "Value")
+	var (
+		params map[string]string
+		names  []string // shown, sorted
+		filter string
+	)
+	render := func() {
+		f := strings.ToLower(filter)
+		names = names[:0]
+		for name, value := range params {
+			if f == "" || strings.Contains(name, f) || strings.Contains(strings.ToLower(value), f) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for row := table.GetRowCount() - 1; row > 0; row-- {
+			table.RemoveRow(row)
+		}
+		for i, name := range names {
+			table.SetCell(i+1, 0, tview.NewTableCell(tview.Escape(name)).SetTextColor(c.theme.Label))
+			table.SetCell(i+1, 1, tview.NewTableCell(tview.Escape(params[name])).SetExpansion(1))
+		}
+		title := fmt.Sprintf(" Server config (%d of %d", len(names), len(params))
+		if filter != "" {
+			title += fmt.Sprintf(", filter %q", filter)
+		}
+		table.SetTitle(tview.Escape(title + ")  Enter=Edit  /=Search  r=Refresh  W=Rewrite  Esc=Close "))
+		if row, _ := table.GetSelection(); row < 1 || row > len(names) {
+			table.Select(min(1, len(names)), 0)
+		}
+	}
+	load := func() {
+		var err error
+		params, err = c.server.ServerConfig()
+		if err != nil {
+			c.error("CONFIG GET failed", err, false)
+			return
+		}
+		render()
+	}
+	// selectParam moves the cursor to name after a reload.
+	selectParam := func(name string) {
+		for i, n := range names {
+			if n == name {
+				table.Select(i+1, 0)
+				return
+			}
+		}
+	}
+
+	edit := func(name string) {
+		if c.refuseWrite() {
+			return
+		}
+		old := params[name]
+		inp := c.view.NewInputDialog(fmt.Sprintf(" CONFIG SET %s ", tview.Escape(name)), old)
+		inp.SetDoneFunc(func(key tcell.Key) {
+			c.view.Pages.RemovePage("modal")
+			value := inp.GetText()
+			if key != tcell.KeyEnter || value == old {
+				return
+			}
+			summary := fmt.Sprintf("Parameter: %s\nOld value: %s\nNew value: %s",
+				tview.Escape(name), tview.Escape(old), tview.Escape(value))
+			q := c.view.NewConfirmForm(" Change server configuration ", summary, "", 3)
+			q.AddButton("Apply", func() {
+				c.view.Pages.RemovePage("modal")
+				if err := c.server.SetServerConfig(name, value); err != nil {
+					c.error("CONFIG SET failed", err, false)
+					return
+				}
+				load()
+				selectParam(name)
+			})
+			q.AddButton("Cancel", func() {
+				c.view.Pages.RemovePage("modal")
+			})
+			c.view.Pages.AddPage("modal", c.view.ModalEdit(q, 72, 10), true, true)
+		})
+		c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 72, 3), true, true)
+	}
+
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Key() {
+		case tcell.KeyEsc:
+			c.closePane("config")
+			return nil
+		case tcell.KeyEnter:
+			if row, _ := table.GetSelection(); row >= 1 && row <= len(names) {
+				edit(names[row-1])
+			}
+			return nil
+		case tcell.KeyRune:
+		default:
+			return ev
+		}
+		switch ev.Rune() {
+		case 'q':
+			c.closePane("config")
+		case 'r':
+			load()
+		case '/':
+			inp := c.view.NewInputDialog(" Search parameters and values ", filter)
+			inp.SetDoneFunc(func(key tcell.Key) {
+				c.view.Pages.RemovePage("modal")
+				if key == tcell.KeyEnter {
+					filter = strings.TrimSpace(inp.GetText())
+					render()
+				}
+			})
+			c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 60, 3), true, true)
+		case 'W':
+			if c.refuseWrite() {
+				return nil
+			}
+			q := c.view.NewConfirmForm(" CONFIG REWRITE ",
+				"Write the running configuration to the server's config file?\n"+
+					"Comments and layout of the file are kept where possible.", "", 2)
+			q.AddButton("Rewrite", func() {
+				c.view.Pages.RemovePage("modal")
+				if err := c.server.RewriteServerConfig(); err != nil {
+					c.error("CONFIG REWRITE failed", err, false)
+					return
+				}
+				c.info("CONFIG REWRITE", "Configuration file rewritten")
+			})
+			q.AddButton("Cancel", func() {
+				c.view.Pages.RemovePage("modal")
+			})
+			c.view.Pages.AddPage("modal", c.view.ModalEdit(q, 72, 9), true, true)
+		default:
+			return ev
+		}
+		return nil
+	})
+
+	load()
+	c.view.Pages.AddPage("config", table, true, true)
+	c.view.App.SetFocus(table)
+	return nil
+}
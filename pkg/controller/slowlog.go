@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nexusriot/redis-walker/pkg/model"
+)
+
+// slowLogSize is how many slow log entries the pane fetches.
+const slowLogSize = 128
+
+// showSlowLog opens the slow log pane.
+func (c *Controller) showSlowLog() *tcell.EventKey {
+	if c.noServer("Slow log") {
+		return nil
+	}
+	table := c.view.NewTable(
+		" Slow log  Enter=Go to key  r=Refresh  R=Reset  Esc=Close ",
+		"ID", "Time", "Duration", "Client", "Command",
+	)
+	var entries []model.SlowEntry
+	load := func() {
+		var err error
+		entries, err = c.server.SlowLog(slowLogSize)
+		if err != nil {
+			c.error("Failed to read slow log", err, false)
+			return
+		}
+		for row := table.GetRowCount() - 1; row > 0; row-- {
+			table.RemoveRow(row)
+		}
+		for i, e := range entries {
+			client := e.ClientAddr
+			if e.ClientName != "" {
+				client += " (" + e.ClientName + ")"
+			}
+			cmd := make([]string, len(e.Args))
+			for j, a := range e.Args {
+				cmd[j] = quoteArg(a)
+			}
+			if len(cmd) > 0 {
+				cmd[0] = strings.ToUpper(cmd[0])
+			}
+			cells := []string{
+				fmt.Sprintf("%d", e.ID),
+				e.Time.Format("2006-01-02 15:04:05"),
+				e.Duration.String(),
+				client,
+				model.OneLine(strings.Join(cmd, " "), 200),
+			}
+			for col, text := range cells {
+				table.SetCell(i+1, col, tview.NewTableCell(tview.Escape(text)).SetExpansion(1))
+			}
+		}
+		if len(entries) > 0 {
+			table.Select(1, 0)
+		}
+	}
+
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case ev.Key() == tcell.KeyEsc, ev.Key() == tcell.KeyRune && ev.Rune() == 'q':
+			c.closePane("slowlog")
+			return nil
+		case ev.Key() == tcell.KeyEnter:
+			row, _ := table.GetSelection()
+			if row < 1 || row > len(entries) {
+				return nil
+			}
+			keys := c.server.CommandKeys(entries[row-1].Args)
+			if len(keys) == 0 {
+				c.error("Go to key", fmt.Errorf("this command references no key"), false)
+				return nil
+			}
+			c.closePane("slowlog")
+			c.jumpTo(normAbs(keys[0])) // key names are absolute, not relative to the current folder
+			return nil
+		case ev.Key() == tcell.KeyRune && ev.Rune() == 'r':
+			load()
+			return nil
+		case ev.Key() == tcell.KeyRune && ev.Rune() == 'R':
+			if c.refuseWrite() {
+				return nil
+			}
+			q := c.view.NewConfirmForm(" Reset slow log ", "Remove all slow log entries on the server?", "", 1)
+			q.AddButton("Reset", func() {
+				c.view.Pages.RemovePage("modal")
+				if err := c.server.ResetSlowLog(); err != nil {
+					c.error("Failed to reset slow log", err, false)
+					return
+				}
+				load()
+			})
+			q.AddButton("Cancel", func() {
+				c.view.Pages.RemovePage("modal")
+			})
+			c.view.Pages.AddPage("modal", c.view.ModalEdit(q, 50, 8), true, true)
+			return nil
+		}
+		return ev
+	})
+
+	load()
+	c.view.Pages.AddPage("slowlog", table, true, true)
+	c.view.App.SetFocus(table)
+	return nil
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nexusriot/redis-walker/pkg/model"
+	"github.com/nexusriot/redis-walker/pkg/snapshot"
+)
+
+// snapshotFolder writes the DUMP payloads of the selected folder, or the
+// current one if a key is selected, to a snapshot file.
+func (c *Controller) snapshotFolder() *tcell.EventKey {
+	if c.noServer("Snapshot") {
+		return nil
+	}
+	dir := c.targetFolder()
+	form := tview.NewForm().
+		AddInputField("File", c.suggestFile(dir, snapshot.Ext), 50, nil, nil)
+	c.showJobForm(form, "Snapshot "+tview.Escape(dir), "Snapshot", 72, 7, func() {
+		path := strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText())
+		if path == "" {
+			return
+		}
+		c.view.Pages.RemovePage("modal")
+		c.runSnapshot(dir, path)
+	})
+	return nil
+}
+
+// runSnapshot writes the snapshot of dir to path in the background with
+// writeFile.
+func (c *Controller) runSnapshot(dir, path string) {
+	c.writeFile("Snapshot", "Snapshotting", dir, path, func(ctx context.Context, f *os.File, count func()) error {
+		w, err := snapshot.NewWriter(f, c.server.SnapshotHeader(dir))
+		if err != nil {
+			return err
+		}
+		if _, err := c.server.SnapshotDir(ctx, dir, func(e *snapshot.Entry) error {
+			if err := w.Write(e); err != nil {
+				return err
+			}
+			count()
+			return nil
+		}); err != nil {
+			return err
+		}
+		return w.Close()
+	}, func(n int) string {
+		return fmt.Sprintf("Wrote %d keys to %s", n, path)
+	})
+}
+
+// restoreSnapshot asks for a snapshot file and where to restore its keys,
+// then shows what the restore would do before doing it.
+func (c *Controller) restoreSnapshot() *tcell.EventKey {
+	if c.noServer("Restore") {
+		return nil
+	}
+	c.loadFile("Restore snapshot", "folder the snapshot was taken of", c.runRestore)
+	return nil
+}
+
+// runRestore runs a snapshot restore, or its dry run, like runImport.
+func (c *Controller) runRestore(path string, opts model.ImportOptions) {
+	open := func() (*snapshot.Reader, error) { return snapshot.Open(path) }
+	c.runLoad("Restore", "Restoring", path, opts, func(ctx context.Context, opts model.ImportOptions, progress func(done, total int)) (*model.ImportResult, error) {
+		return c.server.RestoreSnapshot(ctx, open, opts, progress)
+	}, c.runRestore)
+}
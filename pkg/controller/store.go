@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nexusriot/redis-walker/pkg/audit"
+	"github.com/nexusriot/redis-walker/pkg/dump"
+	"github.com/nexusriot/redis-walker/pkg/model"
+	"github.com/nexusriot/redis-walker/pkg/snapshot"
+	"github.com/nexusriot/redis-walker/pkg/trash"
+)
+
+// Store is what the controller browses: a live server (*model.Model) or
+// an RDB file (*model.Offline).
+type Store interface {
+	Ls(directory string) ([]*model.Node, error)
+	Get(key string) (*model.Node, error)
+	Set(key, value string) error
+	SetIfUnchanged(key, base, value string) error
+	MkDir(directory string) error
+	Del(key string) error
+	DelDir(key string) error
+	RenameDir(oldDir, newDir string) error
+
+	PreviewDir(dir string) (*model.PrefixStats, error)
+	Snapshot(keys []string) ([]model.KeyState, error)
+	Number(key, member string) (float64, error)
+	ExportHeader(dir string) dump.Header
+	Export(ctx context.Context, dir string, fn func(*dump.Record) error) (exported, skipped int, err error)
+
+	ReadOnly() bool
+	Server() string
+	DB() int
+
+	Trash() *trash.Store
+	Undo() (*trash.Op, error)
+	RestoreFromTrash(id, target string, replace bool) (int, error)
+	Audit() *audit.Journal
+}
+
+// Server is a Store backed by a running server, which also offers the
+// server screens: dashboard, clients, config, slow log, monitor, pub/sub,
+// console, scripting, live refresh, import and folder snapshots.
+type Server interface {
+	Store
+
+	Role() string
+	WatchPrefix(dir string) (*model.Watcher, error)
+	LsKeys(directory string, keys []string) ([]*model.Node, []string, error)
+	Info() (map[string]string, error)
+
+	Clients() ([]model.ClientInfo, error)
+	KillClient(c model.ClientInfo) error
+	PauseClients(d time.Duration) error
+	UnpauseClients() error
+
+	ServerConfig() (map[string]string, error)
+	SetServerConfig(name, value string) error
+	RewriteServerConfig() error
+
+	SlowLog(n int64) ([]model.SlowEntry, error)
+	ResetSlowLog() error
+	CommandKeys(args []string) []string
+
+	Monitor(ctx context.Context, out chan<- model.MonitorLine) error
+	MonitorAllowed() bool
+
+	NewSubscriber() *model.Subscriber
+	Publish(channel, msg string, shard bool) (int64, error)
+	PubSubChannels(pattern string) ([]model.ChannelInfo, int64, error)
+
+	CommandDocs() (map[string]*model.CommandDoc, error)
+	Exec(args []string) (interface{}, error)
+	IsWrite(args []string) bool
+
+	RunScript(script string, keys, args []string, readOnly bool) (interface{}, error)
+	CallFunction(name string, keys, args []string, readOnly bool) (interface{}, error)
+	Functions() ([]model.FunctionLibrary, error)
+	LoadFunctions(code string, replace bool) (string, error)
+	DeleteFunctions(library string) error
+
+	Import(ctx context.Context, open func() (dump.Reader, error), opts model.ImportOptions, progress func(done, total int)) (*model.ImportResult, error)
+
+	SnapshotHeader(dir string) snapshot.Header
+	SnapshotDir(ctx context.Context, dir string, fn func(*snapshot.Entry) error) (int, error)
+	RestoreSnapshot(ctx context.Context, open func() (*snapshot.Reader, error), opts model.ImportOptions, progress func(done, total int)) (*model.ImportResult, error)
+}
+
+var errOffline = errors.New("not available while browsing an RDB file: there is no server")
+
+// noServer reports, with an error dialog, that the action needs a
+// server the controller does not have.
+func (c *Controller) noServer(header string) bool {
+	if c.server != nil {
+		return false
+	}
+	c.error(header, errOffline, false)
+	return true
+}
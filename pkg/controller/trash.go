@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nexusriot/redis-walker/pkg/model"
+	"github.com/nexusriot/redis-walker/pkg/trash"
+)
+
+// undo restores the last delete or overwrite on this server/db.
+func (c *Controller) undo() *tcell.EventKey {
+	if c.refuseWrite() {
+		return nil
+	}
+	op, err := c.model.Undo()
+	if errors.Is(err, model.ErrNothingToUndo) {
+		c.error("Undo", err, false)
+		return nil
+	}
+	if err != nil {
+		c.error("Undo failed", err, false)
+		c.updateList()
+		return nil
+	}
+	c.updateList()
+	c.info("Undo", fmt.Sprintf("Restored %s (%d keys)", trashLabel(op), op.Keys))
+	return nil
+}
+
+func trashLabel(op *trash.Op) string {
+	if op.Dir != "" {
+		return op.Dir + "/"
+	}
+	if op.Keys == 1 {
+		return op.Key
+	}
+	return fmt.Sprintf("%d keys", op.Keys)
+}
+
+// showTrash opens the trash browser.
+func (c *Controller) showTrash() *tcell.EventKey {
+	store := c.model.Trash()
+	if store == nil {
+		c.error("Trash", fmt.Errorf("trash is disabled"), false)
+		return nil
+	}
+
+	table := c.view.NewTable(
+		" Trash  Enter=Restore  R=Restore (replace)  r=Restore to...  x=Purge  Esc=Close ",
+		"Time", "Operation", "Keys", "Size", "Path", "Server/DB",
+	)
+	var ops []trash.Op
+	fill := func() {
+		for row := table.GetRowCount() - 1; row > 0; row-- {
+			table.RemoveRow(row)
+		}
+		ops = store.List()
+		for i, op := range ops {
+			row := i + 1
+			cells := []string{
+				op.Time.Format("2006-01-02 15:04:05"),
+				op.Kind,
+				fmt.Sprintf("%d", op.Keys),
+				humanBytes(op.Size()),
+				trashLabel(&op),
+				fmt.Sprintf("%s/%d", op.Server, op.DB),
+			}
+			for col, text := range cells {
+				table.SetCell(row, col, tview.NewTableCell(tview.Escape(text)).SetExpansion(1))
+			}
+		}
+		if len(ops) > 0 {
+			table.Select(1, 0)
+		}
+	}
+	selected := func() (*trash.Op, bool) {
+		row, _ := table.GetSelection()
+		if row < 1 || row > len(ops) {
+			return nil, false
+		}
+		return &ops[row-1], true
+	}
+	restore := func(op *trash.Op, target string, replace bool) {
+		if c.refuseWrite() {
+			return
+		}
+		if op.Server != c.model.Server() || op.DB != c.model.DB() {
+			c.error("Restore", fmt.Errorf("entry belongs to %s/%d, connected to %s/%d",
+				op.Server, op.DB, c.model.Server(), c.model.DB()), false)
+			return
+		}
+		n, err := c.model.RestoreFromTrash(op.ID, target, replace)
+		fill()
+		c.updateList()
+		if err != nil {
+			c.error("Restore incomplete", err, false)
+			return
+		}
+		c.info("Restore", fmt.Sprintf("Restored %d keys", n))
+	}
+
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Key() {
+		case tcell.KeyEsc:
+			c.closePane("trash")
+			return nil
+		case tcell.KeyEnter:
+			if op, ok := selected(); ok {
+				restore(op, "", false)
+			}
+			return nil
+		case tcell.KeyDelete:
+			ev = tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone)
+		}
+		if ev.Key() != tcell.KeyRune {
+			return ev
+		}
+		switch ev.Rune() {
+		case 'q':
+			c.closePane("trash")
+			return nil
+		case 'R':
+			if op, ok := selected(); ok {
+				restore(op, "", true)
+			}
+			return nil
+		case 'r':
+			op, ok := selected()
+			if !ok {
+				return nil
+			}
+			initial := trashLabel(op)
+			title := " Restore to key "
+			if op.Dir != "" {
+				title = " Restore into folder "
+			} else if op.Keys > 1 {
+				return nil
+			}
+			inp := c.view.NewInputDialog(title, strings.TrimSuffix(initial, "/"))
+			inp.SetDoneFunc(func(key tcell.Key) {
+				c.view.Pages.RemovePage("modal")
+				target := strings.TrimSpace(inp.GetText())
+				if key != tcell.KeyEnter || target == "" {
+					return
+				}
+				restore(op, target, false)
+			})
+			c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 70, 3), true, true)
+			return nil
+		case 'x':
+			op, ok := selected()
+			if !ok {
+				return nil
+			}
+			q := c.view.NewDeleteQ(fmt.Sprintf("trash entry %s (%d keys) permanently", trashLabel(op), op.Keys))
+			q.SetDoneFunc(func(_ int, label string) {
+				c.view.Pages.RemovePage("modal")
+				if label != "ok" {
+					return
+				}
+				if err := store.Remove(op.ID); err != nil {
+					c.error("Purge failed", err, false)
+				}
+				fill()
+			})
+			c.view.Pages.AddPage("modal", c.view.ModalEdit(q, 40, 8), true, true)
+			return nil
+		}
+		return ev
+	})
+
+	fill()
+	c.view.Pages.AddPage("trash", table, true, true)
+	c.view.App.SetFocus(table)
+	return nil
+}
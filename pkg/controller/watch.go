@@ -0,0 +1,252 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/keymap"
+	"github.com/nexusriot/redis-walker/pkg/model"
+)
+
+// DefaultWatchInterval is how often pinned keys are polled.
+const DefaultWatchInterval = time.Second
+
+// watchValueWidth truncates values in the watch panel.
+const watchValueWidth = 80
+
+// pin is a key in the watch panel and what the last polls saw of it.
+type pin struct {
+	key     string
+	state   model.KeyState
+	seen    bool      // polled at least once
+	changed time.Time // when a change was last seen; zero if never
+}
+
+// pinSet is shared between the UI goroutine and the poller.
+type pinSet struct {
+	mu   sync.Mutex
+	pins []*pin
+}
+
+func (s *pinSet) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, len(s.pins))
+	for i, p := range s.pins {
+		keys[i] = p.key
+	}
+	return keys
+}
+
+func (s *pinSet) has(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.pins {
+		if p.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// toggle pins key, or unpins it if already pinned; it reports whether the
+// key is pinned afterwards.
+func (s *pinSet) toggle(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, p := range s.pins {
+		if p.key == key {
+			s.pins = append(s.pins[:i], s.pins[i+1:]...)
+			return false
+		}
+	}
+	s.pins = append(s.pins, &pin{key: key})
+	return true
+}
+
+// update records a poll result, stamping keys whose value, type or
+// existence differs from the previous poll.
+func (s *pinSet) update(states []model.KeyState, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	byKey := make(map[string]model.KeyState, len(states))
+	for _, st := range states {
+		byKey[st.Key] = st
+	}
+	for _, p := range s.pins {
+		st, ok := byKey[p.key]
+		if !ok {
+			continue
+		}
+		if p.seen && (st.Exists != p.state.Exists || st.Type != p.state.Type || st.Value != p.state.Value) {
+			p.changed = now
+		}
+		p.state = st
+		p.seen = true
+	}
+}
+
+// rows returns copies of the pins for rendering.
+func (s *pinSet) rows() []pin {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]pin, len(s.pins))
+	for i, p := range s.pins {
+		out[i] = *p
+	}
+	return out
+}
+
+// togglePin pins or unpins the selected key.
+func (c *Controller) togglePin() *tcell.EventKey {
+	i := c.view.List.GetCurrentItem()
+	_, mapKey := c.view.List.GetItemText(i)
+	val, ok := c.currentNodes[strings.TrimSpace(mapKey)]
+	if !ok {
+		return nil
+	}
+	if val.node.IsDir {
+		c.error("Pin", fmt.Errorf("only keys can be pinned, not folders"), false)
+		return nil
+	}
+	pinned := c.pins.toggle(val.node.Name)
+	c.dbg("pin", log.Fields{"key": val.node.Name, "pinned": pinned})
+	c.refreshLabels()
+	c.renderPins()
+	return nil
+}
+
+// refreshLabels redraws the file labels in place, keeping the cursor.
+func (c *Controller) refreshLabels() {
+	for i := 0; i < c.view.List.GetItemCount(); i++ {
+		_, mk := c.view.List.GetItemText(i)
+		mk = strings.TrimSpace(mk)
+		if val, ok := c.currentNodes[mk]; ok && !val.node.IsDir {
+			c.view.List.SetItemText(i, c.fileLabel(val.node), mk)
+		}
+	}
+}
+
+// fileLabel renders a key's list label, marking pinned keys.
+func (c *Controller) fileLabel(n *model.Node) string {
+	base := baseOf(n.Name)
+	marker := "   "
+	if c.pins.has(n.Name) {
+		marker = "📌 "
+	}
+	return c.colorize(n, base, marker+displayName(base, false))
+}
+
+// pollPins polls the pinned keys until the app stops.
+func (c *Controller) pollPins() {
+	t := time.NewTicker(c.watchInterval)
+	defer t.Stop()
+	for range t.C {
+		keys := c.pins.keys()
+		if len(keys) == 0 {
+			continue
+		}
+		states, err := c.model.Snapshot(keys)
+		if err != nil {
+			log.WithError(err).Debug("watch poll failed")
+			continue
+		}
+		c.pins.update(states, time.Now())
+		c.view.App.QueueUpdateDraw(c.renderPins)
+	}
+}
+
+// showWatch opens the watch panel.
+func (c *Controller) showWatch() *tcell.EventKey {
+	table := c.view.NewTable(
+		fmt.Sprintf(" Watch (every %s)  d=Unpin  Esc=Close ", c.watchInterval),
+		"Key", "Type", "TTL", "Value", "Last change",
+	)
+	table.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case ev.Key() == tcell.KeyEsc, ev.Key() == tcell.KeyRune && ev.Rune() == 'q':
+			c.watchTable = nil
+			c.closePane("watch")
+			c.refreshLabels()
+			return nil
+		case ev.Key() == tcell.KeyDelete, ev.Key() == tcell.KeyRune && ev.Rune() == 'd':
+			row, _ := table.GetSelection()
+			if cell := table.GetCell(row, 0); row > 0 && cell != nil {
+				if key, ok := cell.GetReference().(string); ok {
+					c.pins.toggle(key)
+					c.renderPins()
+				}
+			}
+			return nil
+		}
+		return ev
+	})
+	c.watchTable = table
+	c.renderPins()
+	c.view.Pages.AddPage("watch", table, true, true)
+	c.view.App.SetFocus(table)
+	return nil
+}
+
+// renderPins redraws the watch panel if it is open. UI goroutine only.
+func (c *Controller) renderPins() {
+	table := c.watchTable
+	if table == nil {
+		return
+	}
+	selRow, _ := table.GetSelection()
+	for row := table.GetRowCount() - 1; row > 0; row-- {
+		table.RemoveRow(row)
+	}
+
+	now := time.Now()
+	recent := max(3*c.watchInterval, 3*time.Second)
+	rows := c.pins.rows()
+	if len(rows) == 0 {
+		table.SetCell(1, 0, tview.NewTableCell("No pinned keys. Pin keys from the list with "+
+			tview.Escape(c.keys.Label(keymap.Pin))+".").
+			SetTextColor(c.theme.Secondary).
+			SetSelectable(false))
+	}
+	for i, p := range rows {
+		typ, ttl, value, changed := "?", "", "(polling)", "-"
+		switch {
+		case !p.seen:
+		case !p.state.Exists:
+			typ, value = "-", "(missing)"
+		default:
+			typ, value = p.state.Type, model.OneLine(p.state.Value, watchValueWidth)
+			ttl = "-"
+			if p.state.TTL >= 0 {
+				ttl = p.state.TTL.Round(time.Second).String()
+			}
+		}
+		if !p.changed.IsZero() {
+			changed = fmt.Sprintf("%s (%s ago)", p.changed.Format("15:04:05"), now.Sub(p.changed).Round(time.Second))
+		}
+
+		color, attrs := c.theme.Text, tcell.AttrNone
+		if !p.changed.IsZero() && now.Sub(p.changed) < recent {
+			color, attrs = c.theme.Highlight, tcell.AttrBold
+		}
+		for col, text := range []string{p.key, typ, ttl, value, changed} {
+			cell := tview.NewTableCell(tview.Escape(text)).
+				SetTextColor(color).
+				SetAttributes(attrs).
+				SetExpansion(1)
+			if col == 0 {
+				cell.SetReference(p.key)
+			}
+			table.SetCell(i+1, col, cell)
+		}
+	}
+	if len(rows) > 0 {
+		table.Select(min(max(selRow, 1), len(rows)), 0)
+	}
+}
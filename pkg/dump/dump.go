@@ -0,0 +1,431 @@
+// Package dump defines the type-aware key records used by export and
+// import, and the JSON document they are stored in.
+package dump
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Version is the format version written to the document header.
+const Version = 1
+
+// Record is one key with its full contents. Exactly one of the value
+// fields is used, according to Type.
+type Record struct {
+	Key  string
+	Type string // string, hash, list, set, zset or stream
+	TTL  int64  // remaining time to live in ms; 0 = no expiry
+
+	String string
+	Hash   map[string]string
+	List   []string // also set members, sorted
+	ZSet   []ZMember
+	Stream []StreamEntry
+}
+
+// ZMember is a sorted-set member with its score.
+type ZMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+// zmemberJSON is the on-disk form of a ZMember. Infinite scores, which
+// JSON numbers cannot hold, are written as the strings "inf" and "-inf".
+type zmemberJSON struct {
+	Member string          `json:"member"`
+	Score  json.RawMessage `json:"score"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (z ZMember) MarshalJSON() ([]byte, error) {
+	score := []byte(formatScore(z.Score))
+	if math.IsInf(z.Score, 0) {
+		score = []byte(`"` + string(score) + `"`)
+	}
+	return json.Marshal(zmemberJSON{Member: z.Member, Score: score})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (z *ZMember) UnmarshalJSON(data []byte) error {
+	var zj zmemberJSON
+	if err := json.Unmarshal(data, &zj); err != nil {
+		return err
+	}
+	s := strings.Trim(string(zj.Score), `"`)
+	score, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("member %q: bad score %s", zj.Member, zj.Score)
+	}
+	*z = ZMember{Member: zj.Member, Score: score}
+	return nil
+}
+
+// formatScore formats a score the way Redis reads it back.
+func formatScore(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// StreamEntry is one stream entry. Fields alternate names and values in
+// the order they were added.
+type StreamEntry struct {
+	ID     string   `json:"id"`
+	Fields []string `json:"fields"`
+}
+
+// Header describes where a document came from.
+type Header struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Server     string    `json:"server"`
+	DB         int       `json:"db"`
+	Prefix     string    `json:"prefix"` // folder exported, "/" for the whole database
+}
+
+// jsonRecord is the on-disk form of a Record: the value's JSON shape
+// depends on the type. If any key, member, field or value is not valid
+// UTF-8, all of them are base64-encoded and Encoding is "base64".
+type jsonRecord struct {
+	Key      string          `json:"key"`
+	Type     string          `json:"type"`
+	TTL      int64           `json:"ttl_ms,omitempty"`
+	Encoding string          `json:"encoding,omitempty"`
+	Value    json.RawMessage `json:"value"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r *Record) MarshalJSON() ([]byte, error) {
+	enc := func(s string) string { return s }
+	jr := jsonRecord{Key: r.Key, Type: r.Type, TTL: r.TTL}
+	if !r.validUTF8() {
+		jr.Encoding = "base64"
+		enc = func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+		jr.Key = enc(r.Key)
+	}
+	var v interface{}
+	switch r.Type {
+	case "string":
+		v = enc(r.String)
+	case "hash":
+		h := make(map[string]string, len(r.Hash))
+		for f, val := range r.Hash {
+			h[enc(f)] = enc(val)
+		}
+		v = h
+	case "list", "set":
+		l := make([]string, len(r.List))
+		for i, s := range r.List {
+			l[i] = enc(s)
+		}
+		v = l
+	case "zset":
+		z := make([]ZMember, len(r.ZSet))
+		for i, m := range r.ZSet {
+			z[i] = ZMember{Member: enc(m.Member), Score: m.Score}
+		}
+		v = z
+	case "stream":
+		st := make([]StreamEntry, len(r.Stream))
+		for i, e := range r.Stream {
+			st[i] = StreamEntry{ID: e.ID, Fields: make([]string, len(e.Fields))}
+			for j, f := range e.Fields {
+				st[i].Fields[j] = enc(f)
+			}
+		}
+		v = st
+	default:
+		return nil, fmt.Errorf("key %q: unsupported type %q", r.Key, r.Type)
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	jr.Value = raw
+	return json.Marshal(jr)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *Record) UnmarshalJSON(data []byte) error {
+	var jr jsonRecord
+	if err := json.Unmarshal(data, &jr); err != nil {
+		return err
+	}
+	var decErr error
+	dec := func(s string) string { return s }
+	switch jr.Encoding {
+	case "":
+	case "base64":
+		dec = func(s string) string {
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil && decErr == nil {
+				decErr = err
+			}
+			return string(b)
+		}
+	default:
+		return fmt.Errorf("key %q: unknown encoding %q", jr.Key, jr.Encoding)
+	}
+	*r = Record{Key: dec(jr.Key), Type: jr.Type, TTL: jr.TTL}
+	var err error
+	switch jr.Type {
+	case "string":
+		err = json.Unmarshal(jr.Value, &r.String)
+		r.String = dec(r.String)
+	case "hash":
+		var h map[string]string
+		err = json.Unmarshal(jr.Value, &h)
+		r.Hash = make(map[string]string, len(h))
+		for f, v := range h {
+			r.Hash[dec(f)] = dec(v)
+		}
+	case "list", "set":
+		err = json.Unmarshal(jr.Value, &r.List)
+		for i := range r.List {
+			r.List[i] = dec(r.List[i])
+		}
+	case "zset":
+		err = json.Unmarshal(jr.Value, &r.ZSet)
+		for i := range r.ZSet {
+			r.ZSet[i].Member = dec(r.ZSet[i].Member)
+		}
+	case "stream":
+		err = json.Unmarshal(jr.Value, &r.Stream)
+		for i := range r.Stream {
+			for j := range r.Stream[i].Fields {
+				r.Stream[i].Fields[j] = dec(r.Stream[i].Fields[j])
+			}
+		}
+	default:
+		return fmt.Errorf("key %q: unsupported type %q", r.Key, jr.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("key %q: %w", r.Key, err)
+	}
+	if decErr != nil {
+		return fmt.Errorf("key %q: bad base64: %w", r.Key, decErr)
+	}
+	return nil
+}
+
+func (r *Record) validUTF8() bool {
+	ok := utf8.ValidString(r.Key) && utf8.ValidString(r.String)
+	for f, v := range r.Hash {
+		ok = ok && utf8.ValidString(f) && utf8.ValidString(v)
+	}
+	for _, s := range r.List {
+		ok = ok && utf8.ValidString(s)
+	}
+	for _, m := range r.ZSet {
+		ok = ok && utf8.ValidString(m.Member)
+	}
+	for _, e := range r.Stream {
+		for _, f := range e.Fields {
+			ok = ok && utf8.ValidString(f)
+		}
+	}
+	return ok
+}
+
+// Writer is a file format that records are streamed into.
+type Writer interface {
+	Write(r *Record) error
+	Close() error // finishes the document; does not close the underlying writer
+}
+
+// Formats lists the names accepted by NewWriter.
+var Formats = []string{"json", "resp"}
+
+// NewWriter starts a document in the named format.
+func NewWriter(format string, w io.Writer, h Header) (Writer, error) {
+	switch format {
+	case "json":
+		return NewJSONWriter(w, h)
+	case "resp":
+		return NewRESPWriter(w), nil
+	}
+	return nil, fmt.Errorf("unknown export format %q (want one of %v)", format, Formats)
+}
+
+// JSONWriter streams records into one JSON document:
+//
+//	{"version": 1, ..., "keys": [
+//	{"key": "...", "type": "...", "value": ...},
+//	...
+//	]}
+type JSONWriter struct {
+	w *bufio.Writer
+	n int
+}
+
+// NewJSONWriter writes the document header to w.
+func NewJSONWriter(w io.Writer, h Header) (*JSONWriter, error) {
+	h.Version = Version
+	head, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	jw := &JSONWriter{w: bufio.NewWriter(w)}
+	// reopen the header object to append the keys array
+	jw.w.Write(head[:len(head)-1])
+	jw.w.WriteString(`,"keys":[` + "\n")
+	return jw, nil
+}
+
+// Write appends one record.
+func (jw *JSONWriter) Write(r *Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if jw.n > 0 {
+		jw.w.WriteString(",\n")
+	}
+	jw.n++
+	_, err = jw.w.Write(data)
+	return err
+}
+
+// Close ends the document and flushes it. It does not close the
+// underlying writer.
+func (jw *JSONWriter) Close() error {
+	jw.w.WriteString("\n]}\n")
+	return jw.w.Flush()
+}
+
+// ErrBadRecord is returned by Reader.Next for a well-formed record that
+// cannot be decoded, such as one of an unknown type. Reading can go on
+// with the next record.
+var ErrBadRecord = errors.New("bad record")
+
+// Reader reads the records of a document back.
+type Reader interface {
+	Header() Header
+	Next() (*Record, error) // io.EOF after the last record
+	Close() error
+}
+
+// Open opens a document written by JSONWriter.
+func Open(path string) (Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	jr, err := NewJSONReader(bufio.NewReader(f))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	jr.c = f
+	return jr, nil
+}
+
+// JSONReader streams the records of a JSON document one at a time, so
+// that large documents are never held in memory as a whole.
+type JSONReader struct {
+	dec  *json.Decoder
+	h    Header
+	c    io.Closer
+	done bool
+}
+
+// NewJSONReader reads the document header from r, up to the start of the
+// keys array. Header fields after the array are ignored.
+func NewJSONReader(r io.Reader) (*JSONReader, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := tok.(string)
+		if !ok {
+			return nil, fmt.Errorf("document has no \"keys\" array")
+		}
+		if name == "keys" {
+			break
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		fields[name] = raw
+	}
+	if err := expectDelim(dec, '['); err != nil {
+		return nil, err
+	}
+	jr := &JSONReader{dec: dec}
+	head, _ := json.Marshal(fields)
+	if err := json.Unmarshal(head, &jr.h); err != nil {
+		return nil, fmt.Errorf("bad header: %w", err)
+	}
+	if jr.h.Version > Version {
+		return nil, fmt.Errorf("format version %d is newer than supported (%d)", jr.h.Version, Version)
+	}
+	return jr, nil
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("not a redis-walker export: expected %q, got %v", want, tok)
+	}
+	return nil
+}
+
+// Header returns the document header.
+func (jr *JSONReader) Header() Header { return jr.h }
+
+// Next returns the next record, or io.EOF after the last one.
+func (jr *JSONReader) Next() (*Record, error) {
+	if jr.done {
+		return nil, io.EOF
+	}
+	if !jr.dec.More() {
+		jr.done = true
+		if err := expectDelim(jr.dec, ']'); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	r := &Record{}
+	if err := jr.dec.Decode(r); err != nil {
+		var syn *json.SyntaxError
+		if errors.As(err, &syn) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, err
+		}
+		// the decoder has moved past the record; the caller may go on
+		return nil, fmt.Errorf("%w: %v", ErrBadRecord, err)
+	}
+	return r, nil
+}
+
+// Close closes the file opened by Open.
+func (jr *JSONReader) Close() error {
+	if jr.c == nil {
+		return nil
+	}
+	return jr.c.Close()
+}
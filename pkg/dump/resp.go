@@ -0,0 +1,110 @@
+package dump
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// commandChunk is how many elements go into one write command.
+const commandChunk = 1000
+
+// Commands returns the commands that create key with r's contents and
+// TTL, as argument lists. Large collections are written in chunks. The
+// key must not exist yet; callers delete it first. The TTL is set with
+// PEXPIRE, so it counts from when the commands run. Import and the RESP
+// writer both build their commands here.
+func Commands(key string, r *Record) ([][]string, error) {
+	var cmds [][]string
+	chunks := func(n int, fn func(lo, hi int)) {
+		for lo := 0; lo < n; lo += commandChunk {
+			fn(lo, min(lo+commandChunk, n))
+		}
+	}
+	switch r.Type {
+	case "string":
+		cmds = append(cmds, []string{"SET", key, r.String})
+	case "hash":
+		pairs := make([]string, 0, 2*len(r.Hash))
+		for f, v := range r.Hash {
+			pairs = append(pairs, f, v)
+		}
+		chunks(len(pairs)/2, func(lo, hi int) {
+			cmds = append(cmds, append([]string{"HSET", key}, pairs[2*lo:2*hi]...))
+		})
+	case "list":
+		chunks(len(r.List), func(lo, hi int) {
+			cmds = append(cmds, append([]string{"RPUSH", key}, r.List[lo:hi]...))
+		})
+	case "set":
+		chunks(len(r.List), func(lo, hi int) {
+			cmds = append(cmds, append([]string{"SADD", key}, r.List[lo:hi]...))
+		})
+	case "zset":
+		chunks(len(r.ZSet), func(lo, hi int) {
+			args := []string{"ZADD", key}
+			for _, z := range r.ZSet[lo:hi] {
+				args = append(args, formatScore(z.Score), z.Member)
+			}
+			cmds = append(cmds, args)
+		})
+	case "stream":
+		if len(r.Stream) == 0 {
+			// an empty stream can only be created by trimming an entry away
+			cmds = append(cmds, []string{"XADD", key, "MAXLEN", "0", "*", "_", ""})
+		}
+		for _, e := range r.Stream {
+			cmds = append(cmds, append([]string{"XADD", key, e.ID}, e.Fields...))
+		}
+	default:
+		return nil, fmt.Errorf("key %q: unsupported type %q", r.Key, r.Type)
+	}
+	if r.TTL > 0 {
+		cmds = append(cmds, []string{"PEXPIRE", key, strconv.FormatInt(r.TTL, 10)})
+	}
+	return cmds, nil
+}
+
+// RESPWriter writes records as the Redis commands that recreate them,
+// RESP-encoded, for `redis-cli --pipe`. Every key is deleted first, then
+// written with typed commands, then given its TTL, which counts from the
+// moment the file is loaded. Arguments are length-prefixed, so binary
+// keys and values need no escaping. There is no header.
+type RESPWriter struct {
+	w *bufio.Writer
+}
+
+// NewRESPWriter returns a writer of commands to w.
+func NewRESPWriter(w io.Writer) *RESPWriter {
+	return &RESPWriter{w: bufio.NewWriter(w)}
+}
+
+// Write appends the commands for one record.
+func (rw *RESPWriter) Write(r *Record) error {
+	cmds, err := Commands(r.Key, r)
+	if err != nil {
+		return err
+	}
+	rw.command([]string{"DEL", r.Key})
+	for _, c := range cmds {
+		rw.command(c)
+	}
+	return nil
+}
+
+// command writes one command as a RESP array of bulk strings. Errors are
+// sticky in the bufio.Writer and reported by Close.
+func (rw *RESPWriter) command(args []string) {
+	rw.w.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args {
+		rw.w.WriteString("$" + strconv.Itoa(len(a)) + "\r\n")
+		rw.w.WriteString(a)
+		rw.w.WriteString("\r\n")
+	}
+}
+
+// Close flushes the commands. It does not close the underlying writer.
+func (rw *RESPWriter) Close() error {
+	return rw.w.Flush()
+}
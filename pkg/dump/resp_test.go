@@ -0,0 +1,109 @@
+package dump
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// readRESP parses a stream of RESP arrays of bulk strings, the only form
+// RESPWriter emits.
+func readRESP(r io.Reader) ([][]string, error) {
+	br := bufio.NewReader(r)
+	line := func(prefix byte) (int, error) {
+		s, err := br.ReadString('\n')
+		if err != nil {
+			return 0, err
+		}
+		if len(s) < 3 || s[0] != prefix || s[len(s)-2] != '\r' {
+			return 0, fmt.Errorf("bad line %q", s)
+		}
+		return strconv.Atoi(s[1 : len(s)-2])
+	}
+	var cmds [][]string
+	for {
+		n, err := line('*')
+		if err == io.EOF {
+			return cmds, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		args := make([]string, n)
+		for i := range args {
+			size, err := line('$')
+			if err != nil {
+				return nil, err
+			}
+			b := make([]byte, size+2)
+			if _, err := io.ReadFull(br, b); err != nil {
+				return nil, err
+			}
+			if !bytes.HasSuffix(b, []byte("\r\n")) {
+				return nil, fmt.Errorf("bulk string of %d bytes not terminated", size)
+			}
+			args[i] = string(b[:size])
+		}
+		cmds = append(cmds, args)
+	}
+}
+
+func TestRESPWriterBinary(t *testing.T) {
+	key := "bin/\x00\xff\r\n$5\r\n*1"
+	val := "\r\n\x00\xfe\xff*3\r\n$-1\r\n"
+	records := []*Record{
+		{Key: key, Type: "string", String: val, TTL: 1500},
+		{Key: key + "h", Type: "hash", Hash: map[string]string{"\r\n": val}},
+		{Key: key + "l", Type: "list", List: []string{val, "", "\x00"}},
+		{Key: key + "z", Type: "zset", ZSet: []ZMember{{Member: val, Score: math.Inf(-1)}, {Member: "x", Score: 0.5}}},
+		{Key: key + "x", Type: "stream", Stream: []StreamEntry{{ID: "1-1", Fields: []string{val, val}}}},
+	}
+	want := [][]string{
+		{"DEL", key}, {"SET", key, val}, {"PEXPIRE", key, "1500"},
+		{"DEL", key + "h"}, {"HSET", key + "h", "\r\n", val},
+		{"DEL", key + "l"}, {"RPUSH", key + "l", val, "", "\x00"},
+		{"DEL", key + "z"}, {"ZADD", key + "z", "-inf", val, "0.5", "x"},
+		{"DEL", key + "x"}, {"XADD", key + "x", "1-1", val, val},
+	}
+
+	var buf bytes.Buffer
+	rw := NewRESPWriter(&buf)
+	for _, r := range records {
+		if err := rw.Write(r); err != nil {
+			t.Fatalf("write %q: %v", r.Key, err)
+		}
+	}
+	if err := rw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readRESP(&buf)
+	if err != nil {
+		t.Fatalf("read back: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("commands:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestCommandsChunks(t *testing.T) {
+	list := make([]string, 2*commandChunk+1)
+	cmds, err := Commands("l", &Record{Key: "l", Type: "list", List: list})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sizes []int
+	for _, c := range cmds {
+		sizes = append(sizes, len(c)-2)
+	}
+	if want := []int{commandChunk, commandChunk, 1}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("chunk sizes %v, want %v", sizes, want)
+	}
+	if _, err := Commands("m", &Record{Key: "m", Type: "module"}); err == nil {
+		t.Error("unsupported type: want an error")
+	}
+}
@@ -0,0 +1,396 @@
+package keymap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Action names a user-triggerable operation. The string value is what
+// appears in the "keymap" section of the config file.
+type Action string
+
+const (
+	None Action = ""
+
+	Quit         Action = "quit"
+	Up           Action = "up"
+	Create       Action = "create"
+	Edit         Action = "edit"
+	Delete       Action = "delete"
+	Search       Action = "search"
+	Jump         Action = "jump"
+	Help         Action = "help"
+	Logs         Action = "logs"
+	Undo         Action = "undo"
+	Trash        Action = "trash"
+	Audit        Action = "audit"
+	Pin          Action = "pin"
+	Watch        Action = "watch"
+	Graph        Action = "graph"
+	Monitor      Action = "monitor"
+	SlowLog      Action = "slowlog"
+	Dashboard    Action = "dashboard"
+	Clients      Action = "clients"
+	ServerConfig Action = "config"
+	PubSub       Action = "pubsub"
+	Console      Action = "console"
+	Scripts      Action = "scripts"
+	Export       Action = "export"
+	Import       Action = "import"
+	Snapshot     Action = "snapshot"
+	Restore      Action = "restore"
+
+	Save   Action = "save"
+	Cancel Action = "cancel"
+)
+
+// Scope tells where a binding is active. Bindings only conflict with
+// others in the same scope or in the global scope.
+type Scope int
+
+const (
+	Global Scope = iota
+	List
+	Editor
+)
+
+// Info describes an action for help and footer rendering.
+type Info struct {
+	Action      Action
+	Scope       Scope
+	Section     string // help section heading
+	Description string // help text
+	Footer      string // short footer label, "" to keep it out of the footer
+	Defaults    []string
+}
+
+// actions is the registry of every bindable action, in help/footer order.
+var actions = []Info{
+	{Up, List, "Navigation", "Up ([..])", "Up", []string{"Backspace"}},
+	{Create, List, "Actions", "Create key/dir", "New", []string{"Ctrl+N"}},
+	{Edit, List, "Actions", "Edit (value multiline / rename dir)", "Edit", []string{"Ctrl+E"}},
+	{Delete, List, "Actions", "Delete (recursive for dirs)", "Delete", []string{"Delete"}},
+	{Jump, List, "Actions", "Jump to key/dir (dir ends with '/')", "Jump", []string{"Ctrl+J", "Ctrl+G"}},
+	{Pin, List, "Actions", "Pin/unpin key in the watch panel", "", []string{"Ctrl+P"}},
+	{Graph, List, "Actions", "Graph numeric key in Details (toggle)", "", []string{"Ctrl+T"}},
+	{Export, List, "Actions", "Export folder to a file", "", []string{"Ctrl+X"}},
+	{Import, List, "Actions", "Import a file into a folder", "", []string{"Ctrl+O"}},
+	{Snapshot, List, "Actions", "Snapshot folder (DUMP payloads) to a file", "", []string{"Ctrl+B"}},
+	{Restore, List, "Actions", "Restore a snapshot into a folder", "", []string{"Ctrl+R"}},
+	{Undo, List, "Actions", "Undo last delete/overwrite", "", []string{"Ctrl+Z"}},
+	{Search, List, "Search", "Search by name (in current level)", "Search", []string{"/", "Ctrl+S"}},
+	{Save, Editor, "Editor", "Save", "", []string{"Ctrl+S"}},
+	{Cancel, Editor, "Editor", "Cancel", "", []string{"Esc"}},
+	{Logs, List, "Panels", "Log viewer", "", []string{"F2"}},
+	{Trash, List, "Panels", "Trash (restore deleted keys)", "", []string{"F3"}},
+	{Audit, List, "Panels", "Audit journal", "", []string{"F4"}},
+	{Watch, List, "Panels", "Watch panel (pinned keys)", "", []string{"F5"}},
+	{Monitor, List, "Panels", "MONITOR (commands on keys in this folder)", "", []string{"F6"}},
+	{SlowLog, List, "Panels", "Slow log", "", []string{"F7"}},
+	{Dashboard, List, "Panels", "Server dashboard (INFO)", "", []string{"F8"}},
+	{Clients, List, "Panels", "Client list (kill, pause writes)", "", []string{"F9"}},
+	{ServerConfig, List, "Panels", "Server configuration (CONFIG GET/SET)", "", []string{"F10"}},
+	{PubSub, List, "Panels", "Pub/Sub console (subscribe, publish)", "", []string{"F11"}},
+	{Console, List, "Panels", "Command console (toggle)", "", []string{"F12"}},
+	{Scripts, List, "Panels", "Lua scripts and functions", "", []string{"Ctrl+L"}},
+	{Help, List, "Misc", "This help", "Hotkeys", []string{"F1", "?"}},
+	{Quit, Global, "Misc", "Quit", "Quit", []string{"Ctrl+Q"}},
+}
+
+// Actions returns the registry of bindable actions in display order.
+func Actions() []Info {
+	out := make([]Info, len(actions))
+	copy(out, actions)
+	return out
+}
+
+func lookup(a Action) (Info, bool) {
+	for _, in := range actions {
+		if in.Action == a {
+			return in, true
+		}
+	}
+	return Info{}, false
+}
+
+// Key is a single key chord.
+type Key struct {
+	Code tcell.Key
+	Rune rune
+	Mod  tcell.ModMask
+}
+
+// Keymap maps actions to the keys that trigger them.
+type Keymap struct {
+	bindings map[Action][]Key
+}
+
+// Default returns the built-in keymap.
+func Default() *Keymap {
+	km, err := New(nil)
+	if err != nil {
+		// defaults are static; a failure here is a programming error
+		panic(err)
+	}
+	return km
+}
+
+// New builds a keymap from the defaults with the given overrides applied.
+// Each override replaces all default keys of its action; an empty list
+// unbinds the action.
+func New(overrides map[string][]string) (*Keymap, error) {
+	km := &Keymap{bindings: make(map[Action][]Key, len(actions))}
+	for _, in := range actions {
+		keys, err := parseAll(in.Defaults)
+		if err != nil {
+			return nil, fmt.Errorf("action %q: %w", in.Action, err)
+		}
+		km.bindings[in.Action] = keys
+	}
+
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		a := Action(strings.TrimSpace(strings.ToLower(name)))
+		if _, ok := lookup(a); !ok {
+			return nil, fmt.Errorf("unknown action %q", name)
+		}
+		keys, err := parseAll(overrides[name])
+		if err != nil {
+			return nil, fmt.Errorf("action %q: %w", name, err)
+		}
+		km.bindings[a] = keys
+	}
+
+	if err := km.checkConflicts(); err != nil {
+		return nil, err
+	}
+	return km, nil
+}
+
+func (km *Keymap) checkConflicts() error {
+	seen := map[Scope]map[Key]Action{}
+	for _, in := range actions {
+		for _, k := range km.bindings[in.Action] {
+			k = k.normalized()
+			for scope, owners := range seen {
+				if scope != in.Scope && scope != Global && in.Scope != Global {
+					continue
+				}
+				if other, ok := owners[k]; ok {
+					return fmt.Errorf("key %s is bound to both %q and %q", k, other, in.Action)
+				}
+			}
+			if seen[in.Scope] == nil {
+				seen[in.Scope] = map[Key]Action{}
+			}
+			seen[in.Scope][k] = in.Action
+		}
+	}
+	return nil
+}
+
+// Match returns the action bound to the event within the given scope, or
+// None. Global bindings match in every scope.
+func (km *Keymap) Match(scope Scope, ev *tcell.EventKey) Action {
+	k := fromEvent(ev)
+	for _, in := range actions {
+		if in.Scope != scope && in.Scope != Global {
+			continue
+		}
+		for _, b := range km.bindings[in.Action] {
+			if b.normalized() == k {
+				return in.Action
+			}
+		}
+	}
+	return None
+}
+
+// Keys returns the keys bound to an action.
+func (km *Keymap) Keys(a Action) []Key {
+	return km.bindings[a]
+}
+
+// Label returns a human-readable list of the keys bound to an action,
+// e.g. "/, Ctrl+S". Unbound actions yield "".
+func (km *Keymap) Label(a Action) string {
+	keys := km.bindings[a]
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+func fromEvent(ev *tcell.EventKey) Key {
+	k := Key{Code: ev.Key(), Mod: ev.Modifiers()}
+	if k.Code == tcell.KeyRune {
+		k.Rune = ev.Rune()
+	}
+	return k.normalized()
+}
+
+// normalized strips modifier bits that terminals report inconsistently so
+// that parsed bindings and incoming events compare equal.
+func (k Key) normalized() Key {
+	switch {
+	case k.Code == tcell.KeyBackspace2:
+		k.Code = tcell.KeyBackspace
+	case k.Code == tcell.KeyRune:
+		k.Mod &^= tcell.ModShift
+	case k.Code >= tcell.KeyCtrlA && k.Code <= tcell.KeyCtrlZ:
+		k.Mod &^= tcell.ModCtrl
+	}
+	if k.Code != tcell.KeyRune {
+		k.Rune = 0
+	}
+	k.Mod &^= tcell.ModMeta
+	return k
+}
+
+func (k Key) String() string {
+	var mods []string
+	if k.Mod&tcell.ModCtrl != 0 {
+		mods = append(mods, "Ctrl")
+	}
+	if k.Mod&tcell.ModAlt != 0 {
+		mods = append(mods, "Alt")
+	}
+	if k.Mod&tcell.ModShift != 0 {
+		mods = append(mods, "Shift")
+	}
+
+	var base string
+	switch {
+	case k.Code == tcell.KeyRune && k.Rune == ' ':
+		base = "Space"
+	case k.Code == tcell.KeyRune:
+		base = string(k.Rune)
+	case k.Code == tcell.KeyDelete:
+		base = "Del"
+	case k.Code >= tcell.KeyCtrlA && k.Code <= tcell.KeyCtrlZ:
+		mods = append([]string{"Ctrl"}, without(mods, "Ctrl")...)
+		base = string(rune('A' + (k.Code - tcell.KeyCtrlA)))
+	default:
+		name, ok := tcell.KeyNames[k.Code]
+		if !ok {
+			name = fmt.Sprintf("Key(%d)", k.Code)
+		}
+		if rest, ok := strings.CutPrefix(name, "Ctrl-"); ok {
+			mods = append([]string{"Ctrl"}, without(mods, "Ctrl")...)
+			name = rest
+		}
+		base = name
+	}
+	return strings.Join(append(mods, base), "+")
+}
+
+func without(list []string, s string) []string {
+	out := list[:0:0]
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+var keyAliases = map[string]tcell.Key{
+	"del":       tcell.KeyDelete,
+	"return":    tcell.KeyEnter,
+	"escape":    tcell.KeyEsc,
+	"ins":       tcell.KeyInsert,
+	"pagedown":  tcell.KeyPgDn,
+	"pageup":    tcell.KeyPgUp,
+	"bs":        tcell.KeyBackspace,
+	"shifttab":  tcell.KeyBacktab,
+	"backspace": tcell.KeyBackspace,
+}
+
+var keyByName = func() map[string]tcell.Key {
+	m := make(map[string]tcell.Key, len(tcell.KeyNames)+len(keyAliases))
+	for k, name := range tcell.KeyNames {
+		if strings.HasPrefix(name, "Ctrl-") || k == tcell.KeyBackspace2 {
+			continue
+		}
+		m[strings.ToLower(name)] = k
+	}
+	for name, k := range keyAliases {
+		m[name] = k
+	}
+	return m
+}()
+
+func parseAll(specs []string) ([]Key, error) {
+	keys := make([]Key, 0, len(specs))
+	for _, s := range specs {
+		k, err := Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// Parse converts a key description such as "Ctrl+N", "Alt+x", "F2", "Del"
+// or "?" into a Key. Modifiers may be joined with '+' or '-'.
+func Parse(spec string) (Key, error) {
+	s := strings.TrimSpace(spec)
+	if s == "" {
+		return Key{}, fmt.Errorf("empty key")
+	}
+
+	var mod tcell.ModMask
+	base := s
+	for {
+		i := strings.IndexAny(base, "+-")
+		if i <= 0 || i == len(base)-1 {
+			break
+		}
+		switch strings.ToLower(base[:i]) {
+		case "ctrl", "c", "control":
+			mod |= tcell.ModCtrl
+		case "alt", "a", "m", "meta", "opt":
+			mod |= tcell.ModAlt
+		case "shift", "s":
+			mod |= tcell.ModShift
+		default:
+			return Key{}, fmt.Errorf("unknown modifier %q in key %q", base[:i], spec)
+		}
+		base = base[i+1:]
+	}
+
+	if utf8.RuneCountInString(base) == 1 {
+		r, _ := utf8.DecodeRuneInString(base)
+		if mod&tcell.ModCtrl != 0 {
+			lr := r | 0x20
+			if lr < 'a' || lr > 'z' {
+				return Key{}, fmt.Errorf("unsupported key %q: Ctrl only combines with letters", spec)
+			}
+			return Key{Code: tcell.KeyCtrlA + tcell.Key(lr-'a'), Mod: mod &^ tcell.ModCtrl}.normalized(), nil
+		}
+		return Key{Code: tcell.KeyRune, Rune: r, Mod: mod}.normalized(), nil
+	}
+
+	name := strings.ToLower(base)
+	if name == "space" {
+		if mod&tcell.ModCtrl != 0 {
+			return Key{Code: tcell.KeyCtrlSpace}, nil
+		}
+		return Key{Code: tcell.KeyRune, Rune: ' ', Mod: mod}.normalized(), nil
+	}
+	code, ok := keyByName[name]
+	if !ok {
+		return Key{}, fmt.Errorf("unknown key %q", spec)
+	}
+	return Key{Code: code, Mod: mod}.normalized(), nil
+}
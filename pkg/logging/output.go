@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"io"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Output is a logrus hook that writes entries at or above its level to a
+// writer. It lets stderr and the log file keep their own level next to
+// the Ring; the logger's own output should then be io.Discard, and its
+// level the most verbose of its hooks'.
+type Output struct {
+	mu    sync.Mutex
+	w     io.Writer
+	level log.Level
+	f     log.Formatter
+}
+
+// NewOutput writes entries up to level, formatted by f, to w.
+func NewOutput(w io.Writer, level log.Level, f log.Formatter) *Output {
+	return &Output{w: w, level: level, f: f}
+}
+
+// SetWriter replaces the destination.
+func (o *Output) SetWriter(w io.Writer) {
+	o.mu.Lock()
+	o.w = w
+	o.mu.Unlock()
+}
+
+// SetLevel sets the least severe level written.
+func (o *Output) SetLevel(level log.Level) {
+	o.mu.Lock()
+	o.level = level
+	o.mu.Unlock()
+}
+
+// Levels implements log.Hook.
+func (o *Output) Levels() []log.Level { return log.AllLevels }
+
+// Fire implements log.Hook.
+func (o *Output) Fire(e *log.Entry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if e.Level > o.level {
+		return nil
+	}
+	b, err := o.f.Format(e)
+	if err != nil {
+		return err
+	}
+	_, err = o.w.Write(b)
+	return err
+}
@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Entry is a captured log line.
+type Entry struct {
+	Time    time.Time
+	Level   log.Level
+	Message string
+	Fields  string // "k=v k=v", sorted by key
+}
+
+// Ring is a logrus hook that keeps the most recent entries in memory for
+// the in-app log viewer.
+type Ring struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+	notify  chan struct{}
+}
+
+// NewRing creates a ring holding up to size entries.
+func NewRing(size int) *Ring {
+	if size <= 0 {
+		size = 1
+	}
+	return &Ring{
+		entries: make([]Entry, size),
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// Levels implements log.Hook; the ring captures every level the logger
+// lets through and filtering happens at display time.
+func (r *Ring) Levels() []log.Level { return log.AllLevels }
+
+// Fire implements log.Hook.
+func (r *Ring) Fire(e *log.Entry) error {
+	keys := make([]string, 0, len(e.Data))
+	for k := range e.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, e.Data[k]))
+	}
+
+	r.mu.Lock()
+	r.entries[r.next] = Entry{
+		Time:    e.Time,
+		Level:   e.Level,
+		Message: e.Message,
+		Fields:  strings.Join(parts, " "),
+	}
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+	r.mu.Unlock()
+
+	// Never block the logging goroutine (often the UI goroutine).
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Entries returns captured entries at or above minLevel, oldest first.
+// In logrus lower levels are more severe (Panic=0 .. Trace=6).
+func (r *Ring) Entries(minLevel log.Level) []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ordered []Entry
+	if r.full {
+		ordered = append(ordered, r.entries[r.next:]...)
+	}
+	ordered = append(ordered, r.entries[:r.next]...)
+
+	out := ordered[:0]
+	for _, e := range ordered {
+		if e.Level <= minLevel {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Clear drops all captured entries.
+func (r *Ring) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.entries {
+		r.entries[i] = Entry{}
+	}
+	r.next = 0
+	r.full = false
+}
+
+// Updates signals (coalesced) that new entries arrived.
+func (r *Ring) Updates() <-chan struct{} { return r.notify }
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/audit"
+	"github.com/nexusriot/redis-walker/pkg/trash"
+)
+
+// Audit returns the audit journal, or nil if auditing is disabled.
+func (m *Model) Audit() *audit.Journal { return m.audit }
+
+// record appends a mutation to the audit journal. err is the outcome of
+// the mutation; failed attempts are recorded too. Every mutating call
+// must go through here.
+func (m *Model) record(e audit.Entry, err error) {
+	if m.audit == nil {
+		return
+	}
+	e.Server = m.addr
+	e.DB = m.db
+	if e.Count == 0 {
+		e.Count = len(e.Keys)
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if werr := m.audit.Record(e); werr != nil {
+		log.WithError(werr).WithFields(log.Fields{
+			"op":   e.Op,
+			"keys": len(e.Keys),
+		}).Error("audit journal write failed")
+	}
+}
+
+// describe returns the journal description of a key's current value, or
+// nil if the key does not exist or auditing is disabled.
+func (m *Model) describe(ctx context.Context, key string) *audit.Value {
+	if m.audit == nil {
+		return nil
+	}
+	pipe := m.rdb.Pipeline()
+	typ := pipe.Type(ctx, key)
+	mem := pipe.MemoryUsage(ctx, key)
+	_, _ = pipe.Exec(ctx)
+	switch t := typ.Val(); t {
+	case "", "none":
+		return nil
+	case "string":
+		v, err := m.rdb.Get(ctx, key).Result()
+		if err == nil {
+			return audit.Digest(v)
+		}
+		if err == redis.Nil {
+			return nil
+		}
+		fallthrough
+	default:
+		return &audit.Value{Type: t, Size: mem.Val()}
+	}
+}
+
+// describeKeys returns the journal description of keys before a recursive
+// operation: from the DUMP payloads in saved if the trash captured them,
+// otherwise by type and MEMORY USAGE. It returns nil if auditing is
+// disabled.
+func (m *Model) describeKeys(ctx context.Context, keys []string, saved *trash.Op) []audit.Change {
+	if m.audit == nil {
+		return nil
+	}
+	if saved != nil {
+		out := make([]audit.Change, len(saved.Items))
+		for i, it := range saved.Items {
+			out[i] = audit.Change{Key: it.Key, Old: audit.DumpDigest(it.Type, it.Dump)}
+		}
+		return out
+	}
+	var out []audit.Change
+	for start := 0; start < len(keys); start += previewBatchSize {
+		batch := keys[start:min(start+previewBatchSize, len(keys))]
+		pipe := m.rdb.Pipeline()
+		types := make([]*redis.StatusCmd, len(batch))
+		mems := make([]*redis.IntCmd, len(batch))
+		for i, k := range batch {
+			types[i] = pipe.Type(ctx, k)
+			mems[i] = pipe.MemoryUsage(ctx, k)
+		}
+		_, _ = pipe.Exec(ctx) // a key that is gone has no old value
+		for i, k := range batch {
+			c := audit.Change{Key: k}
+			if t := types[i].Val(); t != "" && t != "none" {
+				c.Old = &audit.Value{Type: t, Size: mems[i].Val()}
+			}
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nexusriot/redis-walker/pkg/audit"
+)
+
+// ClientName is set with CLIENT SETNAME on every connection we open, so
+// redis-walker is identifiable in CLIENT LIST and other tools.
+const ClientName = "redis-walker"
+
+// ClientInfo is one connection from CLIENT LIST.
+type ClientInfo struct {
+	ID        int64
+	Addr      string
+	Name      string
+	User      string
+	Age       time.Duration
+	Idle      time.Duration
+	DB        int
+	Flags     string
+	Cmd       string // last command
+	OutputMem int64  // output buffer memory (omem)
+	TotalMem  int64  // total memory used by the client (tot-mem)
+}
+
+// Clients returns the server's connections from CLIENT LIST.
+func (m *Model) Clients() ([]ClientInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	raw, err := m.rdb.ClientList(ctx).Result()
+	if err != nil {
+		return nil, err
+	}
+	var out []ClientInfo
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		f := map[string]string{}
+		for _, kv := range strings.Fields(line) {
+			if k, v, ok := strings.Cut(kv, "="); ok {
+				f[k] = v
+			}
+		}
+		num := func(k string) int64 {
+			n, _ := strconv.ParseInt(f[k], 10, 64)
+			return n
+		}
+		out = append(out, ClientInfo{
+			ID:        num("id"),
+			Addr:      f["addr"],
+			Name:      f["name"],
+			User:      f["user"],
+			Age:       time.Duration(num("age")) * time.Second,
+			Idle:      time.Duration(num("idle")) * time.Second,
+			DB:        int(num("db")),
+			Flags:     f["flags"],
+			Cmd:       f["cmd"],
+			OutputMem: num("omem"),
+			TotalMem:  num("tot-mem"),
+		})
+	}
+	return out, nil
+}
+
+// KillClient closes the connection with the given CLIENT LIST id.
+func (m *Model) KillClient(c ClientInfo) error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := m.rdb.ClientKillByFilter(ctx, "ID", strconv.FormatInt(c.ID, 10)).Err()
+	m.record(audit.Entry{Op: "client-kill", Target: fmt.Sprintf("id=%d addr=%s name=%s", c.ID, c.Addr, c.Name)}, err)
+	return err
+}
+
+// MaxClientPause is the longest pause PauseClients accepts. The server
+// lifts a pause by itself when it runs out, so writes never stay blocked
+// longer, even if redis-walker goes away.
+const MaxClientPause = time.Minute
+
+// PauseClients holds the write commands of every client on the server
+// for d (CLIENT PAUSE ... WRITE); reads go on.
+func (m *Model) PauseClients(d time.Duration) error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
+	if d <= 0 || d > MaxClientPause {
+		return fmt.Errorf("pause must be between 1ms and %s", MaxClientPause)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := m.rdb.Do(ctx, "client", "pause", d.Milliseconds(), "write").Err()
+	m.record(audit.Entry{Op: "client-pause", Target: fmt.Sprintf("%s WRITE", d)}, err)
+	return err
+}
+
+// UnpauseClients ends a CLIENT PAUSE early.
+func (m *Model) UnpauseClients() error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := m.rdb.ClientUnpause(ctx).Err()
+	m.record(audit.Entry{Op: "client-unpause"}, err)
+	return err
+}
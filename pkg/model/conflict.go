@@ -0,0 +1,106 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/audit"
+)
+
+// ErrConflict is returned by SetIfUnchanged when the key no longer holds
+// the value it was read with.
+var ErrConflict = errors.New("key was modified by someone else")
+
+// ConflictError carries the value found on the server when a conditional
+// write was refused. It matches ErrConflict with errors.Is.
+type ConflictError struct {
+	Key     string
+	Current string // current value; empty when the key was deleted
+	Exists  bool
+}
+
+func (e *ConflictError) Error() string {
+	if !e.Exists {
+		return fmt.Sprintf("%s was deleted by someone else", e.Key)
+	}
+	return fmt.Sprintf("%s was modified by someone else", e.Key)
+}
+
+func (e *ConflictError) Is(target error) bool { return target == ErrConflict }
+
+// SetIfUnchanged writes value to key only if the key still holds base.
+// The check and the write are atomic (WATCH/MULTI). On conflict nothing
+// is written and a *ConflictError is returned.
+func (m *Model) SetIfUnchanged(key, base, value string) error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	k := normPath(key)
+	if k == "/" {
+		return fmt.Errorf("cannot set value on root")
+	}
+	start := time.Now()
+	saved, err := m.saveToTrash(ctx, "set", "", []string{k})
+	if err != nil {
+		return err
+	}
+	old := m.describe(ctx, k)
+
+	var conflict *ConflictError
+	err = m.rdb.Watch(ctx, func(tx *redis.Tx) error {
+		cur, err := tx.Get(ctx, k).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if err == redis.Nil || cur != base {
+			conflict = &ConflictError{Key: k, Current: cur, Exists: err == nil}
+			return conflict
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, k, value, 0)
+			return nil
+		})
+		return err
+	}, k)
+	if errors.Is(err, redis.TxFailedErr) {
+		// Changed between our GET and EXEC.
+		cur, gerr := m.rdb.Get(ctx, k).Result()
+		if gerr != nil && gerr != redis.Nil {
+			err = gerr
+		} else {
+			conflict = &ConflictError{Key: k, Current: cur, Exists: gerr == nil}
+			err = conflict
+		}
+	}
+	if err != nil {
+		m.dropFromTrash(saved)
+		if conflict != nil {
+			log.WithFields(log.Fields{
+				"op":  "set",
+				"key": k,
+			}).Info("refused save: key changed since it was opened")
+			return conflict
+		}
+		m.record(audit.Entry{Op: "set", Keys: []string{k}, Old: old, New: audit.Digest(value)}, err)
+		log.WithError(err).WithFields(log.Fields{
+			"op":  "set",
+			"key": k,
+		}).Error("redis conditional set failed")
+		return err
+	}
+	m.record(audit.Entry{Op: "set", Keys: []string{k}, Old: old, New: audit.Digest(value)}, nil)
+	log.WithFields(log.Fields{
+		"op":       "set",
+		"key":      k,
+		"size":     len(value),
+		"duration": time.Since(start),
+	}).Debug("redis conditional set ok")
+	return nil
+}
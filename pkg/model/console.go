@@ -0,0 +1,249 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/audit"
+)
+
+// consoleRefused are commands the console does not send because they
+// would take over or reconfigure a pooled connection. The value says
+// what to use instead.
+var consoleRefused = map[string]string{
+	"subscribe": "use the Pub/Sub console", "psubscribe": "use the Pub/Sub console",
+	"ssubscribe": "use the Pub/Sub console", "unsubscribe": "use the Pub/Sub console",
+	"punsubscribe": "use the Pub/Sub console", "sunsubscribe": "use the Pub/Sub console",
+	"monitor": "use the MONITOR pane",
+	"select":  "the connection pool is bound to one database; restart with -db",
+	"swapdb":  "it would change the database under the pooled connections",
+	"sync":    "replication commands are not supported", "psync": "replication commands are not supported",
+	"quit": "close the console instead", "reset": "it would reset a pooled connection",
+	"hello": "it would change the protocol of a pooled connection", "auth": "restart with -username/-password",
+	"client|reply": "replies are needed to keep the connection in sync",
+	"multi":        "each command may run on a different pooled connection; use EVAL or a function",
+	"exec":         "there is no transaction: MULTI is refused", "discard": "there is no transaction: MULTI is refused",
+	"watch": "it would leave a WATCH on a pooled connection", "unwatch": "WATCH is refused",
+	"client|tracking": "it would turn on tracking for a pooled connection",
+	"client|setname":  "pooled connections keep the redis-walker name",
+}
+
+// ConsoleError reports a command the console refuses to send.
+type ConsoleError struct {
+	Command string
+	Reason  string
+}
+
+func (e *ConsoleError) Error() string {
+	return fmt.Sprintf("%s is not available in the console: %s", e.Command, e.Reason)
+}
+
+// commandTable is COMMAND and COMMAND DOCS, loaded once per Model.
+type commandTable struct {
+	once   sync.Once
+	writes map[string]bool // nil if COMMAND failed
+	docs   map[string]*CommandDoc
+	err    error
+}
+
+// CommandDoc is the part of COMMAND DOCS the console uses.
+type CommandDoc struct {
+	Name        string // lower case; "config|get" for subcommands
+	Summary     string
+	Args        []ArgDoc
+	Subcommands []string // lower-case subcommand names
+}
+
+// ArgDoc describes one argument of a command.
+type ArgDoc struct {
+	Name     string
+	Type     string // key, string, integer, pure-token, oneof, block, ...
+	Token    string // literal that precedes the argument, if any
+	Optional bool
+	Multiple bool
+	Args     []ArgDoc // members of oneof and block arguments
+}
+
+func (m *Model) commands() *commandTable {
+	t := &m.cmds
+	t.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if w, err := writeCommands(ctx, m.rdb); err == nil {
+			t.writes = w
+		} else {
+			log.WithError(err).Warn("COMMAND failed; console writes are audited by default")
+		}
+		raw, err := m.rdb.Do(ctx, "command", "docs").Result()
+		if err != nil {
+			t.err = fmt.Errorf("COMMAND DOCS: %w", err)
+			return
+		}
+		t.docs = map[string]*CommandDoc{}
+		for _, kv := range pairs(raw) {
+			parseCommandDoc(t.docs, strings.ToLower(fmt.Sprint(kv[0])), kv[1])
+		}
+	})
+	return t
+}
+
+// CommandDocs returns COMMAND DOCS keyed by lower-case command name, with
+// subcommands under "command|subcommand". Requires Redis 7.
+func (m *Model) CommandDocs() (map[string]*CommandDoc, error) {
+	t := m.commands()
+	return t.docs, t.err
+}
+
+func parseCommandDoc(docs map[string]*CommandDoc, name string, raw interface{}) {
+	d := &CommandDoc{Name: name}
+	for _, kv := range pairs(raw) {
+		switch fmt.Sprint(kv[0]) {
+		case "summary":
+			d.Summary = fmt.Sprint(kv[1])
+		case "arguments":
+			d.Args = parseArgDocs(kv[1])
+		case "subcommands":
+			for _, sub := range pairs(kv[1]) {
+				full := strings.ToLower(fmt.Sprint(sub[0]))
+				parseCommandDoc(docs, full, sub[1])
+				_, short, _ := strings.Cut(full, "|")
+				d.Subcommands = append(d.Subcommands, short)
+			}
+		}
+	}
+	docs[name] = d
+}
+
+func parseArgDocs(raw interface{}) []ArgDoc {
+	list, _ := raw.([]interface{})
+	out := make([]ArgDoc, 0, len(list))
+	for _, item := range list {
+		var a ArgDoc
+		for _, kv := range pairs(item) {
+			switch fmt.Sprint(kv[0]) {
+			case "name":
+				a.Name = fmt.Sprint(kv[1])
+			case "type":
+				a.Type = fmt.Sprint(kv[1])
+			case "token":
+				a.Token = fmt.Sprint(kv[1])
+			case "flags":
+				flags, _ := kv[1].([]interface{})
+				for _, f := range flags {
+					switch fmt.Sprint(f) {
+					case "optional":
+						a.Optional = true
+					case "multiple":
+						a.Multiple = true
+					}
+				}
+			case "arguments":
+				a.Args = parseArgDocs(kv[1])
+			}
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// pairs returns the entries of a RESP3 map, or of a RESP2 array of
+// alternating keys and values.
+func pairs(v interface{}) [][2]interface{} {
+	var out [][2]interface{}
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		for k, val := range v {
+			out = append(out, [2]interface{}{k, val})
+		}
+	case map[string]interface{}:
+		for k, val := range v {
+			out = append(out, [2]interface{}{k, val})
+		}
+	case []interface{}:
+		for i := 0; i+1 < len(v); i += 2 {
+			out = append(out, [2]interface{}{v[i], v[i+1]})
+		}
+	}
+	return out
+}
+
+// Exec sends one command over the model's connection and returns the
+// raw reply: nil, string, int64, float64, bool, []interface{} or
+// map[interface{}]interface{} (RESP3), nested as the server sent it.
+// Read-only mode refuses writes exactly as for the rest of the model;
+// writes are recorded in the audit journal.
+func (m *Model) Exec(args []string) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
+	name, sub := strings.ToLower(args[0]), ""
+	if len(args) > 1 {
+		sub = strings.ToLower(args[1])
+	}
+	if why, ok := consoleRefused[name+"|"+sub]; ok {
+		return nil, &ConsoleError{Command: strings.ToUpper(name + " " + sub), Reason: why}
+	}
+	if why, ok := consoleRefused[name]; ok {
+		return nil, &ConsoleError{Command: strings.ToUpper(name), Reason: why}
+	}
+
+	t := m.commands()
+	write := m.IsWrite(args)
+	if write && m.readOnly {
+		return nil, fmt.Errorf("%w (%s)", ErrReadOnly, refusedName(t.writes, name, sub))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	res, err := m.rdb.Do(ctx, stringArgs(args)...).Result()
+	if errors.Is(err, redis.Nil) {
+		res, err = nil, nil
+	}
+	if write {
+		target, doc := strings.ToUpper(name), t.docs[name]
+		if d := t.docs[name+"|"+sub]; d != nil {
+			target, doc = target+" "+strings.ToUpper(sub), d
+		}
+		var keys []string
+		if doc == nil || hasKeyArg(doc.Args) {
+			keys = m.CommandKeys(args)
+		}
+		m.record(audit.Entry{Op: "command", Target: target, Keys: keys}, err)
+	}
+	log.WithFields(log.Fields{"op": "exec", "command": name, "write": write}).Debug("console command")
+	return res, err
+}
+
+// IsWrite reports whether read-only mode refuses the command: the server
+// flags it as a write, or it is one of the denied administrative
+// commands. Unknown commands count as writes if COMMAND is unavailable.
+func (m *Model) IsWrite(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	t := m.commands()
+	if t.writes == nil {
+		return true
+	}
+	sub := ""
+	if len(args) > 1 {
+		sub = args[1]
+	}
+	return refusedName(t.writes, args[0], sub) != ""
+}
+
+func hasKeyArg(args []ArgDoc) bool {
+	for _, a := range args {
+		if a.Type == "key" || hasKeyArg(a.Args) {
+			return true
+		}
+	}
+	return false
+}
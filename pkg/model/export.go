@@ -0,0 +1,207 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/dump"
+)
+
+// exportChunk is how many keys, elements or entries are read per call.
+const exportChunk = 1000
+
+// ErrUnsupportedType is returned by ReadRecord for keys of types that
+// cannot be represented in a record, such as module types.
+var ErrUnsupportedType = errors.New("type cannot be exported")
+
+// ReadRecord reads key with its full contents and remaining TTL. Large
+// collections are read in chunks. It returns nil if the key does not
+// exist (any more).
+func (m *Model) ReadRecord(ctx context.Context, key string) (*dump.Record, error) {
+	pipe := m.rdb.Pipeline()
+	typ := pipe.Type(ctx, key)
+	ttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	if typ.Val() == "none" || ttl.Val() == -2 {
+		return nil, nil
+	}
+	r := &dump.Record{Key: key, Type: typ.Val()}
+	if d := ttl.Val(); d > 0 {
+		r.TTL = d.Milliseconds()
+	}
+
+	var err error
+	switch r.Type {
+	case "string":
+		r.String, err = m.rdb.Get(ctx, key).Result()
+	case "hash":
+		r.Hash = map[string]string{}
+		err = m.scanAll(ctx, func(cur uint64) ([]string, uint64, error) {
+			return m.rdb.HScan(ctx, key, cur, "", exportChunk).Result()
+		}, func(page []string) {
+			for i := 0; i+1 < len(page); i += 2 {
+				r.Hash[page[i]] = page[i+1]
+			}
+		})
+	case "set":
+		err = m.scanAll(ctx, func(cur uint64) ([]string, uint64, error) {
+			return m.rdb.SScan(ctx, key, cur, "", exportChunk).Result()
+		}, func(page []string) {
+			r.List = append(r.List, page...)
+		})
+		sort.Strings(r.List) // SSCAN may return a member more than once
+		r.List = dedupSorted(r.List)
+	case "list":
+		for start := int64(0); ; start += exportChunk {
+			page, lerr := m.rdb.LRange(ctx, key, start, start+exportChunk-1).Result()
+			if err = lerr; err != nil || len(page) == 0 {
+				break
+			}
+			r.List = append(r.List, page...)
+			if len(page) < exportChunk {
+				break
+			}
+		}
+	case "zset":
+		for start := int64(0); ; start += exportChunk {
+			page, zerr := m.rdb.ZRangeWithScores(ctx, key, start, start+exportChunk-1).Result()
+			if err = zerr; err != nil || len(page) == 0 {
+				break
+			}
+			for _, z := range page {
+				r.ZSet = append(r.ZSet, dump.ZMember{Member: fmt.Sprint(z.Member), Score: z.Score})
+			}
+			if len(page) < exportChunk {
+				break
+			}
+		}
+	case "stream":
+		r.Stream, err = m.readStream(ctx, key)
+	default:
+		return nil, fmt.Errorf("%s (%s): %w", key, r.Type, ErrUnsupportedType)
+	}
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	return r, nil
+}
+
+// scanAll runs a *SCAN-style call until the cursor wraps around.
+func (m *Model) scanAll(ctx context.Context, call func(uint64) ([]string, uint64, error), page func([]string)) error {
+	var cursor uint64
+	for {
+		items, next, err := call(cursor)
+		if err != nil {
+			return err
+		}
+		page(items)
+		if next == 0 {
+			return ctx.Err()
+		}
+		cursor = next
+	}
+}
+
+// readStream reads all entries of a stream, keeping the field order that
+// XRANGE reports (go-redis' XRange would put fields in a map).
+func (m *Model) readStream(ctx context.Context, key string) ([]dump.StreamEntry, error) {
+	var out []dump.StreamEntry
+	start := "-"
+	for {
+		raw, err := m.rdb.Do(ctx, "xrange", key, start, "+", "count", exportChunk).Slice()
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range raw {
+			e, ok := item.([]interface{})
+			if !ok || len(e) != 2 {
+				return nil, fmt.Errorf("unexpected XRANGE entry %v", item)
+			}
+			entry := dump.StreamEntry{ID: fmt.Sprint(e[0])}
+			fields, _ := e[1].([]interface{})
+			for _, f := range fields {
+				entry.Fields = append(entry.Fields, fmt.Sprint(f))
+			}
+			out = append(out, entry)
+		}
+		if len(raw) < exportChunk {
+			return out, nil
+		}
+		start = "(" + out[len(out)-1].ID // exclusive range, Redis 6.2+
+	}
+}
+
+func dedupSorted(list []string) []string {
+	out := list[:0]
+	for i, s := range list {
+		if i == 0 || s != list[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ExportHeader describes an export of dir from this connection.
+func (m *Model) ExportHeader(dir string) dump.Header {
+	return dump.Header{
+		ExportedAt: time.Now().UTC(),
+		Server:     m.addr,
+		DB:         m.db,
+		Prefix:     normPath(dir),
+	}
+}
+
+// Export reads every key under dir (the whole database for "/"), except
+// excluded ones, and passes each to fn as it goes, so that only one
+// value is held in memory at a time. Keys of unsupported types are
+// skipped and counted.
+func (m *Model) Export(ctx context.Context, dir string, fn func(*dump.Record) error) (exported, skipped int, err error) {
+	match := escapeGlob(withTrail(dir)) + "*"
+	seen := map[string]bool{} // SCAN may return a key more than once
+	var cursor uint64
+	for {
+		keys, next, err := m.rdb.Scan(ctx, cursor, match, exportChunk).Result()
+		if err != nil {
+			return exported, skipped, err
+		}
+		for _, k := range keys {
+			if seen[k] || m.shouldExclude(k) {
+				continue
+			}
+			seen[k] = true
+			r, err := m.ReadRecord(ctx, k)
+			if errors.Is(err, ErrUnsupportedType) {
+				log.WithError(err).Warn("export skipped key")
+				skipped++
+				continue
+			}
+			if err != nil {
+				return exported, skipped, err
+			}
+			if r == nil {
+				continue // deleted while exporting
+			}
+			if err := fn(r); err != nil {
+				return exported, skipped, err
+			}
+			exported++
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	log.WithFields(log.Fields{"op": "export", "dir": dir, "keys": exported, "skipped": skipped}).Info("exported keys")
+	return exported, skipped, nil
+}
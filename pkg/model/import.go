@@ -0,0 +1,400 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/audit"
+	"github.com/nexusriot/redis-walker/pkg/dump"
+)
+
+// ConflictPolicy decides what Import does with target keys that already
+// exist.
+type ConflictPolicy int
+
+const (
+	ConflictSkip      ConflictPolicy = iota // leave the existing key alone
+	ConflictOverwrite                       // replace it; the old value goes to the trash
+	ConflictFail                            // import nothing if any target key exists
+)
+
+// ConflictPolicies lists the policy names, in ConflictPolicy order.
+var ConflictPolicies = []string{"skip", "overwrite", "fail"}
+
+func (p ConflictPolicy) String() string { return ConflictPolicies[p] }
+
+// ParseConflictPolicy parses a policy name.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	for i, name := range ConflictPolicies {
+		if strings.EqualFold(s, name) {
+			return ConflictPolicy(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown conflict policy %q (want one of %v)", s, ConflictPolicies)
+}
+
+// ErrTargetExists is returned by Import with ConflictFail when target
+// keys already exist.
+var ErrTargetExists = errors.New("target keys already exist")
+
+// ImportOptions controls Import.
+type ImportOptions struct {
+	From   string // folder in the document that is remapped; "" = the document's prefix
+	To     string // folder the keys are imported into; "" = keep the key names
+	Policy ConflictPolicy
+	DryRun bool // only count what would happen
+}
+
+// ImportFailure is a key that could not be imported. Key is the target
+// key, or the record's position in the document if it could not be read.
+type ImportFailure struct {
+	Key string
+	Err error
+}
+
+// ImportResult summarizes an import, or what it would do on a dry run.
+type ImportResult struct {
+	Keys      int            // records in the document
+	Types     map[string]int // records per type
+	Conflicts int            // target keys that already exist
+	Written   int            // keys written, or to be written on a dry run
+	Skipped   int            // existing keys left alone
+	Failed    []ImportFailure
+	Sample    []string // the first few conflicting target keys
+}
+
+// importSample is how many conflicting keys ImportResult.Sample keeps.
+const importSample = 10
+
+// Import writes the records of a document into the database. open is
+// called once to check the document against the database and, unless
+// this is a dry run, once more to write it, so the document is streamed
+// rather than held in memory. Records are written in pipelined batches
+// and progress is called after each one; each key is written by one
+// script, which refuses a key created since the check unless it is to be
+// replaced and deletes a key whose commands failed, so a key that fails
+// is not left half-written. TTLs count
+// from now. A key that fails is recorded in the result and the import
+// goes on; the error is only set if the import as a whole failed.
+func (m *Model) Import(ctx context.Context, open func() (dump.Reader, error), opts ImportOptions, progress func(done, total int)) (*ImportResult, error) {
+	return m.load(ctx, "import", "imported", opts, progress, func(fn loadFunc, fail func(ImportFailure)) error {
+		return m.importPass(ctx, open, opts, fn, fail)
+	})
+}
+
+// loadFunc is called by a load pass with the type and target key of each
+// entry of a batch, and the function that writes them.
+type loadFunc func(types, keys []string, write batchWriter) error
+
+// batchWriter writes the entries of the current batch at the positions in
+// at, replacing the existing key where replace is set. It returns the
+// error of each entry, nil if it was written, and ErrTargetExists if the
+// key was created since it was checked.
+type batchWriter func(at []int, replace []bool) []error
+
+// load runs the two passes of Import and RestoreSnapshot over a source
+// that pass reads once per call: the first counts the entries and finds
+// the target keys that exist, the second, unless this is a dry run or
+// ConflictFail finds any, saves the keys to be overwritten to the trash
+// and writes the batches. op names the operation in the trash and the
+// audit log; verb says what happened to the keys in errors and the log.
+func (m *Model) load(ctx context.Context, op, verb string, opts ImportOptions, progress func(done, total int), pass func(fn loadFunc, fail func(ImportFailure)) error) (*ImportResult, error) {
+	if m.readOnly && !opts.DryRun {
+		return nil, ErrReadOnly
+	}
+
+	res := &ImportResult{Types: map[string]int{}}
+	var conflicts []string
+	err := pass(func(types, keys []string, _ batchWriter) error {
+		exists, err := m.existing(ctx, keys)
+		if err != nil {
+			return err
+		}
+		for i, k := range keys {
+			res.Keys++
+			res.Types[types[i]]++
+			if exists[i] {
+				res.Conflicts++
+				conflicts = append(conflicts, k)
+				if len(res.Sample) < importSample {
+					res.Sample = append(res.Sample, k)
+				}
+			}
+		}
+		return nil
+	}, func(f ImportFailure) { res.Failed = append(res.Failed, f) })
+	if err != nil {
+		return res, err
+	}
+
+	if opts.Policy == ConflictFail && res.Conflicts > 0 && !opts.DryRun {
+		return res, fmt.Errorf("%w: %d, e.g. %s", ErrTargetExists, res.Conflicts, res.Sample[0])
+	}
+	if opts.DryRun {
+		switch {
+		case opts.Policy == ConflictSkip:
+			res.Skipped = res.Conflicts
+			res.Written = res.Keys - res.Conflicts
+		case opts.Policy == ConflictFail && res.Conflicts > 0:
+		default:
+			res.Written = res.Keys
+		}
+		return res, nil
+	}
+
+	dir := ""
+	if opts.To != "" {
+		dir = normPath(opts.To)
+	}
+	if opts.Policy != ConflictOverwrite {
+		conflicts = nil // left alone
+	}
+	saved, err := m.saveToTrash(ctx, op, dir, conflicts)
+	if err != nil {
+		return res, err
+	}
+
+	var (
+		written []string
+		done    int
+	)
+	err = pass(func(_, keys []string, write batchWriter) error {
+		exists, err := m.existing(ctx, keys)
+		if err != nil {
+			return err
+		}
+		var (
+			at      []int
+			replace []bool
+		)
+		for i, k := range keys {
+			if exists[i] {
+				switch opts.Policy {
+				case ConflictSkip:
+					res.Skipped++
+					continue
+				case ConflictFail: // created since the check
+					res.Failed = append(res.Failed, ImportFailure{Key: k, Err: ErrTargetExists})
+					continue
+				}
+			}
+			at, replace = append(at, i), append(replace, exists[i])
+		}
+		if len(at) > 0 {
+			for j, err := range write(at, replace) {
+				k := keys[at[j]]
+				switch {
+				case err == nil:
+					res.Written++
+					written = append(written, k)
+				case errors.Is(err, ErrTargetExists) && opts.Policy == ConflictSkip:
+					res.Skipped++
+				default:
+					res.Failed = append(res.Failed, ImportFailure{Key: k, Err: err})
+				}
+			}
+		}
+		if done += len(keys); progress != nil {
+			progress(done, res.Keys)
+		}
+		return ctx.Err()
+	}, func(ImportFailure) {}) // already reported by the first pass
+
+	var loadErr error
+	if n := len(res.Failed); n > 0 {
+		loadErr = fmt.Errorf("%d of %d keys not %s", n, res.Keys, verb)
+	}
+	if err != nil {
+		loadErr = err
+	}
+	if len(written) == 0 {
+		m.dropFromTrash(saved)
+	}
+	m.record(audit.Entry{Op: op, Target: dir, Keys: written}, loadErr)
+	log.WithFields(log.Fields{
+		"op":        op,
+		"target":    dir,
+		"policy":    opts.Policy.String(),
+		"written":   res.Written,
+		"skipped":   res.Skipped,
+		"failed":    len(res.Failed),
+		"conflicts": res.Conflicts,
+	}).Info(verb + " keys")
+	return res, err
+}
+
+// importPass reads the document in batches and passes each batch to fn.
+// Records that cannot be read or remapped are passed to fail.
+func (m *Model) importPass(ctx context.Context, open func() (dump.Reader, error), opts ImportOptions, fn loadFunc, fail func(ImportFailure)) error {
+	rd, err := open()
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+
+	keyFor := remapper(opts, rd.Header().Prefix)
+
+	var (
+		batch []*dump.Record
+		types []string
+		keys  []string
+	)
+	flush := func() error {
+		return fn(types, keys, func(at []int, replace []bool) []error {
+			return m.writeRecords(ctx, batch, keys, at, replace)
+		})
+	}
+	for n := 1; ; n++ {
+		r, err := rd.Next()
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, dump.ErrBadRecord) {
+			fail(ImportFailure{Key: fmt.Sprintf("record #%d", n), Err: err})
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("record #%d: %w", n, err)
+		}
+		k, err := keyFor(r.Key)
+		if err == nil && !importable(r.Type) {
+			err = fmt.Errorf("type %q: %w", r.Type, ErrUnsupportedType)
+		}
+		if err != nil {
+			fail(ImportFailure{Key: r.Key, Err: err})
+			continue
+		}
+		batch, types, keys = append(batch, r), append(types, r.Type), append(keys, k)
+		if len(batch) == previewBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+			batch, types, keys = batch[:0], types[:0], keys[:0]
+		}
+	}
+	if len(batch) > 0 {
+		return flush()
+	}
+	return nil
+}
+
+// writeRecords writes the records of batch at the positions in at, each
+// with writeKeyScript.
+func (m *Model) writeRecords(ctx context.Context, batch []*dump.Record, keys []string, at []int, replace []bool) []error {
+	pipe := m.rdb.Pipeline()
+	cmds := make([]*redis.Cmd, len(at))
+	for j, i := range at {
+		cmds[j] = queueRecord(ctx, pipe, keys[i], batch[i], replace[j])
+	}
+	_, _ = pipe.Exec(ctx) // per-key errors are collected below
+	errs := make([]error, len(at))
+	for j, cmd := range cmds {
+		if errs[j] = cmd.Err(); errs[j] != nil && isBusyKey(errs[j]) {
+			errs[j] = ErrTargetExists // created since the check
+		}
+	}
+	return errs
+}
+
+// remapper returns the function that maps a key of the source folder to
+// its target key: opts.From (or prefix, the folder the source was taken
+// from) is replaced by opts.To. Without opts.To keys keep their names.
+func remapper(opts ImportOptions, prefix string) func(string) (string, error) {
+	if opts.To == "" {
+		return func(k string) (string, error) { return k, nil }
+	}
+	from := opts.From
+	if from == "" {
+		from = prefix
+	}
+	oldPfx, newPfx := withTrail(from), withTrail(opts.To)
+	return func(k string) (string, error) {
+		if !strings.HasPrefix(k, oldPfx) {
+			return "", fmt.Errorf("not under %s", normPath(from))
+		}
+		// Without a source folder (the root) keys keep their leading
+		// slash; drop it so it does not double the target's.
+		rest := strings.TrimPrefix(strings.TrimPrefix(k, oldPfx), "/")
+		if newPfx == "" {
+			return "/" + rest, nil
+		}
+		return newPfx + rest, nil
+	}
+}
+
+// existing reports which keys exist, in one round trip.
+func (m *Model) existing(ctx context.Context, keys []string) ([]bool, error) {
+	pipe := m.rdb.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, k := range keys {
+		cmds[i] = pipe.Exists(ctx, k)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	out := make([]bool, len(keys))
+	for i, c := range cmds {
+		out[i] = c.Val() > 0
+	}
+	return out, nil
+}
+
+func importable(typ string) bool {
+	switch typ {
+	case "string", "hash", "list", "set", "zset", "stream":
+		return true
+	}
+	return false
+}
+
+// writeKeyScript creates KEYS[1] from the commands in ARGV, each given
+// as its argument count followed by its arguments, after ARGV[1], which
+// is "1" to replace an existing key. Running as one script, the check and
+// the write cannot be split by another client, and a key whose command
+// fails is deleted rather than left half-written.
+var writeKeyScript = redis.NewScript(`
+if ARGV[1] == '1' then
+	redis.call('del', KEYS[1])
+elseif redis.call('exists', KEYS[1]) == 1 then
+	return redis.error_reply('BUSYKEY Target key name already exists.')
+end
+local i = 2
+while i <= #ARGV do
+	local n = tonumber(ARGV[i])
+	local r = redis.pcall(unpack(ARGV, i + 1, i + n))
+	if type(r) == 'table' and r.err then
+		redis.call('del', KEYS[1])
+		return r
+	end
+	i = i + n + 1
+end
+return 1
+`)
+
+// queueRecord queues writeKeyScript to create key with r's contents and
+// TTL, replacing the existing key if replace is set, and returns its
+// command. The script fails with BUSYKEY if the key exists otherwise.
+func queueRecord(ctx context.Context, pipe redis.Pipeliner, key string, r *dump.Record, replace bool) *redis.Cmd {
+	cmds, err := dump.Commands(key, r)
+	if err != nil {
+		// importPass lets only importable types through
+		cmd := redis.NewCmd(ctx)
+		cmd.SetErr(err)
+		return cmd
+	}
+	args := []interface{}{"0"}
+	if replace {
+		args[0] = "1"
+	}
+	for _, c := range cmds {
+		args = append(args, len(c))
+		args = append(args, stringArgs(c)...)
+	}
+	return writeKeyScript.Eval(ctx, pipe, []string{key}, args...)
+}
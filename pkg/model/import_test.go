@@ -0,0 +1,43 @@
+package model
+
+import "testing"
+
+func TestRemapper(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     ImportOptions
+		prefix   string
+		key      string
+		want     string
+		notUnder bool
+	}{
+		{name: "no To keeps the name", opts: ImportOptions{}, prefix: "/a", key: "/a/b", want: "/a/b"},
+		{name: "no To keeps keys outside the prefix", opts: ImportOptions{}, prefix: "/a", key: "/z", want: "/z"},
+		{name: "nested", opts: ImportOptions{To: "/x/y"}, prefix: "/a/b", key: "/a/b/c/d", want: "/x/y/c/d"},
+		{name: "From overrides the prefix", opts: ImportOptions{From: "/a", To: "/x"}, prefix: "/a/b", key: "/a/b/c", want: "/x/b/c"},
+		{name: "trailing slashes", opts: ImportOptions{From: "/a/", To: "/x/"}, key: "/a/c", want: "/x/c"},
+		{name: "root source", opts: ImportOptions{To: "/x"}, prefix: "/", key: "/a/b", want: "/x/a/b"},
+		{name: "empty source prefix", opts: ImportOptions{To: "/x"}, prefix: "", key: "/a/b", want: "/x/a/b"},
+		{name: "root source, key without slash", opts: ImportOptions{To: "/x"}, prefix: "/", key: "a/b", want: "/x/a/b"},
+		{name: "root target", opts: ImportOptions{From: "/a", To: "/"}, key: "/a/b/c", want: "/b/c"},
+		{name: "root to root", opts: ImportOptions{From: "/", To: "/"}, key: "/a", want: "/a"},
+		{name: "outside From", opts: ImportOptions{From: "/a", To: "/x"}, key: "/ab/c", notUnder: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := remapper(tt.opts, tt.prefix)(tt.key)
+			if tt.notUnder {
+				if err == nil {
+					t.Fatalf("remap %q = %q, want an error", tt.key, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("remap %q: %v", tt.key, err)
+			}
+			if got != tt.want {
+				t.Errorf("remap %q = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
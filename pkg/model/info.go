@@ -0,0 +1,33 @@
+package model
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Info returns the fields of INFO as a flat map (e.g. "used_memory").
+// Section headers and the per-db keyspace lines are included as-is.
+func (m *Model) Info() (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	raw, err := m.rdb.Info(ctx).Result()
+	if err != nil {
+		return nil, err
+	}
+	return parseInfo(raw), nil
+}
+
+func parseInfo(raw string) map[string]string {
+	out := make(map[string]string)
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -9,28 +9,82 @@ import (
 
 	"github.com/redis/go-redis/v9"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/audit"
+	"github.com/nexusriot/redis-walker/pkg/trash"
 )
 
 type Model struct {
-	rdb     *redis.Client
-	exclude []string
+	rdb      *redis.Client
+	addr     string
+	db       int
+	exclude  []string
+	readOnly bool
+
+	trash         *trash.Store // nil = trash disabled
+	trashMaxBytes int64
+
+	audit *audit.Journal // nil = no audit journal
+
+	allowMonitor bool
+
+	cmds commandTable // COMMAND and COMMAND DOCS for the console
 }
 
 type Node struct {
 	Name  string
 	IsDir bool
 	Value string
+	Type  string // Redis type of a leaf key (string, hash, ...); empty for dirs
+}
+
+// Options configures the Redis connection and model behavior.
+type Options struct {
+	Host            string
+	Port            string
+	DB              int
+	Username        string   // optional ACL user
+	Password        string   // optional password
+	ExcludePrefixes []string // key prefixes hidden from every listing
+
+	// ReadOnly refuses every mutating call, both in the Model API and for
+	// any write command sent over the connection.
+	ReadOnly bool
+	// Replica sends READONLY on each new connection so that cluster
+	// replicas serve reads. Implies ReadOnly.
+	Replica bool
+
+	// Trash, if set, receives a DUMP of every key before it is deleted or
+	// overwritten. TrashMaxBytes caps one operation (0 or more than
+	// trash.MaxOpBytes = trash.MaxOpBytes).
+	Trash         *trash.Store
+	TrashMaxBytes int64
+
+	// Audit, if set, receives one entry per mutation.
+	Audit *audit.Journal
+
+	// AllowMonitor permits MONITOR in read-only mode, where it is
+	// otherwise refused because of its cost on production servers.
+	AllowMonitor bool
 }
 
 // NewModel creates a new Redis-backed model.
-func NewModel(host, port string, db int, username, password string, excludePrefixes []string) (*Model, error) {
-	addr := fmt.Sprintf("%s:%s", host, port)
+func NewModel(o Options) (*Model, error) {
+	addr := fmt.Sprintf("%s:%s", o.Host, o.Port)
+	if o.Replica {
+		o.ReadOnly = true
+	}
 
 	opts := &redis.Options{
 		Addr:     addr,
-		DB:       db,
-		Username: username, // optional ACL user
-		Password: password, // optional password
+		DB:       o.DB,
+		Username: o.Username, // optional ACL user
+		Password: o.Password, // optional password
+
+		ClientName: ClientName, // CLIENT SETNAME on every connection
+	}
+	if o.Replica {
+		opts.OnConnect = readOnlyOnConnect
 	}
 
 	rdb := redis.NewClient(opts)
@@ -43,19 +97,26 @@ func NewModel(host, port string, db int, username, password string, excludePrefi
 		return nil, fmt.Errorf("redis ping failed: %w", err)
 	}
 
-	// Normalize exclude prefixes
-	normEx := make([]string, 0, len(excludePrefixes))
-	for _, p := range excludePrefixes {
-		p = strings.TrimSpace(p)
-		if p == "" {
-			continue
+	if o.ReadOnly {
+		guard, err := newReadOnlyGuard(ctx, rdb)
+		if err != nil {
+			return nil, err
 		}
-		normEx = append(normEx, normPath(p))
+		rdb.AddHook(guard)
 	}
 
+	normEx := normPrefixes(o.ExcludePrefixes)
+
 	return &Model{
-		rdb:     rdb,
-		exclude: normEx,
+		rdb:           rdb,
+		addr:          addr,
+		db:            o.DB,
+		exclude:       normEx,
+		readOnly:      o.ReadOnly,
+		trash:         o.Trash,
+		trashMaxBytes: o.TrashMaxBytes,
+		audit:         o.Audit,
+		allowMonitor:  o.AllowMonitor,
 	}, nil
 }
 
@@ -69,6 +130,9 @@ func (m *Model) Del(key string) error                  { return m.del(key) }
 func (m *Model) DelDir(key string) error               { return m.deldir(key) }
 func (m *Model) RenameDir(oldDir, newDir string) error { return m.renameDir(oldDir, newDir) }
 
+// ReadOnly reports whether the model refuses writes.
+func (m *Model) ReadOnly() bool { return m.readOnly }
+
 const dirMarker = ".dir"
 
 func normPath(p string) string {
@@ -122,11 +186,30 @@ func baseOf(p string) string {
 }
 
 func (m *Model) shouldExclude(key string) bool {
-	if len(m.exclude) == 0 {
+	return excluded(m.exclude, key)
+}
+
+// normPrefixes normalizes exclude prefixes, dropping blank ones.
+func normPrefixes(prefixes []string) []string {
+	out := make([]string, 0, len(prefixes))
+	for _, p := range prefixes {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		out = append(out, normPath(p))
+	}
+	return out
+}
+
+// excluded reports whether key falls under one of the normalized
+// prefixes.
+func excluded(prefixes []string, key string) bool {
+	if len(prefixes) == 0 {
 		return false
 	}
 	k := normPath(key)
-	for _, p := range m.exclude {
+	for _, p := range prefixes {
 		if strings.HasPrefix(k, p) {
 			return true
 		}
@@ -190,14 +273,83 @@ func (m *Model) ls(directory string) ([]*Node, error) {
 		return nil, err
 	}
 
-	type childInfo struct {
-		isDir     bool
-		hasFile   bool
-		fileKey   string
-		fileValue string
+	children := lsChildren(prefix, keys)
+
+	// Fetch key types in one round trip, then values of string keys only.
+	fileNames := make([]string, 0, len(children))
+	for name, ci := range children {
+		if ci.hasFile && ci.fileKey != "" {
+			fileNames = append(fileNames, name)
+		}
 	}
-	children := map[string]*childInfo{}
+	typeCmds := make([]*redis.StatusCmd, len(fileNames))
+	if len(fileNames) > 0 {
+		pipe := m.rdb.Pipeline()
+		for i, name := range fileNames {
+			typeCmds[i] = pipe.Type(ctx, children[name].fileKey)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("type lookup under %s: %w", prefix, err)
+		}
+	}
+	for i, name := range fileNames {
+		ci := children[name]
+		ci.fileType = typeCmds[i].Val()
+		if ci.fileType != "string" {
+			log.WithFields(log.Fields{
+				"op":   "ls-file",
+				"key":  ci.fileKey,
+				"type": ci.fileType,
+			}).Debug("non-string Redis value; skipping value load")
+			continue
+		}
+
+		val, err := m.rdb.Get(ctx, ci.fileKey).Result()
+		if err != nil && err != redis.Nil {
+			// The key may have changed type between TYPE and GET; don't
+			// fail the whole listing for that.
+			if strings.Contains(err.Error(), "WRONGTYPE") {
+				continue
+			}
+			// real error -> bubble up
+			return nil, fmt.Errorf("get %s: %w", ci.fileKey, err)
+		}
+
+		ci.fileValue = val
+		log.WithFields(log.Fields{
+			"op":   "ls-file",
+			"key":  ci.fileKey,
+			"name": name,
+		}).Debug("redis ls loaded value")
+	}
+
+	nodes := lsNodes(directory, children)
+
+	log.WithFields(log.Fields{
+		"op":       "ls",
+		"dir":      directory,
+		"pfx":      prefix,
+		"count":    len(nodes),
+		"duration": time.Since(start),
+	}).Debug("redis ls done")
+
+	return nodes, nil
+}
+
+// childInfo is one direct child of a listed folder: a subfolder, a key,
+// or both when a key and a folder share a name.
+type childInfo struct {
+	isDir     bool
+	hasFile   bool
+	fileKey   string
+	fileValue string
+	fileType  string
+}
 
+// lsChildren groups keys under prefix by the direct child of the folder
+// they belong to. Folder markers are left out.
+func lsChildren(prefix string, keys []string) map[string]*childInfo {
+	children := map[string]*childInfo{}
 	for _, key := range keys {
 		if key == "" {
 			continue
@@ -227,37 +379,11 @@ func (m *Model) ls(directory string) ([]*Node, error) {
 			ci.fileKey = key
 		}
 	}
+	return children
+}
 
-	// Fetch file values (only for string keys).
-	for name, ci := range children {
-		if !ci.hasFile || ci.fileKey == "" {
-			continue
-		}
-
-		val, err := m.rdb.Get(ctx, ci.fileKey).Result()
-		if err != nil && err != redis.Nil {
-			// If this is a WRONGTYPE error, it means the key is not a string
-			// (hash/list/set/zset/stream). We don't want to fail the whole
-			// listing; just skip loading the value.
-			if strings.Contains(err.Error(), "WRONGTYPE") {
-				log.WithError(err).WithFields(log.Fields{
-					"op":  "ls-file",
-					"key": ci.fileKey,
-				}).Debug("non-string Redis value; skipping value load")
-				continue
-			}
-			// real error -> bubble up
-			return nil, fmt.Errorf("get %s: %w", ci.fileKey, err)
-		}
-
-		ci.fileValue = val
-		log.WithFields(log.Fields{
-			"op":   "ls-file",
-			"key":  ci.fileKey,
-			"name": name,
-		}).Debug("redis ls loaded value")
-	}
-
+// lsNodes returns the children of directory as nodes, sorted by name.
+func lsNodes(directory string, children map[string]*childInfo) []*Node {
 	names := make([]string, 0, len(children))
 	for k := range children {
 		names = append(names, k)
@@ -283,22 +409,17 @@ func (m *Model) ls(directory string) ([]*Node, error) {
 				Name:  normPath(full),
 				IsDir: false,
 				Value: ci.fileValue,
+				Type:  ci.fileType,
 			})
 		}
 	}
-
-	log.WithFields(log.Fields{
-		"op":       "ls",
-		"dir":      directory,
-		"pfx":      prefix,
-		"count":    len(nodes),
-		"duration": time.Since(start),
-	}).Debug("redis ls done")
-
-	return nodes, nil
+	return nodes
 }
 
 func (m *Model) set(key, value string) error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	k := normPath(key)
@@ -306,7 +427,15 @@ func (m *Model) set(key, value string) error {
 		return fmt.Errorf("cannot set value on root")
 	}
 	start := time.Now()
-	if err := m.rdb.Set(ctx, k, value, 0).Err(); err != nil {
+	saved, err := m.saveToTrash(ctx, "set", "", []string{k})
+	if err != nil {
+		return err
+	}
+	old := m.describe(ctx, k)
+	err = m.rdb.Set(ctx, k, value, 0).Err()
+	m.record(audit.Entry{Op: "set", Keys: []string{k}, Old: old, New: audit.Digest(value)}, err)
+	if err != nil {
+		m.dropFromTrash(saved)
 		log.WithError(err).WithFields(log.Fields{
 			"op":  "set",
 			"key": k,
@@ -323,6 +452,9 @@ func (m *Model) set(key, value string) error {
 }
 
 func (m *Model) mkdir(directory string) error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	dir := normPath(directory)
@@ -341,27 +473,47 @@ func (m *Model) mkdir(directory string) error {
 		// something already exists under this prefix, that's enough
 		return nil
 	}
-	if err := m.rdb.Set(ctx, markerKey, "", 0).Err(); err != nil {
+	saved, err := m.saveToTrash(ctx, "mkdir", dir, nil)
+	if err != nil {
 		return err
 	}
-	return nil
+	err = m.rdb.Set(ctx, markerKey, "", 0).Err()
+	m.record(audit.Entry{Op: "mkdir", Dir: dir, Keys: []string{markerKey}, New: audit.Digest("")}, err)
+	if err != nil {
+		m.dropFromTrash(saved)
+	}
+	return err
 }
 
 func (m *Model) del(key string) error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	k := normPath(key)
 	if k == "/" {
 		return fmt.Errorf("cannot delete root")
 	}
-	if _, err := m.rdb.Del(ctx, k).Result(); err != nil {
+	saved, err := m.saveToTrash(ctx, "del", "", []string{k})
+	if err != nil {
+		return err
+	}
+	old := m.describe(ctx, k)
+	err = m.rdb.Del(ctx, k).Err()
+	m.record(audit.Entry{Op: "del", Keys: []string{k}, Old: old}, err)
+	if err != nil {
+		m.dropFromTrash(saved)
 		return err
 	}
 	return nil
 }
 
 func (m *Model) deldir(key string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if m.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 	pfx := withTrail(key)
 	keys, err := m.scanKeysWithPrefix(ctx, pfx)
@@ -371,13 +523,24 @@ func (m *Model) deldir(key string) error {
 	if len(keys) == 0 {
 		return nil
 	}
-	if _, err := m.rdb.Del(ctx, keys...).Result(); err != nil {
+	saved, err := m.saveToTrash(ctx, "deldir", normPath(key), keys)
+	if err != nil {
+		return err
+	}
+	values := m.describeKeys(ctx, keys, saved)
+	err = m.rdb.Del(ctx, keys...).Err()
+	m.record(audit.Entry{Op: "deldir", Dir: normPath(key), Keys: keys, Values: values}, err)
+	if err != nil {
+		m.dropFromTrash(saved)
 		return err
 	}
 	return nil
 }
 
 func (m *Model) renameDir(oldDir, newDir string) error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 	defer cancel()
 
@@ -404,22 +567,49 @@ func (m *Model) renameDir(oldDir, newDir string) error {
 		return fmt.Errorf("target already exists: %s", newDir)
 	}
 
+	// The moved keys are captured so that Undo can move them back.
+	saved, err := m.capture(ctx, &trash.Op{Kind: "rename", Dir: normPath(oldDir), Target: normPath(newDir)}, srcKeys)
+	if err != nil {
+		return err
+	}
+	values := m.describeKeys(ctx, srcKeys, saved)
+	written, err := m.copyAndDelete(ctx, srcKeys, oldPfx, newPfx)
+	for i := range values {
+		values[i].New = written[values[i].Key]
+	}
+	m.record(audit.Entry{Op: "rename", Dir: normPath(oldDir), Target: normPath(newDir), Keys: srcKeys, Values: values}, err)
+	if err != nil {
+		m.dropFromTrash(saved)
+	}
+	return err
+}
+
+// copyAndDelete copies srcKeys to newPfx and deletes them. With auditing
+// on it returns the description of each value written, by source key.
+func (m *Model) copyAndDelete(ctx context.Context, srcKeys []string, oldPfx, newPfx string) (map[string]*audit.Value, error) {
+	var written map[string]*audit.Value
+	if m.audit != nil {
+		written = make(map[string]*audit.Value, len(srcKeys))
+	}
 	// Copy all keys
 	for _, oldKey := range srcKeys {
 		newKey := strings.Replace(oldKey, oldPfx, newPfx, 1)
 		val, err := m.rdb.Get(ctx, oldKey).Result()
 		if err != nil && err != redis.Nil {
-			return fmt.Errorf("copy %s -> %s get failed: %w", oldKey, newKey, err)
+			return written, fmt.Errorf("copy %s -> %s get failed: %w", oldKey, newKey, err)
 		}
 		if err := m.rdb.Set(ctx, newKey, val, 0).Err(); err != nil {
-			return fmt.Errorf("copy %s -> %s set failed: %w", oldKey, newKey, err)
+			return written, fmt.Errorf("copy %s -> %s set failed: %w", oldKey, newKey, err)
+		}
+		if written != nil {
+			written[oldKey] = audit.Digest(val)
 		}
 	}
 	// delete old prefix
 	if _, err := m.rdb.Del(ctx, srcKeys...).Result(); err != nil {
-		return err
+		return written, err
 	}
-	return nil
+	return written, nil
 }
 
 func (m *Model) get(key string) (*Node, error) {
@@ -442,6 +632,7 @@ func (m *Model) get(key string) (*Node, error) {
 			Name:  k,
 			IsDir: false,
 			Value: val,
+			Type:  "string",
 		}, nil
 	}
 	if err != nil && err != redis.Nil {
@@ -456,6 +647,7 @@ func (m *Model) get(key string) (*Node, error) {
 				Name:  k,
 				IsDir: false,
 				Value: "",
+				Type:  m.rdb.Type(ctx, k).Val(),
 			}, nil
 		}
 		return nil, err
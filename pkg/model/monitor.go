@@ -0,0 +1,163 @@
+package model
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrMonitorDisabled is returned by Monitor in read-only mode unless
+// MONITOR was explicitly allowed.
+var ErrMonitorDisabled = errors.New("MONITOR is disabled in read-only mode (use -allow-monitor to force)")
+
+// MonitorLine is one command reported by MONITOR.
+type MonitorLine struct {
+	Time   time.Time
+	DB     int
+	Client string // client address, "lua" or "unix:..."
+	Args   []string
+	Raw    string
+}
+
+// Monitor runs MONITOR on a dedicated connection and sends every command
+// to out until ctx is cancelled or the connection fails. MONITOR slows
+// the server down noticeably; callers should warn before starting it.
+func (m *Model) Monitor(ctx context.Context, out chan<- MonitorLine) error {
+	if m.readOnly && !m.allowMonitor {
+		return ErrMonitorDisabled
+	}
+	opt := m.rdb.Options()
+	conn, err := opt.Dialer(ctx, opt.Network, opt.Addr)
+	if err != nil {
+		return fmt.Errorf("monitor connect: %w", err)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	defer conn.Close()
+
+	rd := bufio.NewReader(conn)
+	send := func(args ...string) error {
+		var b strings.Builder
+		fmt.Fprintf(&b, "*%d\r\n", len(args))
+		for _, a := range args {
+			fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+		}
+		if _, err := conn.Write([]byte(b.String())); err != nil {
+			return err
+		}
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(line, "-") {
+			return errors.New(strings.TrimSpace(line[1:]))
+		}
+		return nil
+	}
+	if opt.Password != "" {
+		auth := []string{"AUTH", opt.Password}
+		if opt.Username != "" {
+			auth = []string{"AUTH", opt.Username, opt.Password}
+		}
+		if err := send(auth...); err != nil {
+			return fmt.Errorf("monitor auth: %w", err)
+		}
+	}
+	if err := send("CLIENT", "SETNAME", ClientName); err != nil {
+		return fmt.Errorf("monitor: %w", err)
+	}
+	if err := send("MONITOR"); err != nil {
+		return fmt.Errorf("monitor: %w", err)
+	}
+
+	for {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("monitor: %w", err)
+		}
+		ml, ok := parseMonitorLine(strings.TrimRight(line, "\r\n"))
+		if !ok {
+			continue
+		}
+		select {
+		case out <- ml:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// parseMonitorLine parses `+1339518083.107412 [0 127.0.0.1:60866] "set" "k" "v"`.
+func parseMonitorLine(s string) (MonitorLine, bool) {
+	s = strings.TrimPrefix(s, "+")
+	ml := MonitorLine{Raw: s}
+	tsEnd := strings.IndexByte(s, ' ')
+	open := strings.IndexByte(s, '[')
+	closeIdx := strings.IndexByte(s, ']')
+	if tsEnd < 0 || open < 0 || closeIdx < open {
+		return ml, false
+	}
+	if ts, err := strconv.ParseFloat(s[:tsEnd], 64); err == nil {
+		sec := int64(ts)
+		ml.Time = time.Unix(sec, int64((ts-float64(sec))*1e9))
+	}
+	db, client, _ := strings.Cut(s[open+1:closeIdx], " ")
+	ml.DB, _ = strconv.Atoi(db)
+	ml.Client = client
+
+	rest := s[closeIdx+1:]
+	for {
+		start := strings.IndexByte(rest, '"')
+		if start < 0 {
+			break
+		}
+		var b strings.Builder
+		i := start + 1
+		for ; i < len(rest) && rest[i] != '"'; i++ {
+			if rest[i] != '\\' || i+1 >= len(rest) {
+				b.WriteByte(rest[i])
+				continue
+			}
+			i++
+			switch rest[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'a':
+				b.WriteByte('\a')
+			case 'b':
+				b.WriteByte('\b')
+			case 'x':
+				if i+2 < len(rest) {
+					if v, err := strconv.ParseUint(rest[i+1:i+3], 16, 8); err == nil {
+						b.WriteByte(byte(v))
+						i += 2
+						continue
+					}
+				}
+				b.WriteByte('x')
+			default:
+				b.WriteByte(rest[i])
+			}
+		}
+		ml.Args = append(ml.Args, b.String())
+		if i >= len(rest) {
+			break
+		}
+		rest = rest[i+1:]
+	}
+	return ml, len(ml.Args) > 0
+}
+
+// MonitorAllowed reports whether Monitor may run.
+func (m *Model) MonitorAllowed() bool { return !m.readOnly || m.allowMonitor }
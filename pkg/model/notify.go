@@ -0,0 +1,206 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+)
+
+// KeyEvent is one keyspace notification: Event (set, del, expired, ...)
+// happened to Key.
+type KeyEvent struct {
+	Key   string
+	Event string
+}
+
+// KeyspaceEvents returns the server's notify-keyspace-events flags.
+func (m *Model) KeyspaceEvents() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	res, err := m.rdb.ConfigGet(ctx, "notify-keyspace-events").Result()
+	if err != nil {
+		return "", err
+	}
+	return res["notify-keyspace-events"], nil
+}
+
+// KeyspaceEventsUsable reports whether flags publish keyspace (K)
+// notifications for at least some event classes.
+func KeyspaceEventsUsable(flags string) bool {
+	return strings.Contains(flags, "K") && strings.ContainsAny(flags, "Ag$lshzxe")
+}
+
+// EnableKeyspaceEvents adds keyspace notifications for all event classes
+// ("KA") to the server's notify-keyspace-events, keeping existing flags.
+func (m *Model) EnableKeyspaceEvents() error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
+	cur, err := m.KeyspaceEvents()
+	if err != nil {
+		return err
+	}
+	next := cur
+	for _, f := range "KA" {
+		if !strings.ContainsRune(next, f) {
+			next += string(f)
+		}
+	}
+	if next == cur {
+		return nil
+	}
+	if err := m.SetServerConfig("notify-keyspace-events", next); err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{"old": cur, "new": next}).Info("enabled keyspace notifications")
+	return nil
+}
+
+// Watcher delivers keyspace notifications for keys under a prefix.
+type Watcher struct {
+	ps      *redis.PubSub
+	events  chan KeyEvent
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+// WatchPrefix subscribes to keyspace notifications for keys under dir.
+// Excluded keys are filtered out. Events are dropped, not queued, when
+// the consumer falls behind; Dropped counts them.
+func (m *Model) WatchPrefix(dir string) (*Watcher, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	channelPfx := fmt.Sprintf("__keyspace@%d__:", m.db)
+	pattern := channelPfx + escapeGlob(withTrail(dir)) + "*"
+	ps := m.rdb.PSubscribe(ctx, pattern)
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return nil, fmt.Errorf("subscribe %s: %w", pattern, err)
+	}
+
+	w := &Watcher{
+		ps:     ps,
+		events: make(chan KeyEvent, 256),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(w.events)
+		for msg := range ps.Channel() {
+			key := strings.TrimPrefix(msg.Channel, channelPfx)
+			if m.shouldExclude(key) {
+				continue
+			}
+			select {
+			case w.events <- KeyEvent{Key: key, Event: msg.Payload}:
+			case <-w.done:
+				return
+			default:
+				w.dropped.Add(1)
+			}
+		}
+	}()
+	log.WithField("pattern", pattern).Debug("watching keyspace notifications")
+	return w, nil
+}
+
+// Events returns the notification channel; it is closed by Close.
+func (w *Watcher) Events() <-chan KeyEvent { return w.events }
+
+// Dropped returns the number of notifications lost so far because the
+// consumer fell behind.
+func (w *Watcher) Dropped() int64 { return w.dropped.Load() }
+
+// Close unsubscribes and stops delivery.
+func (w *Watcher) Close() error {
+	close(w.done)
+	return w.ps.Close()
+}
+
+// LsKeys is Ls limited to keys, for applying notifications without a
+// SCAN: it reads only those keys and returns the entries of directory
+// they make up, plus the names of key entries that no longer exist.
+// A subfolder is returned when one of keys under it exists; a deleted
+// key never removes its subfolder, as that needs a SCAN to decide.
+func (m *Model) LsKeys(directory string, keys []string) (nodes []*Node, gone []string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	prefix := withTrail(directory)
+	var in []string
+	for _, k := range keys {
+		if strings.HasPrefix(k, prefix) {
+			in = append(in, k)
+		}
+	}
+	if len(in) == 0 {
+		return nil, nil, nil
+	}
+
+	pipe := m.rdb.Pipeline()
+	types := make([]*redis.StatusCmd, len(in))
+	for i, k := range in {
+		types[i] = pipe.Type(ctx, k)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, nil, fmt.Errorf("type lookup under %s: %w", prefix, err)
+	}
+	var live []string
+	typeOf := map[string]string{}
+	for i, k := range in {
+		if t := types[i].Val(); t != "none" {
+			live = append(live, k)
+			typeOf[k] = t
+			continue
+		}
+		for _, n := range lsNodes(directory, lsChildren(prefix, []string{k})) {
+			if !n.IsDir {
+				gone = append(gone, n.Name)
+			}
+		}
+	}
+
+	children := lsChildren(prefix, live)
+	pipe = m.rdb.Pipeline()
+	gets := map[string]*redis.StringCmd{}
+	for _, ci := range children {
+		if !ci.hasFile {
+			continue
+		}
+		ci.fileType = typeOf[ci.fileKey]
+		if ci.fileType == "string" {
+			gets[ci.fileKey] = pipe.Get(ctx, ci.fileKey)
+		}
+	}
+	if len(gets) > 0 {
+		// A key deleted or retyped since TYPE only loses its value here;
+		// its own notification is on the way.
+		if _, err := pipe.Exec(ctx); err != nil && ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		for _, ci := range children {
+			if cmd, ok := gets[ci.fileKey]; ok && ci.hasFile {
+				ci.fileValue = cmd.Val()
+			}
+		}
+	}
+	return lsNodes(directory, children), gone, nil
+}
+
+// escapeGlob quotes the glob metacharacters of a PSUBSCRIBE pattern.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
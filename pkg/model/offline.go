@@ -0,0 +1,334 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/audit"
+	"github.com/nexusriot/redis-walker/pkg/dump"
+	"github.com/nexusriot/redis-walker/pkg/rdb"
+	"github.com/nexusriot/redis-walker/pkg/trash"
+)
+
+// offlinePreviewItems caps the elements shown as the value of a
+// collection key.
+const offlinePreviewItems = 100
+
+// Offline serves one database of an RDB file through the same browsing
+// API as Model, without a server. It is always read-only. The file is
+// loaded into memory when opened.
+type Offline struct {
+	path    string
+	db      int
+	created time.Time // when the snapshot was taken; TTLs count from here
+	keys    []string  // sorted
+	entries map[string]*rdb.Entry
+}
+
+// OpenRDB loads database db of the RDB file at path. Keys that had
+// expired when the snapshot was taken, and excluded keys, are left out.
+func OpenRDB(path string, db int, exclude []string) (*Offline, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	exclude = normPrefixes(exclude)
+	var (
+		loaded []*rdb.Entry
+		dbs    = map[int]int{}
+	)
+	info, err := rdb.Parse(f, st.Size(), func(e *rdb.Entry) error {
+		dbs[e.DB]++
+		if e.DB == db && !excluded(exclude, e.Record.Key) {
+			loaded = append(loaded, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	o := &Offline{path: path, db: db, created: info.Created(), entries: map[string]*rdb.Entry{}}
+	if o.created.IsZero() {
+		if st, err := f.Stat(); err == nil {
+			o.created = st.ModTime()
+		}
+	}
+	expired := 0
+	for _, e := range loaded {
+		if !e.ExpireAt.IsZero() && !e.ExpireAt.After(o.created) {
+			expired++
+			continue
+		}
+		o.entries[e.Record.Key] = e
+		o.keys = append(o.keys, e.Record.Key)
+	}
+	sort.Strings(o.keys)
+
+	log.WithFields(log.Fields{
+		"op":        "rdb-load",
+		"file":      path,
+		"version":   info.Version,
+		"redis_ver": info.Aux["redis-ver"],
+		"created":   o.created,
+		"db":        db,
+		"keys":      len(o.keys),
+		"expired":   expired,
+		"dbs":       dbs,
+		"duration":  time.Since(start),
+	}).Info("loaded RDB file")
+	if len(o.keys) == 0 && len(dbs) > 0 && dbs[db] == 0 {
+		log.WithField("dbs", dbs).Warn("the RDB file has no keys in the selected database")
+	}
+	return o, nil
+}
+
+// Created returns when the snapshot was taken.
+func (o *Offline) Created() time.Time { return o.created }
+
+// under returns the sorted keys under prefix ("" = all keys).
+func (o *Offline) under(prefix string) []string {
+	i := sort.SearchStrings(o.keys, prefix)
+	j := i
+	for j < len(o.keys) && strings.HasPrefix(o.keys[j], prefix) {
+		j++
+	}
+	return o.keys[i:j]
+}
+
+// Ls lists a folder like Model.Ls. Collection keys get a text rendering
+// of their first elements as value.
+func (o *Offline) Ls(directory string) ([]*Node, error) {
+	prefix := withTrail(directory)
+	children := lsChildren(prefix, o.under(prefix))
+	for _, ci := range children {
+		if e, ok := o.entries[ci.fileKey]; ok && ci.hasFile {
+			ci.fileType = e.Record.Type
+			ci.fileValue = renderValue(&e.Record)
+		}
+	}
+	return lsNodes(directory, children), nil
+}
+
+// Get returns one key, or a folder node if key is a prefix of others.
+func (o *Offline) Get(key string) (*Node, error) {
+	k := normPath(key)
+	if k == "/" {
+		return &Node{Name: "/", IsDir: true}, nil
+	}
+	if e, ok := o.entries[k]; ok {
+		return &Node{Name: k, Value: renderValue(&e.Record), Type: e.Record.Type}, nil
+	}
+	if len(o.under(withTrail(k))) > 0 {
+		return &Node{Name: k, IsDir: true}, nil
+	}
+	return nil, fmt.Errorf("not found: %s", k)
+}
+
+// renderValue returns the string value of r, or a text rendering of the
+// first elements of a collection.
+func renderValue(r *dump.Record) string {
+	var (
+		b     strings.Builder
+		n     int // elements in the collection
+		shown int
+	)
+	line := func(format string, args ...interface{}) {
+		if shown < offlinePreviewItems {
+			fmt.Fprintf(&b, format+"\n", args...)
+			shown++
+		}
+	}
+	switch r.Type {
+	case "string":
+		return r.String
+	case "hash":
+		fields := make([]string, 0, len(r.Hash))
+		for f := range r.Hash {
+			fields = append(fields, f)
+		}
+		sort.Strings(fields)
+		for _, f := range fields {
+			line("%s => %s", strconv.Quote(f), strconv.Quote(r.Hash[f]))
+		}
+		n = len(fields)
+	case "list":
+		for i, v := range r.List {
+			line("%d) %s", i+1, strconv.Quote(v))
+		}
+		n = len(r.List)
+	case "set":
+		for _, v := range r.List {
+			line("%s", strconv.Quote(v))
+		}
+		n = len(r.List)
+	case "zset":
+		for _, z := range r.ZSet {
+			line("%s %s", strconv.FormatFloat(z.Score, 'g', -1, 64), strconv.Quote(z.Member))
+		}
+		n = len(r.ZSet)
+	case "stream":
+		for _, e := range r.Stream {
+			fields := make([]string, len(e.Fields))
+			for i, f := range e.Fields {
+				fields[i] = strconv.Quote(f)
+			}
+			line("%s %s", e.ID, strings.Join(fields, " "))
+		}
+		n = len(r.Stream)
+	default:
+		return fmt.Sprintf("<%s: module value, not shown>", r.Type)
+	}
+	if n > shown {
+		fmt.Fprintf(&b, "... and %d more\n", n-shown)
+	}
+	return fmt.Sprintf("<%d %s>\n%s", n, lengthUnit(r.Type), b.String())
+}
+
+// PreviewDir summarizes the keys under a folder. Sizes are the bytes
+// the values take in the file.
+func (o *Offline) PreviewDir(dir string) (*PrefixStats, error) {
+	keys := o.under(withTrail(dir))
+	st := &PrefixStats{
+		Dir:      normPath(dir),
+		Keys:     len(keys),
+		Measured: len(keys),
+		ByType:   map[string]int{},
+		Sample:   keys[:min(previewNames, len(keys))],
+	}
+	for _, k := range keys {
+		e := o.entries[k]
+		st.ByType[e.Record.Type]++
+		st.Bytes += e.Size
+	}
+	return st, nil
+}
+
+// Snapshot returns the state of keys as of the snapshot.
+func (o *Offline) Snapshot(keys []string) ([]KeyState, error) {
+	out := make([]KeyState, len(keys))
+	for i, k := range keys {
+		out[i] = KeyState{Key: k, TTL: -1}
+		e, ok := o.entries[k]
+		if !ok {
+			continue
+		}
+		out[i].Exists = true
+		out[i].Type = e.Record.Type
+		out[i].TTL = o.ttl(e)
+		if e.Record.Type == "string" {
+			out[i].Value = e.Record.String
+		} else {
+			out[i].Value = strings.SplitN(renderValue(&e.Record), "\n", 2)[0]
+		}
+	}
+	return out, nil
+}
+
+// ttl returns the time a key had left when the snapshot was taken, or
+// -1 if it has no expiry.
+func (o *Offline) ttl(e *rdb.Entry) time.Duration {
+	if e.ExpireAt.IsZero() {
+		return -1
+	}
+	return e.ExpireAt.Sub(o.created)
+}
+
+// Number reads a numeric value like Model.Number.
+func (o *Offline) Number(key, member string) (float64, error) {
+	e, ok := o.entries[key]
+	if !ok {
+		return 0, fmt.Errorf("%s does not exist", key)
+	}
+	if member != "" {
+		for _, z := range e.Record.ZSet {
+			if z.Member == member {
+				return finite(key, z.Score)
+			}
+		}
+		return 0, fmt.Errorf("%s has no member %q", key, member)
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(e.Record.String), 64)
+	if e.Record.Type != "string" || err != nil {
+		return 0, fmt.Errorf("%s is not numeric: %q", key, OneLine(e.Record.String, 40))
+	}
+	return finite(key, v)
+}
+
+// ExportHeader describes an export of dir from the file.
+func (o *Offline) ExportHeader(dir string) dump.Header {
+	return dump.Header{
+		ExportedAt: time.Now().UTC(),
+		Server:     o.path,
+		DB:         o.db,
+		Prefix:     normPath(dir),
+	}
+}
+
+// Export passes every key under dir to fn, like Model.Export. TTLs are
+// what the keys had left when the snapshot was taken. Keys of module
+// types are skipped and counted.
+func (o *Offline) Export(ctx context.Context, dir string, fn func(*dump.Record) error) (exported, skipped int, err error) {
+	for _, k := range o.under(withTrail(dir)) {
+		if err := ctx.Err(); err != nil {
+			return exported, skipped, err
+		}
+		e := o.entries[k]
+		if !importable(e.Record.Type) {
+			log.WithField("key", k).WithField("type", e.Record.Type).Warn("export skipped key")
+			skipped++
+			continue
+		}
+		r := e.Record
+		if ttl := o.ttl(e); ttl > 0 {
+			r.TTL = ttl.Milliseconds()
+		}
+		if err := fn(&r); err != nil {
+			return exported, skipped, err
+		}
+		exported++
+	}
+	return exported, skipped, nil
+}
+
+// ReadOnly reports true: an RDB file cannot be changed.
+func (o *Offline) ReadOnly() bool { return true }
+
+// Server returns the path of the file.
+func (o *Offline) Server() string { return o.path }
+
+// DB returns the database index being browsed.
+func (o *Offline) DB() int { return o.db }
+
+// Trash returns nil: nothing is deleted from a file.
+func (o *Offline) Trash() *trash.Store { return nil }
+
+// Audit returns nil: nothing is changed in a file.
+func (o *Offline) Audit() *audit.Journal { return nil }
+
+// The mutators of the browsing API all refuse.
+
+func (o *Offline) Set(key, value string) error                  { return ErrReadOnly }
+func (o *Offline) SetIfUnchanged(key, base, value string) error { return ErrReadOnly }
+func (o *Offline) MkDir(directory string) error                 { return ErrReadOnly }
+func (o *Offline) Del(key string) error                         { return ErrReadOnly }
+func (o *Offline) DelDir(key string) error                      { return ErrReadOnly }
+func (o *Offline) RenameDir(oldDir, newDir string) error        { return ErrReadOnly }
+func (o *Offline) Undo() (*trash.Op, error)                     { return nil, ErrReadOnly }
+func (o *Offline) RestoreFromTrash(id, target string, replace bool) (int, error) {
+	return 0, ErrReadOnly
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	previewNames     = 10    // key names listed in a preview
+	previewMemLimit  = 10000 // keys measured with MEMORY USAGE; the rest is extrapolated
+	previewBatchSize = 500   // commands per pipeline round trip
+)
+
+// PrefixStats summarizes the keys under a folder, for previews of
+// recursive operations.
+type PrefixStats struct {
+	Dir      string
+	Keys     int
+	Bytes    int64 // total MEMORY USAGE; extrapolated when Measured < Keys
+	Measured int   // keys whose memory was actually measured
+	ByType   map[string]int
+	Sample   []string // first key names in sort order
+}
+
+// Estimated reports whether Bytes is extrapolated from a subset of keys.
+func (s *PrefixStats) Estimated() bool { return s.Measured < s.Keys }
+
+// PreviewDir scans a folder and returns what a recursive delete or rename
+// of it would touch.
+func (m *Model) PreviewDir(dir string) (*PrefixStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	keys, err := m.scanKeysWithPrefix(ctx, withTrail(dir))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+
+	st := &PrefixStats{
+		Dir:    normPath(dir),
+		Keys:   len(keys),
+		ByType: map[string]int{},
+	}
+	if len(keys) > previewNames {
+		st.Sample = keys[:previewNames]
+	} else {
+		st.Sample = keys
+	}
+
+	for start := 0; start < len(keys); start += previewBatchSize {
+		end := min(start+previewBatchSize, len(keys))
+		batch := keys[start:end]
+
+		pipe := m.rdb.Pipeline()
+		types := make([]*redis.StatusCmd, len(batch))
+		mems := make([]*redis.IntCmd, len(batch))
+		for i, k := range batch {
+			types[i] = pipe.Type(ctx, k)
+			if start+i < previewMemLimit {
+				mems[i] = pipe.MemoryUsage(ctx, k)
+			}
+		}
+		// Per-command errors (e.g. MEMORY disabled by ACL, key expired
+		// meanwhile) only make the preview less precise.
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		for i := range batch {
+			if t, err := types[i].Result(); err == nil && t != "none" {
+				st.ByType[t]++
+			}
+			if mems[i] == nil {
+				continue
+			}
+			if n, err := mems[i].Result(); err == nil {
+				st.Bytes += n
+				st.Measured++
+			}
+		}
+	}
+
+	if st.Measured > 0 && st.Measured < st.Keys {
+		st.Bytes = st.Bytes * int64(st.Keys) / int64(st.Measured)
+	}
+	return st, nil
+}
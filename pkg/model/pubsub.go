@@ -0,0 +1,307 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/audit"
+)
+
+// SubKind selects the SUBSCRIBE family a Subscriber uses.
+type SubKind int
+
+const (
+	SubChannel SubKind = iota // SUBSCRIBE
+	SubPattern                // PSUBSCRIBE
+	SubShard                  // SSUBSCRIBE, Redis 7 and later
+)
+
+func (k SubKind) String() string {
+	switch k {
+	case SubPattern:
+		return "pattern"
+	case SubShard:
+		return "shard"
+	}
+	return "channel"
+}
+
+// PubSubEvent is one item received by a Subscriber: a message, a
+// subscription change or an error reply.
+type PubSubEvent struct {
+	Time    time.Time
+	Kind    string // message, pmessage, smessage, subscribe, ..., error
+	Channel string
+	Pattern string // pmessage only
+	Payload string
+	Count   int // subscriptions left on the connection, for (un)subscribe
+	Err     error
+}
+
+// ChannelInfo is an active channel and its subscriber count.
+type ChannelInfo struct {
+	Name        string
+	Subscribers int64
+	Shard       bool
+}
+
+// Subscriber is a dedicated pub/sub connection. Events are dropped, not
+// queued, when the consumer falls behind; Dropped counts them.
+type Subscriber struct {
+	ps      *redis.PubSub
+	events  chan PubSubEvent
+	done    chan struct{}
+	dropped atomic.Int64
+
+	mu   sync.Mutex
+	subs [3]map[string]bool // by SubKind
+}
+
+// NewSubscriber opens a pub/sub connection with no subscriptions.
+func (m *Model) NewSubscriber() *Subscriber {
+	s := &Subscriber{
+		ps:     m.rdb.Subscribe(context.Background()),
+		events: make(chan PubSubEvent, 1024),
+		done:   make(chan struct{}),
+	}
+	for i := range s.subs {
+		s.subs[i] = map[string]bool{}
+	}
+	go s.receive()
+	return s
+}
+
+func (s *Subscriber) receive() {
+	defer close(s.events)
+	for {
+		msg, err := s.ps.Receive(context.Background())
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+		ev := PubSubEvent{Time: time.Now()}
+		switch msg := msg.(type) {
+		case *redis.Message:
+			ev.Channel, ev.Pattern, ev.Payload = msg.Channel, msg.Pattern, msg.Payload
+			switch {
+			case msg.Pattern != "":
+				ev.Kind = "pmessage"
+			case s.has(SubShard, msg.Channel):
+				ev.Kind = "smessage"
+			default:
+				ev.Kind = "message"
+			}
+		case *redis.Subscription:
+			ev.Kind, ev.Channel, ev.Count = msg.Kind, msg.Channel, msg.Count
+		case *redis.Pong:
+			continue
+		default:
+			if err == nil {
+				continue
+			}
+			ev.Kind, ev.Err = "error", err
+			var rerr redis.Error
+			if !errors.As(err, &rerr) {
+				// connection trouble; go-redis reconnects and
+				// resubscribes on the next Receive
+				log.WithError(err).Warn("pub/sub receive failed")
+				time.Sleep(time.Second)
+			}
+		}
+		select {
+		case s.events <- ev:
+		case <-s.done:
+			return
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Events returns the event channel; it is closed by Close.
+func (s *Subscriber) Events() <-chan PubSubEvent { return s.events }
+
+// Dropped returns the number of events lost so far because the consumer
+// fell behind.
+func (s *Subscriber) Dropped() int64 { return s.dropped.Load() }
+
+// Subscribe adds channels (or patterns) of the given kind.
+func (s *Subscriber) Subscribe(kind SubKind, names ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var err error
+	switch kind {
+	case SubPattern:
+		err = s.ps.PSubscribe(ctx, names...)
+	case SubShard:
+		err = s.ps.SSubscribe(ctx, names...)
+	default:
+		err = s.ps.Subscribe(ctx, names...)
+	}
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	for _, n := range names {
+		s.subs[kind][n] = true
+	}
+	s.mu.Unlock()
+	log.WithFields(log.Fields{"kind": kind.String(), "names": names}).Debug("subscribed")
+	return nil
+}
+
+// Unsubscribe removes channels of the given kind; with no names it
+// removes every subscription of that kind.
+func (s *Subscriber) Unsubscribe(kind SubKind, names ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var err error
+	switch kind {
+	case SubPattern:
+		err = s.ps.PUnsubscribe(ctx, names...)
+	case SubShard:
+		err = s.ps.SUnsubscribe(ctx, names...)
+	default:
+		err = s.ps.Unsubscribe(ctx, names...)
+	}
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if len(names) == 0 {
+		s.subs[kind] = map[string]bool{}
+	}
+	for _, n := range names {
+		delete(s.subs[kind], n)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// UnsubscribeAll drops every subscription of every kind.
+func (s *Subscriber) UnsubscribeAll() error {
+	for _, kind := range []SubKind{SubChannel, SubPattern, SubShard} {
+		if len(s.Subscriptions(kind)) == 0 {
+			continue
+		}
+		if err := s.Unsubscribe(kind); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscriptions returns the sorted names subscribed with kind.
+func (s *Subscriber) Subscriptions(kind SubKind) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]string, 0, len(s.subs[kind]))
+	for n := range s.subs[kind] {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (s *Subscriber) has(kind SubKind, name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.subs[kind][name]
+}
+
+// Close unsubscribes and closes the connection.
+func (s *Subscriber) Close() error {
+	close(s.done)
+	return s.ps.Close()
+}
+
+// Publish sends msg to channel with PUBLISH, or SPUBLISH if shard is
+// set, and returns the number of clients that received it.
+func (m *Model) Publish(channel, msg string, shard bool) (int64, error) {
+	if m.readOnly {
+		return 0, ErrReadOnly
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	op := "publish"
+	var (
+		n   int64
+		err error
+	)
+	if shard {
+		op = "spublish"
+		n, err = m.rdb.SPublish(ctx, channel, msg).Result()
+	} else {
+		n, err = m.rdb.Publish(ctx, channel, msg).Result()
+	}
+	m.record(audit.Entry{
+		Op:    op,
+		Keys:  []string{channel},
+		New:   audit.Digest(msg),
+		Count: int(n),
+	}, err)
+	return n, err
+}
+
+// PubSubChannels lists the active channels matching pattern ("" for all)
+// with their subscriber counts, sharded channels included where the
+// server supports them. It also returns the number of pattern
+// subscriptions (PUBSUB NUMPAT).
+func (m *Model) PubSubChannels(pattern string) ([]ChannelInfo, int64, error) {
+	if pattern == "" {
+		pattern = "*"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var out []ChannelInfo
+	list := func(shard bool) error {
+		var (
+			names []string
+			err   error
+		)
+		if shard {
+			names, err = m.rdb.PubSubShardChannels(ctx, pattern).Result()
+		} else {
+			names, err = m.rdb.PubSubChannels(ctx, pattern).Result()
+		}
+		if err != nil || len(names) == 0 {
+			return err
+		}
+		var counts map[string]int64
+		if shard {
+			counts, err = m.rdb.PubSubShardNumSub(ctx, names...).Result()
+		} else {
+			counts, err = m.rdb.PubSubNumSub(ctx, names...).Result()
+		}
+		if err != nil {
+			return err
+		}
+		for _, n := range names {
+			out = append(out, ChannelInfo{Name: n, Subscribers: counts[n], Shard: shard})
+		}
+		return nil
+	}
+	if err := list(false); err != nil {
+		return nil, 0, fmt.Errorf("PUBSUB CHANNELS: %w", err)
+	}
+	if err := list(true); err != nil {
+		// PUBSUB SHARDCHANNELS is Redis 7+
+		log.WithError(err).Debug("PUBSUB SHARDCHANNELS unavailable")
+	}
+	numPat, err := m.rdb.PubSubNumPat(ctx).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("PUBSUB NUMPAT: %w", err)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, numPat, nil
+}
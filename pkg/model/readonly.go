@@ -0,0 +1,147 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrReadOnly is returned by every mutating call in read-only mode.
+var ErrReadOnly = errors.New("read-only mode: writes are disabled")
+
+// deniedCommands are refused in read-only mode even though COMMAND does
+// not flag them as writes: they run arbitrary code or change server state.
+// Entries are "command" or "command|subcommand".
+var deniedCommands = map[string]bool{
+	"eval": true, "evalsha": true, "fcall": true,
+	"script|flush": true, "script|load": true, "script|kill": true,
+	"function|load": true, "function|delete": true, "function|flush": true, "function|restore": true, "function|kill": true,
+	"config|set": true, "config|rewrite": true, "config|resetstat": true,
+	"client|kill": true, "client|pause": true, "client|unpause": true,
+	"slowlog|reset": true, "latency|reset": true, "memory|purge": true,
+	"acl|setuser": true, "acl|deluser": true, "acl|save": true, "acl|load": true,
+	"shutdown": true, "debug": true, "save": true, "bgsave": true, "bgrewriteaof": true,
+	"replicaof": true, "slaveof": true, "failover": true, "module": true,
+	"publish": true, "spublish": true,
+}
+
+// readOnlyGuard is a go-redis hook that refuses write commands, so that
+// read-only mode holds for every code path that uses the connection.
+type readOnlyGuard struct {
+	writes map[string]bool // commands flagged "write" or "may_replicate"
+}
+
+func newReadOnlyGuard(ctx context.Context, rdb *redis.Client) (*readOnlyGuard, error) {
+	writes, err := writeCommands(ctx, rdb)
+	if err != nil {
+		// Without COMMAND we cannot classify commands; refuse to start
+		// rather than silently allowing writes.
+		return nil, fmt.Errorf("read-only mode: COMMAND failed: %w", err)
+	}
+	log.WithField("write_commands", len(writes)).Debug("read-only guard installed")
+	return &readOnlyGuard{writes: writes}, nil
+}
+
+// writeCommands returns the lower-case names of the commands the server
+// flags "write" or "may_replicate".
+func writeCommands(ctx context.Context, rdb *redis.Client) (map[string]bool, error) {
+	infos, err := rdb.Command(ctx).Result()
+	if err != nil {
+		return nil, err
+	}
+	writes := map[string]bool{}
+	for name, info := range infos {
+		for _, f := range info.Flags {
+			if f == "write" || f == "may_replicate" {
+				writes[strings.ToLower(name)] = true
+				break
+			}
+		}
+	}
+	return writes, nil
+}
+
+// refusedName returns the command (and subcommand) in upper case if
+// read-only mode refuses it, or "" if it is allowed.
+func refusedName(writes map[string]bool, name, sub string) string {
+	name = strings.ToLower(name)
+	if writes[name] || deniedCommands[name] {
+		return strings.ToUpper(name)
+	}
+	if sub != "" && deniedCommands[name+"|"+strings.ToLower(sub)] {
+		return strings.ToUpper(name + " " + sub)
+	}
+	return ""
+}
+
+func (g *readOnlyGuard) check(cmd redis.Cmder) error {
+	var sub string
+	if args := cmd.Args(); len(args) > 1 {
+		sub, _ = args[1].(string)
+	}
+	if name := refusedName(g.writes, cmd.Name(), sub); name != "" {
+		return fmt.Errorf("%w (%s)", ErrReadOnly, name)
+	}
+	return nil
+}
+
+func (g *readOnlyGuard) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (g *readOnlyGuard) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := g.check(cmd); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (g *readOnlyGuard) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			if err := g.check(cmd); err != nil {
+				cmd.SetErr(err)
+				return err
+			}
+		}
+		return next(ctx, cmds)
+	}
+}
+
+// readOnlyOnConnect enables reads from cluster replicas. Standalone
+// servers reject READONLY; that is harmless, a plain replica already
+// serves reads.
+func readOnlyOnConnect(ctx context.Context, cn *redis.Conn) error {
+	if err := cn.ReadOnly(ctx).Err(); err != nil {
+		log.WithError(err).Debug("READONLY not accepted; continuing")
+	}
+	return nil
+}
+
+// Role returns the replication role reported by INFO replication
+// ("master" or "slave"), or "" if it cannot be determined.
+func (m *Model) Role() string {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	info, err := m.rdb.Info(ctx, "replication").Result()
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(info, "\n") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "role:"); ok {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,154 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/audit"
+)
+
+// scriptTimeout bounds one script or function call. Redis itself stops
+// answering while a script runs, so long scripts should be avoided anyway.
+const scriptTimeout = 60 * time.Second
+
+// FunctionLibrary is one Redis 7 function library from FUNCTION LIST.
+type FunctionLibrary struct {
+	Name      string
+	Engine    string
+	Code      string
+	Functions []FunctionInfo
+}
+
+// FunctionInfo is one function of a library.
+type FunctionInfo struct {
+	Name        string
+	Description string
+	Flags       []string // e.g. no-writes, allow-stale
+}
+
+// RunScript runs a Lua script with EVALSHA, falling back to EVAL when the
+// server does not have it cached. With readOnly set, EVALSHA_RO/EVAL_RO
+// are used instead (Redis 7+); those are the only variants allowed in
+// read-only mode. The reply is returned as by Exec.
+func (m *Model) RunScript(script string, keys, args []string, readOnly bool) (interface{}, error) {
+	if m.readOnly && !readOnly {
+		return nil, ErrReadOnly
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), scriptTimeout)
+	defer cancel()
+	s := redis.NewScript(script)
+	var cmd *redis.Cmd
+	if readOnly {
+		cmd = s.RunRO(ctx, m.rdb, keys, stringArgs(args)...)
+	} else {
+		cmd = s.Run(ctx, m.rdb, keys, stringArgs(args)...)
+	}
+	res, err := cmd.Result()
+	if errors.Is(err, redis.Nil) {
+		res, err = nil, nil
+	}
+	if !readOnly {
+		m.record(audit.Entry{Op: "eval", Keys: keys, Target: s.Hash(), New: audit.Digest(script)}, err)
+	}
+	log.WithFields(log.Fields{"op": "eval", "sha": s.Hash(), "keys": len(keys), "read_only": readOnly}).Debug("ran script")
+	return res, err
+}
+
+// CallFunction calls a Redis 7 function with FCALL, or FCALL_RO if
+// readOnly is set.
+func (m *Model) CallFunction(name string, keys, args []string, readOnly bool) (interface{}, error) {
+	if m.readOnly && !readOnly {
+		return nil, ErrReadOnly
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), scriptTimeout)
+	defer cancel()
+	var cmd *redis.Cmd
+	if readOnly {
+		cmd = m.rdb.FCallRO(ctx, name, keys, stringArgs(args)...)
+	} else {
+		cmd = m.rdb.FCall(ctx, name, keys, stringArgs(args)...)
+	}
+	res, err := cmd.Result()
+	if errors.Is(err, redis.Nil) {
+		res, err = nil, nil
+	}
+	if !readOnly {
+		m.record(audit.Entry{Op: "fcall", Keys: keys, Target: name}, err)
+	}
+	return res, err
+}
+
+// Functions returns the loaded function libraries with their code.
+func (m *Model) Functions() ([]FunctionLibrary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	libs, err := m.rdb.FunctionList(ctx, redis.FunctionListQuery{WithCode: true}).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]FunctionLibrary, len(libs))
+	for i, l := range libs {
+		out[i] = FunctionLibrary{Name: l.Name, Engine: l.Engine, Code: l.Code}
+		for _, f := range l.Functions {
+			out[i].Functions = append(out[i].Functions, FunctionInfo{
+				Name:        f.Name,
+				Description: f.Description,
+				Flags:       f.Flags,
+			})
+		}
+	}
+	return out, nil
+}
+
+// LoadFunctions loads a library with FUNCTION LOAD, replacing an existing
+// library of the same name if replace is set. It returns the library name.
+func (m *Model) LoadFunctions(code string, replace bool) (string, error) {
+	if m.readOnly {
+		return "", ErrReadOnly
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var (
+		name string
+		err  error
+	)
+	if replace {
+		name, err = m.rdb.FunctionLoadReplace(ctx, code).Result()
+	} else {
+		name, err = m.rdb.FunctionLoad(ctx, code).Result()
+	}
+	m.record(audit.Entry{Op: "function-load", Target: name, New: audit.Digest(code)}, err)
+	return name, err
+}
+
+// DeleteFunctions removes a library with FUNCTION DELETE.
+func (m *Model) DeleteFunctions(library string) error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var old *audit.Value
+	if libs, err := m.Functions(); err == nil {
+		for _, l := range libs {
+			if l.Name == library {
+				old = audit.Digest(l.Code)
+			}
+		}
+	}
+	err := m.rdb.FunctionDelete(ctx, library).Err()
+	m.record(audit.Entry{Op: "function-delete", Target: library, Old: old}, err)
+	return err
+}
+
+func stringArgs(args []string) []interface{} {
+	out := make([]interface{}, len(args))
+	for i, a := range args {
+		out[i] = a
+	}
+	return out
+}
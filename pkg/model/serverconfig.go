@@ -0,0 +1,72 @@
+package model
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/nexusriot/redis-walker/pkg/audit"
+)
+
+// ServerConfig returns every server parameter from CONFIG GET *.
+func (m *Model) ServerConfig() (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return m.rdb.ConfigGet(ctx, "*").Result()
+}
+
+// secretParameters hold passwords; see SecretParameter.
+var secretParameters = map[string]bool{
+	"requirepass":              true,
+	"masterauth":               true,
+	"masteruser":               true,
+	"tls-key-file-pass":        true,
+	"tls-client-key-file-pass": true,
+}
+
+// SecretParameter reports whether a server parameter holds a password,
+// whose value must not be written anywhere, not even as a digest.
+func SecretParameter(name string) bool {
+	return secretParameters[strings.ToLower(name)]
+}
+
+// SetServerConfig changes one parameter with CONFIG SET. The journal gets
+// digests of the old and new values, never the values; for a
+// SecretParameter it only gets the name, as an unsalted digest of a
+// password can be brute-forced.
+func (m *Model) SetServerConfig(name, value string) error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	secret := SecretParameter(name)
+	var before, after *audit.Value
+	if m.audit != nil && !secret {
+		if cur, err := m.rdb.ConfigGet(ctx, name).Result(); err == nil {
+			before = audit.Digest(cur[name])
+		}
+		after = audit.Digest(value)
+	}
+	err := m.rdb.ConfigSet(ctx, name, value).Err()
+	m.record(audit.Entry{
+		Op:     "config-set",
+		Target: name,
+		Old:    before,
+		New:    after,
+	}, err)
+	return err
+}
+
+// RewriteServerConfig persists the running configuration to the
+// server's config file with CONFIG REWRITE.
+func (m *Model) RewriteServerConfig() error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := m.rdb.ConfigRewrite(ctx).Err()
+	m.record(audit.Entry{Op: "config-rewrite"}, err)
+	return err
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/nexusriot/redis-walker/pkg/audit"
+)
+
+// SlowEntry is one SLOWLOG GET entry.
+type SlowEntry struct {
+	ID         int64
+	Time       time.Time
+	Duration   time.Duration
+	Args       []string
+	ClientAddr string
+	ClientName string
+}
+
+// SlowLog returns the newest n slow log entries, newest first.
+func (m *Model) SlowLog(n int64) ([]SlowEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	logs, err := m.rdb.SlowLogGet(ctx, n).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SlowEntry, len(logs))
+	for i, l := range logs {
+		out[i] = SlowEntry{
+			ID:         l.ID,
+			Time:       l.Time,
+			Duration:   l.Duration,
+			Args:       l.Args,
+			ClientAddr: l.ClientAddr,
+			ClientName: l.ClientName,
+		}
+	}
+	return out, nil
+}
+
+// ResetSlowLog empties the server's slow log.
+func (m *Model) ResetSlowLog() error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := m.rdb.SlowLogReset(ctx).Err()
+	m.record(audit.Entry{Op: "slowlog-reset"}, err)
+	return err
+}
+
+// CommandKeys returns the keys a command references, as reported by
+// COMMAND GETKEYS. If the server cannot tell (e.g. arguments truncated
+// by the slow log), the argument at the first key position from COMMAND
+// INFO is assumed to be the key; commands without a fixed key position
+// return nil.
+func (m *Model) CommandKeys(args []string) []string {
+	if len(args) < 2 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	iargs := make([]interface{}, len(args))
+	for i, a := range args {
+		iargs[i] = a
+	}
+	keys, err := m.rdb.CommandGetKeys(ctx, iargs...).Result()
+	if err == nil {
+		return keys
+	}
+	cmd := redis.NewCommandsInfoCmd(ctx, "command", "info", args[0])
+	_ = m.rdb.Process(ctx, cmd)
+	info, err := cmd.Result()
+	if err != nil {
+		return nil
+	}
+	ci := info[strings.ToLower(args[0])]
+	if ci == nil || ci.FirstKeyPos <= 0 || int(ci.FirstKeyPos) >= len(args) {
+		return nil
+	}
+	return args[ci.FirstKeyPos : ci.FirstKeyPos+1]
+}
@@ -0,0 +1,141 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyState is a point-in-time view of one key for the watch panel.
+type KeyState struct {
+	Key    string
+	Exists bool
+	Type   string
+	Value  string        // string value, or a size summary for other types
+	TTL    time.Duration // negative = no expiry
+}
+
+// Snapshot reads the type, TTL and value of keys in two pipelined round
+// trips. Non-string values are summarized by their length.
+func (m *Model) Snapshot(keys []string) ([]KeyState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out := make([]KeyState, len(keys))
+	pipe := m.rdb.Pipeline()
+	types := make([]*redis.StatusCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, k := range keys {
+		out[i].Key = k
+		types[i] = pipe.Type(ctx, k)
+		ttls[i] = pipe.PTTL(ctx, k)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	pipe = m.rdb.Pipeline()
+	vals := make([]redis.Cmder, len(keys))
+	for i, k := range keys {
+		t := types[i].Val()
+		if t == "" || t == "none" {
+			continue
+		}
+		out[i].Exists = true
+		out[i].Type = t
+		out[i].TTL = ttls[i].Val()
+		switch t {
+		case "string":
+			vals[i] = pipe.Get(ctx, k)
+		case "list":
+			vals[i] = pipe.LLen(ctx, k)
+		case "hash":
+			vals[i] = pipe.HLen(ctx, k)
+		case "set":
+			vals[i] = pipe.SCard(ctx, k)
+		case "zset":
+			vals[i] = pipe.ZCard(ctx, k)
+		case "stream":
+			vals[i] = pipe.XLen(ctx, k)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	for i, cmd := range vals {
+		switch c := cmd.(type) {
+		case *redis.StringCmd:
+			if v, err := c.Result(); err == nil {
+				out[i].Value = v
+			} else {
+				out[i].Exists = err != redis.Nil
+			}
+		case *redis.IntCmd:
+			out[i].Value = fmt.Sprintf("<%d %s>", c.Val(), lengthUnit(out[i].Type))
+		}
+	}
+	return out, nil
+}
+
+func lengthUnit(t string) string {
+	switch t {
+	case "hash":
+		return "fields"
+	case "list", "stream":
+		return "entries"
+	default:
+		return "members"
+	}
+}
+
+// Number reads a numeric value: the string value of key parsed as a
+// float, or, when member is set, the score of member in the zset key.
+func (m *Model) Number(key, member string) (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if member != "" {
+		v, err := m.rdb.ZScore(ctx, key, member).Result()
+		if err == redis.Nil {
+			return 0, fmt.Errorf("%s has no member %q", key, member)
+		}
+		if err != nil {
+			return 0, err
+		}
+		return finite(key, v)
+	}
+	s, err := m.rdb.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return 0, fmt.Errorf("%s does not exist", key)
+	}
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not numeric: %q", key, OneLine(s, 40))
+	}
+	return finite(key, v)
+}
+
+// finite rejects infinities and NaN, which cannot be plotted.
+func finite(key string, v float64) (float64, error) {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, fmt.Errorf("%s is not a finite number: %v", key, v)
+	}
+	return v, nil
+}
+
+// OneLine flattens a value to one line of at most width runes, showing
+// line breaks and tabs as escapes.
+func OneLine(s string, width int) string {
+	s = strings.NewReplacer("\r", `\r`, "\n", `\n`, "\t", `\t`).Replace(s)
+	if r := []rune(s); len(r) > width {
+		return string(r[:width-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,178 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/snapshot"
+)
+
+// SnapshotHeader describes a snapshot of dir from this connection.
+func (m *Model) SnapshotHeader(dir string) snapshot.Header {
+	h := snapshot.Header{
+		CreatedAt: time.Now().UTC(),
+		Server:    m.addr,
+		DB:        m.db,
+		Prefix:    normPath(dir),
+	}
+	if info, err := m.Info(); err == nil {
+		h.RedisVersion = info["redis_version"]
+	}
+	return h
+}
+
+// SnapshotDir reads the DUMP payload and TTL of every key under dir (the
+// whole database for "/"), except excluded ones, and passes each to fn.
+// Keys are found with SCAN and read in pipelined batches. It returns the
+// number of keys passed to fn.
+func (m *Model) SnapshotDir(ctx context.Context, dir string, fn func(*snapshot.Entry) error) (int, error) {
+	match := escapeGlob(withTrail(dir)) + "*"
+	seen := map[string]bool{} // SCAN may return a key more than once
+	var (
+		cursor uint64
+		n      int
+	)
+	for {
+		page, next, err := m.rdb.Scan(ctx, cursor, match, exportChunk).Result()
+		if err != nil {
+			return n, err
+		}
+		keys := page[:0]
+		for _, k := range page {
+			if !seen[k] && !m.shouldExclude(k) {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+		pipe := m.rdb.Pipeline()
+		dumps := make([]*redis.StringCmd, len(keys))
+		ttls := make([]*redis.DurationCmd, len(keys))
+		types := make([]*redis.StatusCmd, len(keys))
+		for i, k := range keys {
+			dumps[i] = pipe.Dump(ctx, k)
+			ttls[i] = pipe.PTTL(ctx, k)
+			types[i] = pipe.Type(ctx, k)
+		}
+		if len(keys) > 0 {
+			if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+				return n, err
+			}
+		}
+		for i, k := range keys {
+			payload, err := dumps[i].Result()
+			if err == redis.Nil {
+				continue // deleted while reading
+			}
+			if err != nil {
+				return n, fmt.Errorf("%s: %w", k, err)
+			}
+			e := &snapshot.Entry{Key: k, Type: types[i].Val(), Dump: []byte(payload)}
+			if ttl := ttls[i].Val(); ttl > 0 {
+				e.TTL = ttl.Milliseconds()
+			}
+			if err := fn(e); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	log.WithFields(log.Fields{"op": "snapshot", "dir": dir, "keys": n}).Info("snapshotted keys")
+	return n, nil
+}
+
+// RestoreSnapshot loads a snapshot into the database with RESTORE, in the
+// same two passes as Import: open is called once to check the snapshot
+// against the database and, unless this is a dry run, once more to
+// restore it. ConflictOverwrite restores with REPLACE after saving the
+// existing keys to the trash. TTLs count from now. A key that fails is
+// recorded in the result and the restore goes on.
+func (m *Model) RestoreSnapshot(ctx context.Context, open func() (*snapshot.Reader, error), opts ImportOptions, progress func(done, total int)) (*ImportResult, error) {
+	return m.load(ctx, "snapshot-restore", "restored", opts, progress, func(fn loadFunc, fail func(ImportFailure)) error {
+		return m.restorePass(ctx, open, opts, fn, fail)
+	})
+}
+
+// restorePass reads the snapshot in batches and passes each batch to fn.
+// Entries that cannot be remapped are passed to fail.
+func (m *Model) restorePass(ctx context.Context, open func() (*snapshot.Reader, error), opts ImportOptions, fn loadFunc, fail func(ImportFailure)) error {
+	rd, err := open()
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+
+	keyFor := remapper(opts, rd.Header().Prefix)
+	var (
+		batch []*snapshot.Entry
+		types []string
+		keys  []string
+	)
+	flush := func() error {
+		return fn(types, keys, func(at []int, replace []bool) []error {
+			return m.restoreEntries(ctx, batch, keys, at, replace)
+		})
+	}
+	for n := 1; ; n++ {
+		e, err := rd.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("entry #%d: %w", n, err)
+		}
+		k, err := keyFor(e.Key)
+		if err != nil {
+			fail(ImportFailure{Key: e.Key, Err: err})
+			continue
+		}
+		batch, types, keys = append(batch, e), append(types, e.Type), append(keys, k)
+		if len(batch) == previewBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+			batch, types, keys = batch[:0], types[:0], keys[:0]
+		}
+	}
+	if len(batch) > 0 {
+		return flush()
+	}
+	return nil
+}
+
+// restoreEntries restores the entries of batch at the positions in at.
+func (m *Model) restoreEntries(ctx context.Context, batch []*snapshot.Entry, keys []string, at []int, replace []bool) []error {
+	pipe := m.rdb.Pipeline()
+	cmds := make([]*redis.StatusCmd, len(at))
+	for j, i := range at {
+		ttl := time.Duration(batch[i].TTL) * time.Millisecond
+		if replace[j] {
+			cmds[j] = pipe.RestoreReplace(ctx, keys[i], ttl, string(batch[i].Dump))
+		} else {
+			cmds[j] = pipe.Restore(ctx, keys[i], ttl, string(batch[i].Dump))
+		}
+	}
+	_, _ = pipe.Exec(ctx) // per-command errors are collected below
+	errs := make([]error, len(at))
+	for j, cmd := range cmds {
+		if errs[j] = cmd.Err(); errs[j] != nil && isBusyKey(errs[j]) {
+			errs[j] = ErrTargetExists // created since the check
+		}
+	}
+	return errs
+}
+
+// isBusyKey reports whether RESTORE failed because the key exists.
+func isBusyKey(err error) bool {
+	return strings.HasPrefix(err.Error(), "BUSYKEY")
+}
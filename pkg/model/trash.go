@@ -0,0 +1,284 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nexusriot/redis-walker/pkg/audit"
+	"github.com/nexusriot/redis-walker/pkg/trash"
+)
+
+// DefaultTrashMaxBytes caps the DUMP payload captured for one operation.
+// It can be raised up to trash.MaxOpBytes.
+const DefaultTrashMaxBytes = 256 << 20
+
+// ErrTrashTooLarge is returned when an operation would capture more than
+// the configured trash limit; nothing has been changed in that case.
+var ErrTrashTooLarge = errors.New("operation exceeds the trash size limit")
+
+// ErrNothingToUndo is returned by Undo when the trash holds no operation
+// for the current server and database.
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// Trash returns the trash store, or nil if the trash is disabled.
+func (m *Model) Trash() *trash.Store { return m.trash }
+
+// Server returns the address the model is connected to.
+func (m *Model) Server() string { return m.addr }
+
+// DB returns the selected database index.
+func (m *Model) DB() int { return m.db }
+
+// saveToTrash DUMPs keys and records them as one operation before they
+// are deleted or overwritten. Keys that no longer exist are skipped. An
+// operation that captures nothing, such as creating a key, is recorded
+// too, so that Undo does not reach past it to an older one. It returns
+// nil if the trash is disabled.
+func (m *Model) saveToTrash(ctx context.Context, kind, dir string, keys []string) (*trash.Op, error) {
+	return m.capture(ctx, &trash.Op{Kind: kind, Dir: dir}, keys)
+}
+
+// capture fills op with the DUMP of keys and adds it to the trash; see
+// saveToTrash.
+func (m *Model) capture(ctx context.Context, op *trash.Op, keys []string) (*trash.Op, error) {
+	if m.trash == nil {
+		return nil, nil
+	}
+	op.Server, op.DB = m.addr, m.db
+	limit := m.trashMaxBytes
+	if limit <= 0 || limit > trash.MaxOpBytes {
+		limit = trash.MaxOpBytes
+	}
+	var total int64
+	for start := 0; start < len(keys); start += previewBatchSize {
+		batch := keys[start:min(start+previewBatchSize, len(keys))]
+		pipe := m.rdb.Pipeline()
+		dumps := make([]*redis.StringCmd, len(batch))
+		ttls := make([]*redis.DurationCmd, len(batch))
+		types := make([]*redis.StatusCmd, len(batch))
+		for i, k := range batch {
+			dumps[i] = pipe.Dump(ctx, k)
+			ttls[i] = pipe.PTTL(ctx, k)
+			types[i] = pipe.Type(ctx, k)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("trash capture: %w", err)
+		}
+		for i, k := range batch {
+			payload, err := dumps[i].Result()
+			if err == redis.Nil {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("trash capture %s: %w", k, err)
+			}
+			total += int64(len(payload))
+			if total > limit {
+				return nil, fmt.Errorf("%w (%d MiB)", ErrTrashTooLarge, limit>>20)
+			}
+			it := trash.Item{Key: k, Type: types[i].Val(), Dump: []byte(payload)}
+			if ttl := ttls[i].Val(); ttl > 0 {
+				it.TTL = ttl.Milliseconds()
+			}
+			op.Items = append(op.Items, it)
+		}
+	}
+	if err := m.trash.Add(op); err != nil {
+		return nil, fmt.Errorf("save to trash: %w", err)
+	}
+	log.WithFields(log.Fields{
+		"op":    "trash",
+		"kind":  op.Kind,
+		"id":    op.ID,
+		"keys":  len(op.Items),
+		"bytes": total,
+	}).Debug("captured keys to trash")
+	return op, nil
+}
+
+// dropFromTrash removes an op whose mutation failed, so the trash only
+// holds changes that actually happened.
+func (m *Model) dropFromTrash(op *trash.Op) {
+	if op == nil {
+		return
+	}
+	if err := m.trash.Remove(op.ID); err != nil {
+		log.WithError(err).WithField("id", op.ID).Warn("failed to drop trash entry")
+	}
+}
+
+// Undo restores the newest trash operation for this server and database
+// to its original keys, replacing whatever is there now; what it replaces
+// goes to the trash in turn. A rename is moved back. If that operation
+// captured nothing it is dropped and ErrNothingToUndo returned.
+func (m *Model) Undo() (*trash.Op, error) {
+	if m.readOnly {
+		return nil, ErrReadOnly
+	}
+	if m.trash == nil {
+		return nil, ErrNothingToUndo
+	}
+	op, ok := m.trash.Last(m.addr, m.db)
+	if !ok {
+		return nil, ErrNothingToUndo
+	}
+	if op.Keys == 0 {
+		m.dropFromTrash(&op)
+		return nil, fmt.Errorf("%w: the last operation (%s) only created keys", ErrNothingToUndo, op.Kind)
+	}
+	if _, err := m.RestoreFromTrash(op.ID, "", true); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+// RestoreFromTrash restores a trash operation. An empty target restores
+// to the original keys, and moves a rename back by deleting the keys it
+// created; otherwise target is the new key (single-key ops) or the new
+// folder the operation's keys are remapped into. Keys that are replaced
+// or deleted are captured to the trash first, so a restore can be undone
+// too. Keys whose TTL ran out since capture are skipped. On full success
+// the entry is removed from the trash. It returns the number of restored
+// keys.
+func (m *Model) RestoreFromTrash(id, target string, replace bool) (int, error) {
+	if m.readOnly {
+		return 0, ErrReadOnly
+	}
+	if m.trash == nil {
+		return 0, fmt.Errorf("trash is disabled")
+	}
+	op, err := m.trash.Load(id)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	dir := op.Dir
+	keyFor := func(k string) string { return k }
+	if target != "" {
+		switch {
+		case op.Dir != "":
+			oldPfx, newPfx := withTrail(op.Dir), withTrail(target)
+			keyFor = func(k string) string { return newPfx + strings.TrimPrefix(k, oldPfx) }
+			dir = normPath(target)
+		case len(op.Items) == 1:
+			t := normPath(target)
+			keyFor = func(string) string { return t }
+		default:
+			return 0, fmt.Errorf("cannot remap a multi-key entry without a folder")
+		}
+	}
+	// moved[i] is where a rename put op.Items[i].
+	var moved []string
+	if target == "" && op.Kind == "rename" && op.Target != "" {
+		oldPfx, newPfx := withTrail(op.Dir), withTrail(op.Target)
+		for _, it := range op.Items {
+			moved = append(moved, newPfx+strings.TrimPrefix(it.Key, oldPfx))
+		}
+	}
+
+	var doomed []string // keys this restore replaces or deletes
+	if replace {
+		for _, it := range op.Items {
+			doomed = append(doomed, keyFor(it.Key))
+		}
+	}
+	doomed = append(doomed, moved...)
+	var saved *trash.Op
+	if len(doomed) > 0 {
+		if saved, err = m.saveToTrash(ctx, "restore", dir, doomed); err != nil {
+			return 0, err
+		}
+		if saved != nil && len(saved.Items) == 0 {
+			// nothing is replaced; no need to stop Undo here
+			m.dropFromTrash(saved)
+			saved = nil
+		}
+	}
+
+	elapsed := time.Since(op.Time)
+	var (
+		restored int
+		failures []string
+		written  []string
+		unmove   []string // moved keys whose originals are back
+	)
+	for start := 0; start < len(op.Items); start += previewBatchSize {
+		batch := op.Items[start:min(start+previewBatchSize, len(op.Items))]
+		pipe := m.rdb.Pipeline()
+		cmds := make([]*redis.StatusCmd, len(batch))
+		for i, it := range batch {
+			var ttl time.Duration
+			if it.TTL > 0 {
+				ttl = time.Duration(it.TTL)*time.Millisecond - elapsed
+				if ttl <= 0 {
+					continue // would have expired by now
+				}
+			}
+			if replace {
+				cmds[i] = pipe.RestoreReplace(ctx, keyFor(it.Key), ttl, string(it.Dump))
+			} else {
+				cmds[i] = pipe.Restore(ctx, keyFor(it.Key), ttl, string(it.Dump))
+			}
+		}
+		_, _ = pipe.Exec(ctx) // per-command errors are collected below
+		for i, cmd := range cmds {
+			if cmd == nil {
+				continue
+			}
+			if err := cmd.Err(); err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", keyFor(batch[i].Key), err))
+				continue
+			}
+			restored++
+			written = append(written, keyFor(batch[i].Key))
+			if moved != nil {
+				unmove = append(unmove, moved[start+i])
+			}
+		}
+	}
+	if len(unmove) > 0 {
+		if err := m.rdb.Del(ctx, unmove...).Err(); err != nil {
+			failures = append(failures, fmt.Sprintf("removing the renamed keys under %s: %v", op.Target, err))
+		} else {
+			written = append(written, unmove...)
+		}
+	}
+	if restored == 0 {
+		m.dropFromTrash(saved)
+	}
+
+	log.WithFields(log.Fields{
+		"op":       "restore",
+		"id":       id,
+		"target":   target,
+		"restored": restored,
+		"failed":   len(failures),
+	}).Info("restore from trash")
+
+	var restoreErr error
+	if len(failures) > 0 {
+		const show = 5
+		msg := strings.Join(failures[:min(show, len(failures))], "; ")
+		if len(failures) > show {
+			msg += fmt.Sprintf("; ... %d more", len(failures)-show)
+		}
+		restoreErr = fmt.Errorf("%d of %d keys not restored: %s", len(failures), len(op.Items), msg)
+	}
+	m.record(audit.Entry{Op: "restore", Dir: op.Dir, Target: target, Keys: written}, restoreErr)
+	if restoreErr != nil {
+		return restored, restoreErr
+	}
+	if err := m.trash.Remove(id); err != nil {
+		return restored, err
+	}
+	return restored, nil
+}
@@ -0,0 +1,316 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var errTruncated = errors.New("encoded value is truncated")
+
+// ziplist decodes a ziplist (Redis < 7) into its entries.
+func ziplist(b []byte) ([]string, error) {
+	if len(b) < 11 {
+		return nil, errTruncated
+	}
+	n := int(binary.LittleEndian.Uint16(b[8:10]))
+	out := make([]string, 0, n)
+	i := 10
+	for {
+		if i >= len(b) {
+			return nil, errTruncated
+		}
+		if b[i] == 0xff {
+			return out, nil
+		}
+		// previous entry length: 1 byte, or 0xfe and 4 bytes
+		if b[i] == 0xfe {
+			i += 5
+		} else {
+			i++
+		}
+		if i >= len(b) {
+			return nil, errTruncated
+		}
+		enc := b[i]
+		var (
+			size int   // string length, or -1 for an integer
+			v    int64 // integer value
+			head int   // encoding bytes
+		)
+		switch {
+		case enc>>6 == 0:
+			size, head = int(enc&0x3f), 1
+		case enc>>6 == 1:
+			if i+1 >= len(b) {
+				return nil, errTruncated
+			}
+			size, head = int(enc&0x3f)<<8|int(b[i+1]), 2
+		case enc == 0x80:
+			if i+5 > len(b) {
+				return nil, errTruncated
+			}
+			size, head = int(binary.BigEndian.Uint32(b[i+1:])), 5
+		default:
+			size, head = -1, 1
+			var w int
+			switch enc {
+			case 0xc0:
+				w = 2
+			case 0xd0:
+				w = 4
+			case 0xe0:
+				w = 8
+			case 0xf0:
+				w = 3
+			case 0xfe:
+				w = 1
+			default:
+				if enc < 0xf1 || enc > 0xfd {
+					return nil, fmt.Errorf("bad ziplist encoding 0x%02x", enc)
+				}
+				v = int64(enc&0x0f) - 1 // immediate 0..12
+			}
+			if i+1+w > len(b) {
+				return nil, errTruncated
+			}
+			if w > 0 {
+				v = leInt(b[i+1 : i+1+w])
+			}
+			head += w
+		}
+		i += head
+		if size < 0 {
+			out = append(out, strconv.FormatInt(v, 10))
+			continue
+		}
+		if i+size > len(b) {
+			return nil, errTruncated
+		}
+		out = append(out, string(b[i:i+size]))
+		i += size
+	}
+}
+
+// listpack decodes a listpack (Redis 7+) into its entries.
+func listpack(b []byte) ([]string, error) {
+	if len(b) < 7 {
+		return nil, errTruncated
+	}
+	var out []string
+	i := 6
+	for {
+		if i >= len(b) {
+			return nil, errTruncated
+		}
+		enc := b[i]
+		if enc == 0xff {
+			return out, nil
+		}
+		var (
+			size = -1 // string length, or -1 for an integer
+			v    int64
+			head int
+		)
+		need := func(n int) bool { return i+n <= len(b) }
+		switch {
+		case enc&0x80 == 0: // 7-bit unsigned
+			v, head = int64(enc), 1
+		case enc&0xc0 == 0x80: // 6-bit string length
+			size, head = int(enc&0x3f), 1
+		case enc&0xe0 == 0xc0: // 13-bit signed
+			if !need(2) {
+				return nil, errTruncated
+			}
+			u := int64(enc&0x1f)<<8 | int64(b[i+1])
+			if u >= 1<<12 {
+				u -= 1 << 13
+			}
+			v, head = u, 2
+		case enc&0xf0 == 0xe0: // 12-bit string length
+			if !need(2) {
+				return nil, errTruncated
+			}
+			size, head = int(enc&0x0f)<<8|int(b[i+1]), 2
+		case enc == 0xf0: // 32-bit string length
+			if !need(5) {
+				return nil, errTruncated
+			}
+			size, head = int(binary.LittleEndian.Uint32(b[i+1:])), 5
+		default:
+			var w int
+			switch enc {
+			case 0xf1:
+				w = 2
+			case 0xf2:
+				w = 3
+			case 0xf3:
+				w = 4
+			case 0xf4:
+				w = 8
+			default:
+				return nil, fmt.Errorf("bad listpack encoding 0x%02x", enc)
+			}
+			if !need(1 + w) {
+				return nil, errTruncated
+			}
+			v, head = leInt(b[i+1:i+1+w]), 1+w
+		}
+		entry := head
+		if size >= 0 {
+			if !need(head + size) {
+				return nil, errTruncated
+			}
+			out = append(out, string(b[i+head:i+head+size]))
+			entry += size
+		} else {
+			out = append(out, strconv.FormatInt(v, 10))
+		}
+		i += entry + backlenSize(entry)
+	}
+}
+
+// backlenSize is the size of the back-length that follows a listpack
+// entry of n bytes, with the bounds of Redis' lpEncodeBacklen.
+func backlenSize(n int) int {
+	switch {
+	case n <= 127:
+		return 1
+	case n < 16383:
+		return 2
+	case n < 2097151:
+		return 3
+	case n < 268435455:
+		return 4
+	}
+	return 5
+}
+
+// leInt reads a little-endian signed integer of 1 to 8 bytes.
+func leInt(b []byte) int64 {
+	var u uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		u = u<<8 | uint64(b[i])
+	}
+	shift := 64 - 8*uint(len(b))
+	return int64(u<<shift) >> shift
+}
+
+// intset decodes an intset into its members.
+func intset(b []byte) ([]string, error) {
+	if len(b) < 8 {
+		return nil, errTruncated
+	}
+	w := int(binary.LittleEndian.Uint32(b[0:4]))
+	n := int(binary.LittleEndian.Uint32(b[4:8]))
+	if w != 2 && w != 4 && w != 8 {
+		return nil, fmt.Errorf("bad intset width %d", w)
+	}
+	if len(b) < 8+n*w {
+		return nil, errTruncated
+	}
+	out := make([]string, n)
+	for i := range out {
+		out[i] = strconv.FormatInt(leInt(b[8+i*w:8+(i+1)*w]), 10)
+	}
+	return out, nil
+}
+
+// zipmap decodes a zipmap (Redis < 2.6 hashes) into field/value pairs.
+func zipmap(b []byte) ([]string, error) {
+	var out []string
+	i := 1 // entry count, unreliable beyond 253
+	readLen := func() (int, bool) {
+		if i >= len(b) || b[i] == 0xff {
+			return 0, false
+		}
+		if b[i] < 254 {
+			i++
+			return int(b[i-1]), true
+		}
+		if i+5 > len(b) {
+			return 0, false
+		}
+		n := int(binary.LittleEndian.Uint32(b[i+1:]))
+		i += 5
+		return n, true
+	}
+	for {
+		klen, ok := readLen()
+		if !ok {
+			break
+		}
+		if i+klen > len(b) {
+			return nil, errTruncated
+		}
+		key := string(b[i : i+klen])
+		i += klen
+		vlen, ok := readLen()
+		if !ok || i >= len(b) {
+			return nil, errTruncated
+		}
+		free := int(b[i])
+		i++
+		if i+vlen > len(b) {
+			return nil, errTruncated
+		}
+		out = append(out, key, string(b[i:i+vlen]))
+		i += vlen + free
+	}
+	return out, nil
+}
+
+// lzfMaxRatio bounds how far LZF expands: a 3-byte back reference
+// yields at most 264 bytes.
+const lzfMaxRatio = 88
+
+// lzfDecompress expands LZF-compressed data to n bytes.
+func lzfDecompress(in []byte, n int) ([]byte, error) {
+	if n < 0 || n > lzfMaxRatio*len(in) {
+		return nil, fmt.Errorf("LZF data of %d bytes cannot expand to %d", len(in), n)
+	}
+	out := make([]byte, 0, n)
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+		if ctrl < 32 { // literal run of ctrl+1 bytes
+			ctrl++
+			if i+ctrl > len(in) {
+				return nil, errTruncated
+			}
+			if len(out)+ctrl > n {
+				return nil, fmt.Errorf("LZF data expands past %d bytes", n)
+			}
+			out = append(out, in[i:i+ctrl]...)
+			i += ctrl
+			continue
+		}
+		length := ctrl >> 5
+		if length == 7 {
+			if i >= len(in) {
+				return nil, errTruncated
+			}
+			length += int(in[i])
+			i++
+		}
+		if i >= len(in) {
+			return nil, errTruncated
+		}
+		ref := len(out) - (ctrl&0x1f)<<8 - int(in[i]) - 1
+		i++
+		if ref < 0 {
+			return nil, fmt.Errorf("bad LZF back reference")
+		}
+		if len(out)+length+2 > n {
+			return nil, fmt.Errorf("LZF data expands past %d bytes", n)
+		}
+		for j := 0; j < length+2; j++ { // may overlap the output
+			out = append(out, out[ref+j])
+		}
+	}
+	if len(out) != n {
+		return nil, fmt.Errorf("LZF data expands to %d bytes, want %d", len(out), n)
+	}
+	return out, nil
+}
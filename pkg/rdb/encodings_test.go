@@ -0,0 +1,229 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lp builds a listpack from raw entries (encoding and data), adding the
+// back-lengths, header and terminator.
+func lp(entries ...[]byte) []byte {
+	var body []byte
+	for _, e := range entries {
+		body = append(body, e...)
+		body = append(body, backlen(len(e))...)
+	}
+	body = append(body, 0xff)
+	out := binary.LittleEndian.AppendUint32(nil, uint32(6+len(body)))
+	out = binary.LittleEndian.AppendUint16(out, uint16(len(entries)))
+	return append(out, body...)
+}
+
+// backlen is Redis' lpEncodeBacklen.
+func backlen(l int) []byte {
+	switch {
+	case l <= 127:
+		return []byte{byte(l)}
+	case l < 16383:
+		return []byte{byte(l >> 7), byte(l&127) | 128}
+	case l < 2097151:
+		return []byte{byte(l >> 14), byte((l>>7)&127) | 128, byte(l&127) | 128}
+	}
+	return []byte{byte(l >> 21), byte((l>>14)&127) | 128, byte((l>>7)&127) | 128, byte(l&127) | 128}
+}
+
+func lpStr(s string) []byte {
+	n := len(s)
+	switch {
+	case n < 64:
+		return append([]byte{0x80 | byte(n)}, s...)
+	case n < 4096:
+		return append([]byte{0xe0 | byte(n>>8), byte(n)}, s...)
+	}
+	return append(binary.LittleEndian.AppendUint32([]byte{0xf0}, uint32(n)), s...)
+}
+
+func TestBacklenSize(t *testing.T) {
+	for _, n := range []int{0, 1, 127, 128, 16382, 16383, 16384, 2097150, 2097151, 268435454, 268435455} {
+		if got, want := backlenSize(n), len(backlen(n)); n < 268435455 && got != want {
+			t.Errorf("backlenSize(%d) = %d, want %d", n, got, want)
+		}
+	}
+	if got := backlenSize(268435455); got != 5 {
+		t.Errorf("backlenSize(268435455) = %d, want 5", got)
+	}
+}
+
+func TestListpack(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries [][]byte
+		want    []string
+	}{
+		{"empty", nil, nil},
+		{"7-bit uint", [][]byte{{0}, {127}}, []string{"0", "127"}},
+		{"13-bit int", [][]byte{{0xc0, 0x80}, {0xdf, 0xff}, {0xd0, 0x00}, {0xcf, 0xff}}, []string{"128", "-1", "-4096", "4095"}},
+		{"16-bit int", [][]byte{{0xf1, 0x00, 0x80}}, []string{"-32768"}},
+		{"24-bit int", [][]byte{{0xf2, 0xff, 0xff, 0x7f}, {0xf2, 0x00, 0x00, 0x80}}, []string{"8388607", "-8388608"}},
+		{"32-bit int", [][]byte{{0xf3, 0xff, 0xff, 0xff, 0x7f}}, []string{"2147483647"}},
+		{"64-bit int", [][]byte{{0xf4, 0, 0, 0, 0, 0, 0, 0, 0x80}}, []string{"-9223372036854775808"}},
+		{"6-bit string", [][]byte{lpStr(""), lpStr("a\x00b")}, []string{"", "a\x00b"}},
+		// entries of 127 and 128 bytes straddle the one-byte back-length
+		{"back-length 1/2 bytes", [][]byte{lpStr(strings.Repeat("x", 125)), lpStr(strings.Repeat("y", 126))},
+			[]string{strings.Repeat("x", 125), strings.Repeat("y", 126)}},
+		{"12-bit string", [][]byte{lpStr(strings.Repeat("s", 4095))}, []string{strings.Repeat("s", 4095)}},
+		// a 16383-byte entry takes a 3-byte back-length, 16382 bytes 2
+		{"back-length 3/2 bytes", [][]byte{lpStr(strings.Repeat("b", 16383-5)), lpStr(strings.Repeat("a", 16382-5)), {1}},
+			[]string{strings.Repeat("b", 16383-5), strings.Repeat("a", 16382-5), "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := listpack(lp(tt.entries...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListpackBad(t *testing.T) {
+	good := lp(lpStr("hello"), []byte{0xf3, 1, 2, 3, 4}, lpStr(strings.Repeat("z", 200)))
+	for n := 0; n < len(good); n++ {
+		if _, err := listpack(good[:n]); err == nil {
+			t.Errorf("cut at %d of %d: no error", n, len(good))
+		}
+	}
+	if _, err := listpack(lp([]byte{0xf5})); err == nil {
+		t.Error("unknown encoding 0xf5: no error")
+	}
+	huge := lp(append([]byte{0xf0}, 0xff, 0xff, 0xff, 0x7f))
+	if _, err := listpack(huge); err == nil {
+		t.Error("32-bit length past the end: no error")
+	}
+}
+
+// zl builds a ziplist from raw entry encodings, adding the previous
+// entry lengths, header and terminator.
+func zl(entries ...[]byte) []byte {
+	var body []byte
+	prev := 0
+	for _, e := range entries {
+		var head []byte
+		if prev < 254 {
+			head = []byte{byte(prev)}
+		} else {
+			head = binary.LittleEndian.AppendUint32([]byte{0xfe}, uint32(prev))
+		}
+		e = append(head, e...)
+		body = append(body, e...)
+		prev = len(e)
+	}
+	body = append(body, 0xff)
+	out := binary.LittleEndian.AppendUint32(nil, uint32(10+len(body)))
+	out = binary.LittleEndian.AppendUint32(out, 0)
+	out = binary.LittleEndian.AppendUint16(out, uint16(len(entries)))
+	return append(out, body...)
+}
+
+func zlStr(s string) []byte {
+	n := len(s)
+	switch {
+	case n < 64:
+		return append([]byte{byte(n)}, s...)
+	case n < 16384:
+		return append([]byte{0x40 | byte(n>>8), byte(n)}, s...)
+	}
+	return append(binary.BigEndian.AppendUint32([]byte{0x80}, uint32(n)), s...)
+}
+
+func TestZiplist(t *testing.T) {
+	long := strings.Repeat("l", 300) // makes the next prevlen 5 bytes
+	got, err := ziplist(zl(
+		zlStr(""), zlStr("a\xffb"),
+		zlStr(strings.Repeat("m", 64)), zlStr(long), zlStr(strings.Repeat("h", 16384)),
+		[]byte{0xf1}, []byte{0xfd}, // immediates 0 and 12
+		[]byte{0xfe, 0x80},                   // int8
+		[]byte{0xc0, 0x00, 0x80},             // int16
+		[]byte{0xf0, 0x00, 0x00, 0x80},       // int24
+		[]byte{0xd0, 0xff, 0xff, 0xff, 0x7f}, // int32
+		[]byte{0xe0, 1, 0, 0, 0, 0, 0, 0, 0}, // int64
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"", "a\xffb", strings.Repeat("m", 64), long, strings.Repeat("h", 16384),
+		"0", "12", "-128", "-32768", "-8388608", "2147483647", "1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestZiplistBad(t *testing.T) {
+	good := zl(zlStr("x"), zlStr(strings.Repeat("p", 260)), []byte{0xd0, 1, 2, 3, 4}, zlStr("y"))
+	for n := 0; n < len(good); n++ {
+		if _, err := ziplist(good[:n]); err == nil {
+			t.Errorf("cut at %d of %d: no error", n, len(good))
+		}
+	}
+	if _, err := ziplist(zl([]byte{0xc5})); err == nil {
+		t.Error("unknown encoding 0xc5: no error")
+	}
+}
+
+func TestLZF(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{"empty", nil, ""},
+		{"literal", []byte{2, 'a', 'b', 'c'}, "abc"},
+		{"longest literal", append([]byte{31}, bytes.Repeat([]byte{'q'}, 32)...), strings.Repeat("q", 32)},
+		{"short back reference", []byte{1, 'a', 'b', 1 << 5, 1}, "ababa"},
+		{"overlapping run", []byte{0, 'z', 5 << 5, 0}, strings.Repeat("z", 8)},
+		{"extended length", []byte{1, 'x', 'y', 7 << 5, 255, 1}, "xy" + strings.Repeat("xy", 132)},
+		{"far reference", append(append([]byte{31}, []byte(strings.Repeat("0123456789abcdef", 2))...), 1<<5|0, 31), strings.Repeat("0123456789abcdef", 2) + "012"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lzfDecompress(tt.in, len(tt.want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLZFBad(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		n    int
+	}{
+		{"literal past the input", []byte{3, 'a', 'b'}, 4},
+		{"reference before the start", []byte{0, 'a', 1 << 5, 1}, 4},
+		{"missing offset byte", []byte{0, 'a', 1 << 5}, 4},
+		{"missing length byte", []byte{0, 'a', 7 << 5}, 12},
+		{"shorter than declared", []byte{1, 'a', 'b'}, 3},
+		{"longer than declared", []byte{2, 'a', 'b', 'c'}, 2},
+		{"run past the declared size", []byte{0, 'a', 7 << 5, 255, 0}, 10},
+		{"declared size beyond any expansion", []byte{0, 'a'}, 1 << 30},
+		{"negative size", []byte{0, 'a'}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := lzfDecompress(tt.in, tt.n); err == nil {
+				t.Errorf("got %q, want an error", got)
+			}
+		})
+	}
+}
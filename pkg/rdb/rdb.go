@@ -0,0 +1,732 @@
+// Package rdb reads Redis RDB snapshot files (versions 1 to 12) into
+// dump records, without a server.
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/nexusriot/redis-walker/pkg/dump"
+)
+
+// Entry is one key read from the file.
+type Entry struct {
+	DB       int
+	Record   dump.Record // TTL is not set; see ExpireAt
+	ExpireAt time.Time   // zero = no expiry
+	Size     int64       // bytes the value took in the file
+}
+
+// Info is what the file says about itself.
+type Info struct {
+	Version int
+	Aux     map[string]string // AUX fields: redis-ver, ctime, used-mem, ...
+}
+
+// Created returns the time the snapshot was taken (the "ctime" AUX
+// field), or the zero time if the file does not record it.
+func (i *Info) Created() time.Time {
+	if s, err := strconv.ParseInt(i.Aux["ctime"], 10, 64); err == nil {
+		return time.Unix(s, 0)
+	}
+	return time.Time{}
+}
+
+// Opcodes and value types, as in Redis' rdb.h.
+const (
+	opSlotInfo     = 244
+	opFunction2    = 245
+	opFunctionPre  = 246
+	opModuleAux    = 247
+	opIdle         = 248
+	opFreq         = 249
+	opAux          = 250
+	opResizeDB     = 251
+	opExpireTimeMS = 252
+	opExpireTime   = 253
+	opSelectDB     = 254
+	opEOF          = 255
+
+	typeString           = 0
+	typeList             = 1
+	typeSet              = 2
+	typeZSet             = 3
+	typeHash             = 4
+	typeZSet2            = 5
+	typeModule2          = 7
+	typeHashZipmap       = 9
+	typeListZiplist      = 10
+	typeSetIntset        = 11
+	typeZSetZiplist      = 12
+	typeHashZiplist      = 13
+	typeListQuicklist    = 14
+	typeStreamListpacks  = 15
+	typeHashListpack     = 16
+	typeZSetListpack     = 17
+	typeListQuicklist2   = 18
+	typeStreamListpacks2 = 19
+	typeSetListpack      = 20
+	typeStreamListpacks3 = 21
+	typeHashMetadata     = 24
+	typeHashListpackEx   = 25
+)
+
+// maxVersion is the newest format version this package knows.
+const maxVersion = 12
+
+// maxString is the longest string read into memory, Redis' default
+// proto-max-bulk-len. Longer lengths mean a corrupt file.
+const maxString = 512 << 20
+
+// ErrNotRDB is returned for files that do not start with the RDB magic.
+var ErrNotRDB = errors.New("not an RDB file")
+
+// Parse reads an RDB file of size bytes (-1 if unknown) and calls fn for
+// every key, in file order. Keys of module types are passed with the
+// module's type name and no value. Lengths that run past size are
+// reported as truncation before anything is allocated for them.
+func Parse(r io.Reader, size int64, fn func(*Entry) error) (*Info, error) {
+	p := &parser{r: bufio.NewReaderSize(r, 1<<16), size: size}
+	head := make([]byte, 9)
+	if err := p.full(head); err != nil {
+		return nil, ErrNotRDB
+	}
+	if !bytes.HasPrefix(head, []byte("REDIS")) {
+		return nil, ErrNotRDB
+	}
+	version, err := strconv.Atoi(string(head[5:]))
+	if err != nil {
+		return nil, ErrNotRDB
+	}
+	if version < 1 || version > maxVersion {
+		return nil, fmt.Errorf("unsupported RDB version %d (supported: 1 to %d)", version, maxVersion)
+	}
+	info := &Info{Version: version, Aux: map[string]string{}}
+	if err := p.run(info, fn); err != nil {
+		return info, fmt.Errorf("offset %d: %w", p.off, err)
+	}
+	return info, nil
+}
+
+type parser struct {
+	r    *bufio.Reader
+	off  int64 // bytes consumed, for error messages and sizes
+	size int64 // file size, or -1
+	db   int
+}
+
+func (p *parser) run(info *Info, fn func(*Entry) error) error {
+	var expireAt time.Time
+	for {
+		op, err := p.byte()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case opEOF:
+			return nil // an 8-byte checksum may follow; it is not checked
+		case opSelectDB:
+			db, err := p.length()
+			if err != nil {
+				return err
+			}
+			p.db = int(db)
+		case opResizeDB:
+			if _, err := p.lengths(2); err != nil {
+				return err
+			}
+		case opSlotInfo:
+			if _, err := p.lengths(3); err != nil {
+				return err
+			}
+		case opAux:
+			k, err := p.string()
+			if err != nil {
+				return err
+			}
+			v, err := p.string()
+			if err != nil {
+				return err
+			}
+			info.Aux[k] = v
+		case opModuleAux:
+			if _, err := p.length(); err != nil { // module id
+				return err
+			}
+			if err := p.skipModule(); err != nil {
+				return err
+			}
+		case opFunction2:
+			if _, err := p.string(); err != nil {
+				return err
+			}
+		case opFunctionPre:
+			return fmt.Errorf("pre-release function format is not supported")
+		case opIdle:
+			if _, err := p.length(); err != nil {
+				return err
+			}
+		case opFreq:
+			if _, err := p.byte(); err != nil {
+				return err
+			}
+		case opExpireTime:
+			var b [4]byte
+			if err := p.full(b[:]); err != nil {
+				return err
+			}
+			expireAt = time.Unix(int64(binary.LittleEndian.Uint32(b[:])), 0)
+		case opExpireTimeMS:
+			ms, err := p.millis()
+			if err != nil {
+				return err
+			}
+			expireAt = time.UnixMilli(ms)
+		default:
+			key, err := p.string()
+			if err != nil {
+				return err
+			}
+			e := &Entry{DB: p.db, ExpireAt: expireAt, Record: dump.Record{Key: key}}
+			start := p.off
+			if err := p.value(op, &e.Record); err != nil {
+				return fmt.Errorf("key %q: %w", key, err)
+			}
+			e.Size = p.off - start
+			expireAt = time.Time{}
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// value reads a value of type typ into r.
+func (p *parser) value(typ byte, r *dump.Record) error {
+	var err error
+	switch typ {
+	case typeString:
+		r.Type = "string"
+		r.String, err = p.string()
+	case typeList, typeSet:
+		r.Type = "list"
+		if typ == typeSet {
+			r.Type = "set"
+		}
+		r.List, err = p.strings()
+	case typeZSet, typeZSet2:
+		r.Type = "zset"
+		err = p.zset(typ == typeZSet2, r)
+	case typeHash:
+		r.Type = "hash"
+		var kv []string
+		if kv, err = p.strings2(); err == nil {
+			r.Hash = pairsToMap(kv)
+		}
+	case typeHashMetadata:
+		r.Type = "hash"
+		err = p.hashMetadata(r)
+	case typeHashZipmap:
+		r.Type = "hash"
+		err = p.encoded(func(b []byte) error {
+			kv, err := zipmap(b)
+			r.Hash = pairsToMap(kv)
+			return err
+		})
+	case typeListZiplist:
+		r.Type = "list"
+		err = p.encoded(func(b []byte) (err error) { r.List, err = ziplist(b); return })
+	case typeSetIntset:
+		r.Type = "set"
+		err = p.encoded(func(b []byte) (err error) { r.List, err = intset(b); return })
+	case typeSetListpack:
+		r.Type = "set"
+		err = p.encoded(func(b []byte) (err error) { r.List, err = listpack(b); return })
+	case typeZSetZiplist, typeZSetListpack:
+		r.Type = "zset"
+		decode := ziplist
+		if typ == typeZSetListpack {
+			decode = listpack
+		}
+		err = p.encoded(func(b []byte) error {
+			ms, err := decode(b)
+			if err != nil {
+				return err
+			}
+			r.ZSet, err = zmembers(ms)
+			return err
+		})
+	case typeHashZiplist, typeHashListpack:
+		r.Type = "hash"
+		decode := ziplist
+		if typ == typeHashListpack {
+			decode = listpack
+		}
+		err = p.encoded(func(b []byte) error {
+			kv, err := decode(b)
+			r.Hash = pairsToMap(kv)
+			return err
+		})
+	case typeHashListpackEx:
+		r.Type = "hash"
+		if _, err = p.millis(); err != nil { // earliest field expiry
+			return err
+		}
+		err = p.encoded(func(b []byte) error {
+			triples, err := listpack(b)
+			if err != nil {
+				return err
+			}
+			r.Hash = map[string]string{}
+			for i := 0; i+2 < len(triples); i += 3 { // field, value, field TTL
+				r.Hash[triples[i]] = triples[i+1]
+			}
+			return nil
+		})
+	case typeListQuicklist, typeListQuicklist2:
+		r.Type = "list"
+		r.List, err = p.quicklist(typ == typeListQuicklist2)
+	case typeStreamListpacks, typeStreamListpacks2, typeStreamListpacks3:
+		r.Type = "stream"
+		r.Stream, err = p.stream(typ)
+	case typeModule2:
+		var id uint64
+		if id, err = p.length(); err == nil {
+			r.Type = moduleName(id)
+			err = p.skipModule()
+		}
+	default:
+		err = fmt.Errorf("unsupported value type %d", typ)
+	}
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	// order collections as a server would return them
+	switch r.Type {
+	case "set":
+		sort.Strings(r.List)
+	case "zset":
+		sort.Slice(r.ZSet, func(i, j int) bool {
+			a, b := r.ZSet[i], r.ZSet[j]
+			if a.Score != b.Score {
+				return a.Score < b.Score
+			}
+			return a.Member < b.Member
+		})
+	}
+	return err
+}
+
+func (p *parser) byte() (byte, error) {
+	b, err := p.r.ReadByte()
+	if err == nil {
+		p.off++
+	}
+	return b, err
+}
+
+func (p *parser) full(b []byte) error {
+	n, err := io.ReadFull(p.r, b)
+	p.off += int64(n)
+	return err
+}
+
+// need fails unless n more bytes can be left in the file. Counts of
+// elements are checked with it too, as each takes at least a byte.
+func (p *parser) need(n uint64) error {
+	if p.size >= 0 && n > uint64(max(p.size-p.off, 0)) {
+		return fmt.Errorf("length %d runs past the end of the file: %w", n, errTruncated)
+	}
+	return nil
+}
+
+// read reads n bytes into a new buffer, after checking n against the
+// file and maxString.
+func (p *parser) read(n uint64) ([]byte, error) {
+	if n > maxString {
+		return nil, fmt.Errorf("string of %d bytes is longer than %d", n, maxString)
+	}
+	if err := p.need(n); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	return buf, p.full(buf)
+}
+
+// skip reads past n bytes without keeping them.
+func (p *parser) skip(n uint64) error {
+	if err := p.need(n); err != nil {
+		return err
+	}
+	if n > math.MaxInt64 {
+		return errTruncated
+	}
+	c, err := io.CopyN(io.Discard, p.r, int64(n))
+	p.off += c
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (p *parser) millis() (int64, error) {
+	var b [8]byte
+	if err := p.full(b[:]); err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(b[:])), nil
+}
+
+// length reads a length. Special string encodings are an error here.
+func (p *parser) length() (uint64, error) {
+	n, special, err := p.lengthOrEncoding()
+	if err == nil && special {
+		err = fmt.Errorf("unexpected string encoding %d", n)
+	}
+	return n, err
+}
+
+func (p *parser) lengths(n int) ([]uint64, error) {
+	if err := p.need(uint64(n)); err != nil {
+		return nil, err
+	}
+	out := make([]uint64, n)
+	for i := range out {
+		var err error
+		if out[i], err = p.length(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// lengthOrEncoding reads a length, or, when special is set, the kind of
+// a specially encoded string (an integer or LZF-compressed data).
+func (p *parser) lengthOrEncoding() (n uint64, special bool, err error) {
+	b, err := p.byte()
+	if err != nil {
+		return 0, false, err
+	}
+	switch b >> 6 {
+	case 0:
+		return uint64(b & 0x3f), false, nil
+	case 1:
+		b2, err := p.byte()
+		return uint64(b&0x3f)<<8 | uint64(b2), false, err
+	case 3:
+		return uint64(b & 0x3f), true, nil
+	}
+	switch b {
+	case 0x80:
+		var buf [4]byte
+		err = p.full(buf[:])
+		return uint64(binary.BigEndian.Uint32(buf[:])), false, err
+	case 0x81:
+		var buf [8]byte
+		err = p.full(buf[:])
+		return binary.BigEndian.Uint64(buf[:]), false, err
+	}
+	return 0, false, fmt.Errorf("bad length encoding 0x%02x", b)
+}
+
+// String encodings after the 11 length prefix.
+const (
+	encInt8  = 0
+	encInt16 = 1
+	encInt32 = 2
+	encLZF   = 3
+)
+
+// string reads a string, decoding integer and LZF encodings.
+func (p *parser) string() (string, error) {
+	b, err := p.bytes()
+	return string(b), err
+}
+
+func (p *parser) bytes() ([]byte, error) {
+	n, special, err := p.lengthOrEncoding()
+	if err != nil {
+		return nil, err
+	}
+	if !special {
+		return p.read(n)
+	}
+	switch n {
+	case encInt8, encInt16, encInt32:
+		buf := make([]byte, 1<<n)
+		if err := p.full(buf); err != nil {
+			return nil, err
+		}
+		var v int64
+		switch n {
+		case encInt8:
+			v = int64(int8(buf[0]))
+		case encInt16:
+			v = int64(int16(binary.LittleEndian.Uint16(buf)))
+		case encInt32:
+			v = int64(int32(binary.LittleEndian.Uint32(buf)))
+		}
+		return strconv.AppendInt(nil, v, 10), nil
+	case encLZF:
+		clen, err := p.length()
+		if err != nil {
+			return nil, err
+		}
+		ulen, err := p.length()
+		if err != nil {
+			return nil, err
+		}
+		if ulen > maxString {
+			return nil, fmt.Errorf("string of %d bytes is longer than %d", ulen, maxString)
+		}
+		in, err := p.read(clen)
+		if err != nil {
+			return nil, err
+		}
+		return lzfDecompress(in, int(ulen))
+	}
+	return nil, fmt.Errorf("unknown string encoding %d", n)
+}
+
+// encoded reads a string holding an encoded collection and decodes it.
+func (p *parser) encoded(decode func([]byte) error) error {
+	b, err := p.bytes()
+	if err != nil {
+		return err
+	}
+	return decode(b)
+}
+
+// strings reads a length followed by that many strings.
+func (p *parser) strings() ([]string, error) {
+	n, err := p.length()
+	if err == nil {
+		err = p.need(n)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, min(n, 1<<16))
+	for ; n > 0; n-- {
+		s, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// strings2 reads a length followed by that many pairs of strings.
+func (p *parser) strings2() ([]string, error) {
+	n, err := p.length()
+	if err == nil && n > math.MaxUint64/2 {
+		err = errTruncated
+	}
+	if err == nil {
+		err = p.need(2 * n)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, min(2*n, 1<<16))
+	for ; n > 0; n-- {
+		for range 2 {
+			s, err := p.string()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (p *parser) zset(binaryScores bool, r *dump.Record) error {
+	n, err := p.length()
+	if err != nil {
+		return err
+	}
+	for ; n > 0; n-- {
+		member, err := p.string()
+		if err != nil {
+			return err
+		}
+		var score float64
+		if binaryScores {
+			var b [8]byte
+			if err := p.full(b[:]); err != nil {
+				return err
+			}
+			score = math.Float64frombits(binary.LittleEndian.Uint64(b[:]))
+		} else if score, err = p.textDouble(); err != nil {
+			return err
+		}
+		r.ZSet = append(r.ZSet, dump.ZMember{Member: member, Score: score})
+	}
+	return nil
+}
+
+// textDouble reads a score of the old zset format: a length byte with
+// special values for NaN and the infinities, then the number as text.
+func (p *parser) textDouble() (float64, error) {
+	n, err := p.byte()
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 253:
+		return math.NaN(), nil
+	case 254:
+		return math.Inf(1), nil
+	case 255:
+		return math.Inf(-1), nil
+	}
+	buf, err := p.read(uint64(n))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(string(buf), 64)
+}
+
+// hashMetadata reads a hash with per-field expiry (Redis 7.4+). Field
+// TTLs are dropped; records have none.
+func (p *parser) hashMetadata(r *dump.Record) error {
+	if _, err := p.millis(); err != nil { // earliest field expiry
+		return err
+	}
+	n, err := p.length()
+	if err != nil {
+		return err
+	}
+	r.Hash = map[string]string{}
+	for ; n > 0; n-- {
+		if _, err := p.length(); err != nil { // field TTL
+			return err
+		}
+		f, err := p.string()
+		if err != nil {
+			return err
+		}
+		v, err := p.string()
+		if err != nil {
+			return err
+		}
+		r.Hash[f] = v
+	}
+	return nil
+}
+
+// Quicklist node containers (quicklist 2).
+const (
+	containerPlain  = 1
+	containerPacked = 2
+)
+
+func (p *parser) quicklist(v2 bool) ([]string, error) {
+	n, err := p.length()
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for ; n > 0; n-- {
+		container := uint64(containerPacked)
+		if v2 {
+			if container, err = p.length(); err != nil {
+				return nil, err
+			}
+		}
+		b, err := p.bytes()
+		if err != nil {
+			return nil, err
+		}
+		var items []string
+		switch {
+		case container == containerPlain:
+			items = []string{string(b)}
+		case v2:
+			items, err = listpack(b)
+		default:
+			items, err = ziplist(b)
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, items...)
+	}
+	return out, nil
+}
+
+// skipModule skips a module value serialized with the generic opcodes
+// of module format 2, up to its EOF opcode.
+func (p *parser) skipModule() error {
+	const (
+		modEOF = iota
+		modSInt
+		modUInt
+		modFloat
+		modDouble
+		modString
+	)
+	for {
+		op, err := p.length()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case modEOF:
+			return nil
+		case modSInt, modUInt:
+			_, err = p.length()
+		case modFloat:
+			err = p.skip(4)
+		case modDouble:
+			err = p.skip(8)
+		case modString:
+			_, err = p.bytes()
+		default:
+			return fmt.Errorf("unknown module opcode %d", op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// moduleName decodes the 9-character type name from a module type id.
+func moduleName(id uint64) string {
+	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	name := make([]byte, 9)
+	for i := range name {
+		name[i] = charset[(id>>(64-6*(i+1)))&0x3f]
+	}
+	return string(name)
+}
+
+func pairsToMap(kv []string) map[string]string {
+	m := make(map[string]string, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		m[kv[i]] = kv[i+1]
+	}
+	return m
+}
+
+func zmembers(kv []string) ([]dump.ZMember, error) {
+	out := make([]dump.ZMember, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		score, err := strconv.ParseFloat(kv[i+1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad score %q", kv[i+1])
+		}
+		out = append(out, dump.ZMember{Member: kv[i], Score: score})
+	}
+	return out, nil
+}
@@ -0,0 +1,230 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/crc64"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the .golden files in testdata")
+
+// render prints what Parse made of a file, one line per AUX field and
+// two per key.
+func render(info *Info, entries []*Entry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "version %d\n", info.Version)
+	aux := make([]string, 0, len(info.Aux))
+	for k := range info.Aux {
+		aux = append(aux, k)
+	}
+	sort.Strings(aux)
+	for _, k := range aux {
+		fmt.Fprintf(&b, "aux %s=%q\n", k, info.Aux[k])
+	}
+	for _, e := range entries {
+		r := e.Record
+		expire := "none"
+		if !e.ExpireAt.IsZero() {
+			expire = e.ExpireAt.UTC().Format("2006-01-02T15:04:05.000Z")
+		}
+		fmt.Fprintf(&b, "db=%d key=%q type=%s expire=%s size=%d\n", e.DB, r.Key, r.Type, expire, e.Size)
+		var v any
+		switch r.Type {
+		case "string":
+			fmt.Fprintf(&b, "\t%q\n", r.String) // JSON would mangle binary
+			continue
+		case "hash":
+			v = r.Hash
+		case "list", "set":
+			v = r.List
+		case "zset":
+			v = r.ZSet
+		case "stream":
+			v = r.Stream
+		}
+		out, err := json.Marshal(v)
+		if err != nil {
+			out = []byte(err.Error())
+		}
+		fmt.Fprintf(&b, "\t%s\n", out)
+	}
+	return b.String()
+}
+
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.rdb")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no fixtures: %v", err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var entries []*Entry
+			info, err := Parse(bytes.NewReader(data), int64(len(data)), func(e *Entry) error {
+				entries = append(entries, e)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			got := render(info, entries)
+			golden := strings.TrimSuffix(file, ".rdb") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("output differs from %s (rerun with -update if intended):\n%s", golden, got)
+			}
+		})
+	}
+}
+
+// TestServer reads files written by real servers rather than by
+// testdata/gen.go; the expected contents were taken from the server
+// side (INFO, the replication handshake), not from Parse.
+//
+// redis-7.2.0-empty.rdb is the snapshot a 7.2.0 master sends a replica
+// of an empty instance on full resynchronization.
+func TestServer(t *testing.T) {
+	tests := []struct {
+		file    string
+		version int
+		aux     map[string]string
+		keys    []string
+	}{
+		{
+			file:    "redis-7.2.0-empty.rdb",
+			version: 11,
+			aux: map[string]string{
+				"redis-ver":  "7.2.0",
+				"redis-bits": "64",
+				"ctime":      "1706821741", // 2024-02-01T21:09:01Z
+				"used-mem":   "1098928",
+				"aof-base":   "0",
+			},
+		},
+	}
+	// Redis' CRC-64 (Jones polynomial, reflected, no final inversion),
+	// stored little-endian after the EOF opcode
+	table := crc64.MakeTable(0x95ac9329ac4bc9b5)
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "server", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, sum := data[:len(data)-8], data[len(data)-8:]
+			crc := ^crc64.Update(^uint64(0), table, body)
+			for i := range sum {
+				if sum[i] != byte(crc>>(8*i)) {
+					t.Fatalf("checksum %x does not match the file (%016x): fixture damaged", sum, crc)
+				}
+			}
+
+			var keys []string
+			info, err := Parse(bytes.NewReader(data), int64(len(data)), func(e *Entry) error {
+				keys = append(keys, e.Record.Key)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if info.Version != tt.version {
+				t.Errorf("version %d, want %d", info.Version, tt.version)
+			}
+			if fmt.Sprint(info.Aux) != fmt.Sprint(tt.aux) {
+				t.Errorf("aux %v, want %v", info.Aux, tt.aux)
+			}
+			if fmt.Sprint(keys) != fmt.Sprint(tt.keys) {
+				t.Errorf("keys %q, want %q", keys, tt.keys)
+			}
+			if got := info.Created().UTC().Format(time.RFC3339); got != "2024-02-01T21:09:01Z" {
+				t.Errorf("created %s", got)
+			}
+		})
+	}
+}
+
+// TestTruncated cuts every fixture at every offset. Parse must fail
+// without panicking, whether or not it knows the file size.
+func TestTruncated(t *testing.T) {
+	files, _ := filepath.Glob("testdata/*.rdb")
+	server, _ := filepath.Glob("testdata/server/*.rdb")
+	files = append(files, server...)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the EOF opcode, followed from version 5 on by a checksum that
+		// may itself contain 0xff
+		end := len(data) - 1
+		if v, _ := strconv.Atoi(string(data[5:9])); v >= 5 {
+			end -= 8
+		}
+		if data[end] != opEOF {
+			t.Fatalf("%s: no EOF opcode at %d", file, end)
+		}
+		for n := 0; n < end; n++ {
+			for _, size := range []int64{int64(n), -1} {
+				_, err := Parse(bytes.NewReader(data[:n]), size, func(*Entry) error { return nil })
+				if err == nil {
+					t.Errorf("%s cut at %d (size %d): no error", file, n, size)
+				}
+			}
+		}
+	}
+}
+
+func TestHugeLengths(t *testing.T) {
+	huge := func(length []byte) []byte {
+		b := []byte("REDIS0009\xfe\x00\x00\x03key")
+		return append(b, length...)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		size int64
+	}{
+		{"string past the end of the file", huge([]byte{0x80, 0x40, 0, 0, 0}), -2},
+		{"string over maxString", huge([]byte{0x81, 0, 0, 1, 0, 0, 0, 0, 0}), -1},
+		{"LZF string over maxString", huge([]byte{0xc3, 0x01, 0x81, 0, 0, 1, 0, 0, 0, 0, 0}), -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := tt.size
+			if size == -2 {
+				size = int64(len(tt.data))
+			}
+			_, err := Parse(bytes.NewReader(tt.data), size, func(*Entry) error { return nil })
+			if err == nil {
+				t.Fatal("no error")
+			}
+		})
+	}
+
+	// a consumer group claiming more pending ids than the file holds
+	p := &parser{r: nil, size: 10}
+	if err := p.skip(16 * 1 << 40); !errors.Is(err, errTruncated) {
+		t.Errorf("skip past the end: %v, want errTruncated", err)
+	}
+}
@@ -0,0 +1,218 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/nexusriot/redis-walker/pkg/dump"
+)
+
+// Stream entry flags in the listpacks.
+const (
+	streamDeleted    = 1
+	streamSameFields = 2
+)
+
+// stream reads the entries of a stream. Consumer groups are read past
+// but not kept.
+func (p *parser) stream(typ byte) ([]dump.StreamEntry, error) {
+	nodes, err := p.length()
+	if err != nil {
+		return nil, err
+	}
+	var out []dump.StreamEntry
+	for ; nodes > 0; nodes-- {
+		master, err := p.bytes()
+		if err != nil {
+			return nil, err
+		}
+		if len(master) != 16 {
+			return nil, fmt.Errorf("bad stream node key of %d bytes", len(master))
+		}
+		lp, err := p.bytes()
+		if err != nil {
+			return nil, err
+		}
+		items, err := listpack(lp)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := streamNode(binary.BigEndian.Uint64(master[:8]), binary.BigEndian.Uint64(master[8:]), items)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, entries...)
+	}
+
+	// length, last id; then first id, max deleted id and entries added
+	// since version 2
+	meta := 3
+	if typ >= typeStreamListpacks2 {
+		meta += 5
+	}
+	if _, err := p.lengths(meta); err != nil {
+		return nil, err
+	}
+	groups, err := p.length()
+	if err != nil {
+		return nil, err
+	}
+	for ; groups > 0; groups-- {
+		if err := p.skipGroup(typ); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// streamNode decodes the entries of one listpack node whose master ID
+// is ms-seq.
+func streamNode(ms, seq uint64, items []string) ([]dump.StreamEntry, error) {
+	pos := 0
+	next := func() (string, error) {
+		if pos >= len(items) {
+			return "", errTruncated
+		}
+		pos++
+		return items[pos-1], nil
+	}
+	nextInt := func() (int64, error) {
+		s, err := next()
+		if err != nil {
+			return 0, err
+		}
+		return strconv.ParseInt(s, 10, 64)
+	}
+
+	// master entry: count, deleted, number of fields, fields, 0
+	count, err := nextInt()
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := nextInt()
+	if err != nil {
+		return nil, err
+	}
+	nf, err := nextInt()
+	if err != nil {
+		return nil, err
+	}
+	if nf < 0 || nf > int64(len(items)-pos) {
+		return nil, fmt.Errorf("bad stream master entry with %d fields", nf)
+	}
+	masterFields := make([]string, nf)
+	for i := range masterFields {
+		if masterFields[i], err = next(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := next(); err != nil { // master terminator
+		return nil, err
+	}
+
+	var out []dump.StreamEntry
+	for n := count + deleted; n > 0; n-- {
+		flags, err := nextInt()
+		if err != nil {
+			return nil, err
+		}
+		msDiff, err := nextInt()
+		if err != nil {
+			return nil, err
+		}
+		seqDiff, err := nextInt()
+		if err != nil {
+			return nil, err
+		}
+		var fields []string
+		if flags&streamSameFields != 0 {
+			for _, f := range masterFields {
+				v, err := next()
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, f, v)
+			}
+		} else {
+			k, err := nextInt()
+			if err != nil {
+				return nil, err
+			}
+			for ; k > 0; k-- {
+				for range 2 {
+					s, err := next()
+					if err != nil {
+						return nil, err
+					}
+					fields = append(fields, s)
+				}
+			}
+		}
+		if _, err := next(); err != nil { // entry count, for reverse iteration
+			return nil, err
+		}
+		if flags&streamDeleted != 0 {
+			continue
+		}
+		id := fmt.Sprintf("%d-%d", ms+uint64(msDiff), seq+uint64(seqDiff))
+		out = append(out, dump.StreamEntry{ID: id, Fields: fields})
+	}
+	return out, nil
+}
+
+// skipGroup reads past a consumer group with its pending entries and
+// consumers.
+func (p *parser) skipGroup(typ byte) error {
+	if _, err := p.bytes(); err != nil { // name
+		return err
+	}
+	meta := 2 // last delivered id
+	if typ >= typeStreamListpacks2 {
+		meta++ // entries read
+	}
+	if _, err := p.lengths(meta); err != nil {
+		return err
+	}
+	pending, err := p.length()
+	if err != nil {
+		return err
+	}
+	for ; pending > 0; pending-- {
+		// id, delivery time, delivery count
+		if err := p.skip(16 + 8); err != nil {
+			return err
+		}
+		if _, err := p.length(); err != nil {
+			return err
+		}
+	}
+	consumers, err := p.length()
+	if err != nil {
+		return err
+	}
+	for ; consumers > 0; consumers-- {
+		if _, err := p.bytes(); err != nil { // name
+			return err
+		}
+		times := 8 // seen time
+		if typ >= typeStreamListpacks3 {
+			times += 8 // active time
+		}
+		if err := p.skip(uint64(times)); err != nil {
+			return err
+		}
+		owned, err := p.length()
+		if err != nil {
+			return err
+		}
+		if owned > math.MaxUint64/16 {
+			return errTruncated
+		}
+		if err := p.skip(16 * owned); err != nil { // ids of owned pending entries
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,467 @@
+//go:build ignore
+
+// Gen writes the RDB fixtures in this directory, one per format
+// generation, each covering the encodings a server of that generation
+// writes. The files are built from the RDB format description (Redis'
+// rdb.h, rdb.c, listpack.c, ziplist.c) rather than dumped by a server,
+// so that every encoding is present in a few hundred bytes.
+//
+// Regenerate them, then the expected output, with
+//
+//	go run testdata/gen.go && go test -run TestGolden -update
+//
+// from pkg/rdb.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+func main() {
+	files := map[string][]byte{
+		"v3.rdb":  v3(),
+		"v7.rdb":  v7(),
+		"v9.rdb":  v9(),
+		"v10.rdb": v10(),
+		"v11.rdb": v11(),
+		"v12.rdb": v12(),
+	}
+	for name, b := range files {
+		if err := os.WriteFile(filepath.Join("testdata", name), b, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// v3 is Redis 2.4/2.6: plain collections, zipmap, ziplists, intsets,
+// second-resolution expiry and text zset scores.
+func v3() []byte {
+	var b rdbBuf
+	b.header(3)
+	b.op(254, 0)
+	b.raw(253).le32(1700000000) // expire in seconds
+	b.key(0, "/v3/str").str("hello")
+	b.key(0, "/v3/int8").intStr(0, -5)
+	b.key(0, "/v3/int16").intStr(1, 1234)
+	b.key(0, "/v3/int32").intStr(2, -70000)
+	b.key(0, "/v3/lzf").lzfRef()
+	b.key(0, "/v3/bin\xff").str("\x00\xff\r\n")
+	b.key(1, "/v3/list").len(3).str("a").str("").str("c")
+	b.key(2, "/v3/set").len(2).str("y").str("x")
+	b.key(3, "/v3/zset").len(5).
+		str("a").textScore("1.5").
+		str("b").raw(254). // +inf
+		str("c").raw(255). // -inf
+		str("d").textScore("-2").
+		str("e").textScore("1e3")
+	b.key(4, "/v3/hash").len(2).str("f1").str("v1").str("f2").str("")
+	b.key(9, "/v3/zipmap").str(string(zipmap("k", "v1", "long", "value", "e", "")))
+	b.key(10, "/v3/ziplist").str(string(ziplist(
+		"s", "", 0, 12, 13, -1, 127, -128, 300, -32768, 70000, -8388608, 1<<31-1, -1<<31, 1<<40, -1<<62,
+		string(bytes.Repeat([]byte("m"), 70)), string(bytes.Repeat([]byte("L"), 300)),
+	)))
+	b.key(11, "/v3/intset16").str(string(intset(2, -3, 5, 300)))
+	b.key(11, "/v3/intset32").str(string(intset(4, -1, 70000)))
+	b.key(11, "/v3/intset64").str(string(intset(8, -1<<40, 1<<40)))
+	b.key(12, "/v3/zsetzl").str(string(ziplist("m", "2.5", "n", 3, "o", "-inf")))
+	b.key(13, "/v3/hashzl").str(string(ziplist("f", "v", "n", 42)))
+	b.key(10, "/v3/lzfzl").lzf(ziplist("compressed", "ziplist", 7))
+	b.raw(255)
+	return b.Bytes()
+}
+
+// v7 is Redis 3.2: AUX fields, RESIZEDB, millisecond expiry and
+// quicklists of ziplists.
+func v7() []byte {
+	var b rdbBuf
+	b.header(7)
+	b.aux("redis-ver", "3.2.12")
+	b.raw(250).str("ctime").intStr(2, 1500000000)
+	b.op(254, 0)
+	b.raw(251).len(4).len(1)
+	b.raw(252).le64(1700000000123)
+	b.key(0, "/v7/str").str("v7")
+	b.key(14, "/v7/quicklist").len(2).
+		str(string(ziplist("a", 1, "b"))).
+		lzf(ziplist(string(bytes.Repeat([]byte("p"), 260)), "q"))
+	b.op(254, 2)
+	b.key(0, "/v7/db2").str("other")
+	b.raw(255).le64(0) // checksum
+	return b.Bytes()
+}
+
+// v9 is Redis 5.0: binary zset scores, the first stream format, module
+// values and aux, LRU and LFU hints.
+func v9() []byte {
+	var b rdbBuf
+	b.header(9)
+	b.aux("redis-ver", "5.0.14")
+	b.aux("aof-preamble", "0")
+	mid := moduleID("ReJSON-RL", 3)
+	b.raw(247).len(mid).len(2).len(7).len(5).str("aux").len(0)
+	b.op(254, 0)
+	b.raw(251).len(3).len(0)
+	b.raw(248).len(12345) // idle
+	b.key(5, "/v9/zset2").len(4).
+		str("b").f64(2).
+		str("a").f64(math.Inf(1)).
+		str("c").f64(-0.5).
+		str("d").f64(math.Inf(-1))
+	b.raw(249).raw(7) // frequency
+	b.key(15, "/v9/stream")
+	b.stream(15, 1000, 0)
+	b.key(13, "/v9/hashzl").str(string(ziplist("f1", "v1", "f2", -7)))
+	b.key(7, "/v9/json").len(mid).
+		len(1).len(uint64(5)). // signed int
+		len(2).len(6).         // unsigned int
+		len(3).f32(1.5).
+		len(4).f64(2.25).
+		len(5).str(`{"a":1}`).
+		len(0)
+	b.raw(255).le64(0)
+	return b.Bytes()
+}
+
+// v10 is Redis 7.0: listpack hashes and zsets, quicklist 2 with plain
+// nodes, the second stream format and functions.
+func v10() []byte {
+	var b rdbBuf
+	b.header(10)
+	b.aux("redis-ver", "7.0.15")
+	b.raw(245).str("#!lua name=lib\nredis.register_function('f', function() return 1 end)")
+	b.op(254, 0)
+	b.key(16, "/v10/hash").str(string(listpack("f1", "v1", "f2", 2, "f3", -3000)))
+	b.key(17, "/v10/zset").str(string(listpack("m1", 1, "m2", "2.5", "m3", "-inf")))
+	b.key(18, "/v10/list").len(3).
+		len(2).str(string(listpack("x", 5, -3000, 70000, 1<<40))).
+		len(1).str("plain node").
+		len(2).lzf(listpack(string(bytes.Repeat([]byte("y"), 100)), "z"))
+	b.key(19, "/v10/stream")
+	b.stream(19, 2000, 5)
+	b.raw(255).le64(0)
+	return b.Bytes()
+}
+
+// v11 is Redis 7.2: listpack sets and the third stream format.
+func v11() []byte {
+	var b rdbBuf
+	b.header(11)
+	b.aux("redis-ver", "7.2.5")
+	b.op(254, 0)
+	b.key(20, "/v11/set").str(string(listpack("b", "a", 7, -1)))
+	b.key(21, "/v11/stream")
+	b.stream(21, 3000, 0)
+	b.raw(255).le64(0)
+	return b.Bytes()
+}
+
+// v12 is Redis 7.4: hashes with field expiry and cluster slot info.
+func v12() []byte {
+	var b rdbBuf
+	b.header(12)
+	b.aux("redis-ver", "7.4.1")
+	b.op(254, 0)
+	b.raw(244).len(42).len(3).len(0) // slot info
+	b.key(25, "/v12/hashex").le64(1700000000000).
+		str(string(listpack("f", "v", 0, "g", "w", 1700000000000)))
+	b.key(24, "/v12/hashmeta").le64(1700000000000).len(2).
+		len(0).str("ff").str("vv").
+		len(1700000000000).str("gg").str("ww")
+	b.raw(255).le64(0)
+	return b.Bytes()
+}
+
+type rdbBuf struct{ bytes.Buffer }
+
+func (b *rdbBuf) header(version int) {
+	b.WriteString("REDIS" + leftPad(strconv.Itoa(version)))
+}
+
+func leftPad(s string) string {
+	for len(s) < 4 {
+		s = "0" + s
+	}
+	return s
+}
+
+func (b *rdbBuf) raw(c byte) *rdbBuf { b.WriteByte(c); return b }
+
+func (b *rdbBuf) op(op byte, n uint64) *rdbBuf { return b.raw(op).len(n) }
+
+func (b *rdbBuf) key(typ byte, key string) *rdbBuf { return b.raw(typ).str(key) }
+
+func (b *rdbBuf) aux(k, v string) *rdbBuf { return b.raw(250).str(k).str(v) }
+
+// len writes an RDB length in the smallest form.
+func (b *rdbBuf) len(n uint64) *rdbBuf {
+	switch {
+	case n < 1<<6:
+		b.WriteByte(byte(n))
+	case n < 1<<14:
+		b.WriteByte(0x40 | byte(n>>8))
+		b.WriteByte(byte(n))
+	case n < 1<<32:
+		b.WriteByte(0x80)
+		binary.Write(b, binary.BigEndian, uint32(n))
+	default:
+		b.WriteByte(0x81)
+		binary.Write(b, binary.BigEndian, n)
+	}
+	return b
+}
+
+func (b *rdbBuf) str(s string) *rdbBuf {
+	b.len(uint64(len(s)))
+	b.WriteString(s)
+	return b
+}
+
+// intStr writes an integer-encoded string of encoding enc (8, 16 or 32
+// bits).
+func (b *rdbBuf) intStr(enc byte, v int64) *rdbBuf {
+	b.WriteByte(0xc0 | enc)
+	switch enc {
+	case 0:
+		b.WriteByte(byte(int8(v)))
+	case 1:
+		binary.Write(b, binary.LittleEndian, int16(v))
+	case 2:
+		binary.Write(b, binary.LittleEndian, int32(v))
+	}
+	return b
+}
+
+// lzf writes data as an LZF-compressed string made of literal runs only,
+// which is valid LZF.
+func (b *rdbBuf) lzf(data []byte) *rdbBuf {
+	var c []byte
+	for lo := 0; lo < len(data); lo += 32 {
+		hi := min(lo+32, len(data))
+		c = append(c, byte(hi-lo-1))
+		c = append(c, data[lo:hi]...)
+	}
+	b.WriteByte(0xc3)
+	b.len(uint64(len(c))).len(uint64(len(data)))
+	b.Write(c)
+	return b
+}
+
+// lzfRef writes "abc" followed by 267 bytes copied from it with
+// overlapping back references, long and short.
+func (b *rdbBuf) lzfRef() *rdbBuf {
+	c := []byte{2, 'a', 'b', 'c'}
+	c = append(c, 7<<5, 255, 2) // 7+255+2 = 264 bytes from 3 back
+	c = append(c, 1<<5|0, 0)    // 3 bytes from 1 back
+	c = append(c, 0, '!')       // literal
+	b.WriteByte(0xc3)
+	b.len(uint64(len(c))).len(3 + 264 + 3 + 1)
+	b.Write(c)
+	return b
+}
+
+func (b *rdbBuf) textScore(s string) *rdbBuf {
+	b.WriteByte(byte(len(s)))
+	b.WriteString(s)
+	return b
+}
+
+func (b *rdbBuf) le32(v uint32) *rdbBuf { binary.Write(b, binary.LittleEndian, v); return b }
+func (b *rdbBuf) le64(v uint64) *rdbBuf { binary.Write(b, binary.LittleEndian, v); return b }
+
+func (b *rdbBuf) f64(v float64) *rdbBuf { return b.le64(math.Float64bits(v)) }
+func (b *rdbBuf) f32(v float32) *rdbBuf { return b.le32(math.Float32bits(v)) }
+
+// stream writes one listpack node with master ID ms-seq holding three
+// entries (same fields, deleted, own fields) and a consumer group with a
+// pending entry and a consumer, in format typ (15, 19 or 21).
+func (b *rdbBuf) stream(typ byte, ms, seq uint64) {
+	master := make([]byte, 16)
+	binary.BigEndian.PutUint64(master, ms)
+	binary.BigEndian.PutUint64(master[8:], seq)
+	b.len(1).str(string(master))
+	b.str(string(listpack(
+		2, 1, 2, "a", "b", 0, // count, deleted, master fields, terminator
+		2, 0, 0, "1", "2", 6, // same fields: a=1 b=2
+		3, 1, 0, "9", "9", 6, // deleted, same fields
+		0, 5, 1, 1, "c", "x", 7, // own fields: c=x
+	)))
+	b.len(2).len(ms + 5).len(seq + 1) // length, last id
+	if typ >= 19 {
+		b.len(ms).len(seq).len(0).len(0).len(3) // first id, max deleted id, entries added
+	}
+	b.len(1).str("group").len(ms + 5).len(seq + 1) // one group, last delivered id
+	if typ >= 19 {
+		b.len(2) // entries read
+	}
+	b.len(1) // pending entries
+	id := make([]byte, 16)
+	binary.BigEndian.PutUint64(id, ms)
+	binary.BigEndian.PutUint64(id[8:], seq)
+	b.Write(id)
+	b.le64(1700000000000).len(1)                 // delivery time and count
+	b.len(1).str("consumer").le64(1700000000001) // seen time
+	if typ >= 21 {
+		b.le64(1700000000002) // active time
+	}
+	b.len(1).Write(id) // owned pending entry
+}
+
+func moduleID(name string, version uint64) uint64 {
+	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	var id uint64
+	for i := 0; i < len(name); i++ {
+		id = id<<6 | uint64(bytes.IndexByte([]byte(charset), name[i]))
+	}
+	return id<<10 | version
+}
+
+// listpack encodes items (strings or ints) the way Redis' listpack.c
+// does, choosing the smallest encoding for each.
+func listpack(items ...any) []byte {
+	var body []byte
+	for _, it := range items {
+		var e []byte
+		switch v := it.(type) {
+		case int:
+			e = lpInt(int64(v))
+		case string:
+			n := len(v)
+			switch {
+			case n < 64:
+				e = append([]byte{0x80 | byte(n)}, v...)
+			case n < 4096:
+				e = append([]byte{0xe0 | byte(n>>8), byte(n)}, v...)
+			default:
+				e = binary.LittleEndian.AppendUint32([]byte{0xf0}, uint32(n))
+				e = append(e, v...)
+			}
+		}
+		body = append(body, e...)
+		body = append(body, lpBacklen(len(e))...)
+	}
+	body = append(body, 0xff)
+	out := binary.LittleEndian.AppendUint32(nil, uint32(6+len(body)))
+	out = binary.LittleEndian.AppendUint16(out, uint16(len(items)))
+	return append(out, body...)
+}
+
+func lpInt(v int64) []byte {
+	switch {
+	case v >= 0 && v <= 127:
+		return []byte{byte(v)}
+	case v >= -4096 && v <= 4095:
+		u := uint16(v) & 0x1fff
+		return []byte{0xc0 | byte(u>>8), byte(u)}
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		return binary.LittleEndian.AppendUint16([]byte{0xf1}, uint16(v))
+	case v >= -1<<23 && v < 1<<23:
+		u := uint32(v)
+		return []byte{0xf2, byte(u), byte(u >> 8), byte(u >> 16)}
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		return binary.LittleEndian.AppendUint32([]byte{0xf3}, uint32(v))
+	}
+	return binary.LittleEndian.AppendUint64([]byte{0xf4}, uint64(v))
+}
+
+// lpBacklen is lpEncodeBacklen: the entry length in 7-bit groups, most
+// significant last, read from the end.
+func lpBacklen(l int) []byte {
+	switch {
+	case l <= 127:
+		return []byte{byte(l)}
+	case l < 16383:
+		return []byte{byte(l >> 7), byte(l&127) | 128}
+	case l < 2097151:
+		return []byte{byte(l >> 14), byte((l>>7)&127) | 128, byte(l&127) | 128}
+	}
+	panic("entry too long for a fixture")
+}
+
+// ziplist encodes items the way Redis' ziplist.c does.
+func ziplist(items ...any) []byte {
+	var body []byte
+	prev := 0
+	for _, it := range items {
+		var e []byte
+		if prev < 254 {
+			e = []byte{byte(prev)}
+		} else {
+			e = binary.LittleEndian.AppendUint32([]byte{0xfe}, uint32(prev))
+		}
+		switch v := it.(type) {
+		case int:
+			e = append(e, zlInt(int64(v))...)
+		case string:
+			n := len(v)
+			switch {
+			case n < 64:
+				e = append(e, byte(n))
+			case n < 16384:
+				e = append(e, 0x40|byte(n>>8), byte(n))
+			default:
+				e = binary.BigEndian.AppendUint32(append(e, 0x80), uint32(n))
+			}
+			e = append(e, v...)
+		}
+		body = append(body, e...)
+		prev = len(e)
+	}
+	body = append(body, 0xff)
+	out := binary.LittleEndian.AppendUint32(nil, uint32(10+len(body)))
+	out = binary.LittleEndian.AppendUint32(out, 0) // tail offset, unused
+	out = binary.LittleEndian.AppendUint16(out, uint16(len(items)))
+	return append(out, body...)
+}
+
+func zlInt(v int64) []byte {
+	switch {
+	case v >= 0 && v <= 12:
+		return []byte{0xf1 + byte(v)}
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		return []byte{0xfe, byte(v)}
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		return binary.LittleEndian.AppendUint16([]byte{0xc0}, uint16(v))
+	case v >= -1<<23 && v < 1<<23:
+		u := uint32(v)
+		return []byte{0xf0, byte(u), byte(u >> 8), byte(u >> 16)}
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		return binary.LittleEndian.AppendUint32([]byte{0xd0}, uint32(v))
+	}
+	return binary.LittleEndian.AppendUint64([]byte{0xe0}, uint64(v))
+}
+
+func intset(width int, vals ...int64) []byte {
+	out := binary.LittleEndian.AppendUint32(nil, uint32(width))
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(vals)))
+	for _, v := range vals {
+		switch width {
+		case 2:
+			out = binary.LittleEndian.AppendUint16(out, uint16(v))
+		case 4:
+			out = binary.LittleEndian.AppendUint32(out, uint32(v))
+		case 8:
+			out = binary.LittleEndian.AppendUint64(out, uint64(v))
+		}
+	}
+	return out
+}
+
+// zipmap encodes field/value pairs with one byte of free space after
+// each value.
+func zipmap(kv ...string) []byte {
+	out := []byte{byte(len(kv) / 2)}
+	for i := 0; i+1 < len(kv); i += 2 {
+		out = append(out, byte(len(kv[i])))
+		out = append(out, kv[i]...)
+		out = append(out, byte(len(kv[i+1])), 1)
+		out = append(out, kv[i+1]...)
+		out = append(out, 0) // free byte
+	}
+	return append(out, 0xff)
+}
@@ -0,0 +1,253 @@
+// Package snapshot reads and writes folder snapshots: the names, types,
+// TTLs and DUMP payloads of the keys under a prefix, gzip-compressed in
+// one file. A snapshot is restored with RESTORE, so it is exact for
+// every type, module types included, but only loads into a Redis whose
+// RDB format is at least as new as that of the server it was taken from.
+//
+// Inside the gzip stream the file holds a magic string, a length-prefixed
+// JSON header, then one record per key:
+//
+//	1, key, type, ttl, payload
+//
+// where strings are uvarint-length-prefixed and ttl is a uvarint of
+// milliseconds (0 = none). A 0 byte and the uvarint number of records end
+// the file, so a truncated file is detected.
+package snapshot
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// magic starts every snapshot, after decompression. The last byte is the
+// format version.
+const magic = "RWSNAP\x00\x01"
+
+// Ext is the file extension of snapshots.
+const Ext = ".snap"
+
+// maxString bounds the length of a key, type or payload, so that a
+// corrupt length cannot make the reader allocate without limit. It is
+// Redis' default proto-max-bulk-len, the largest value it accepts.
+const maxString = 512 << 20
+
+var (
+	// ErrNotSnapshot is returned for files that are not snapshots.
+	ErrNotSnapshot = errors.New("not a snapshot file")
+	// ErrTruncated is returned when a snapshot ends early.
+	ErrTruncated = errors.New("snapshot is truncated")
+)
+
+// Header describes a snapshot.
+type Header struct {
+	Version      int       `json:"version"`
+	CreatedAt    time.Time `json:"created_at"`
+	Server       string    `json:"server"`
+	DB           int       `json:"db"`
+	Prefix       string    `json:"prefix"`
+	RedisVersion string    `json:"redis_version,omitempty"` // of the server the payloads came from
+}
+
+// Entry is one key.
+type Entry struct {
+	Key  string
+	Type string
+	TTL  int64  // remaining TTL in ms when the snapshot was taken, 0 = none
+	Dump []byte // DUMP payload
+}
+
+// Writer writes a snapshot.
+type Writer struct {
+	zw  *gzip.Writer
+	bw  *bufio.Writer
+	n   uint64
+	buf [binary.MaxVarintLen64]byte
+}
+
+// NewWriter writes the header to w and returns a Writer for the entries.
+// Close must be called to complete the file; it does not close w.
+func NewWriter(w io.Writer, h Header) (*Writer, error) {
+	h.Version = 1
+	hdr, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	zw := gzip.NewWriter(w)
+	sw := &Writer{zw: zw, bw: bufio.NewWriter(zw)}
+	sw.bw.WriteString(magic)
+	sw.bytes(hdr)
+	return sw, nil
+}
+
+// Write adds one entry.
+func (w *Writer) Write(e *Entry) error {
+	w.bw.WriteByte(1)
+	w.bytes([]byte(e.Key))
+	w.bytes([]byte(e.Type))
+	w.uvarint(uint64(max(e.TTL, 0)))
+	w.bytes(e.Dump)
+	w.n++
+	// bufio keeps the first write error and returns it from here on
+	_, err := w.bw.Write(nil)
+	return err
+}
+
+// Close ends the file and flushes it.
+func (w *Writer) Close() error {
+	w.bw.WriteByte(0)
+	w.uvarint(w.n)
+	if err := w.bw.Flush(); err != nil {
+		return err
+	}
+	return w.zw.Close()
+}
+
+func (w *Writer) uvarint(v uint64) {
+	n := binary.PutUvarint(w.buf[:], v)
+	w.bw.Write(w.buf[:n])
+}
+
+func (w *Writer) bytes(b []byte) {
+	w.uvarint(uint64(len(b)))
+	w.bw.Write(b)
+}
+
+// Reader streams the entries of a snapshot.
+type Reader struct {
+	br   *bufio.Reader
+	zr   *gzip.Reader
+	c    io.Closer
+	h    Header
+	n    uint64
+	done bool
+}
+
+// Open opens the snapshot at path. The Reader closes the file.
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	r.c = f
+	return r, nil
+}
+
+// NewReader reads the header of a snapshot from r.
+func NewReader(r io.Reader) (*Reader, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, ErrNotSnapshot
+	}
+	sr := &Reader{br: bufio.NewReader(zr), zr: zr}
+	m := make([]byte, len(magic))
+	if _, err := io.ReadFull(sr.br, m); err != nil || string(m) != magic {
+		return nil, ErrNotSnapshot
+	}
+	hdr, err := sr.bytes()
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(hdr, &sr.h); err != nil {
+		return nil, fmt.Errorf("snapshot header: %w", err)
+	}
+	return sr, nil
+}
+
+// Header returns the snapshot header.
+func (r *Reader) Header() Header { return r.h }
+
+// Next returns the next entry, or io.EOF after the last one.
+func (r *Reader) Next() (*Entry, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+	tag, err := r.br.ReadByte()
+	if err != nil {
+		return nil, truncated(err)
+	}
+	if tag == 0 {
+		n, err := binary.ReadUvarint(r.br)
+		if err != nil {
+			return nil, truncated(err)
+		}
+		if n != r.n {
+			return nil, fmt.Errorf("snapshot has %d entries, its trailer says %d", r.n, n)
+		}
+		// reading to the end makes gzip check its own trailer, the
+		// checksum and length of the data
+		if _, err := r.br.ReadByte(); err != io.EOF {
+			if err == nil {
+				return nil, errors.New("data after the snapshot trailer")
+			}
+			return nil, truncated(err)
+		}
+		r.done = true
+		return nil, io.EOF
+	}
+	if tag != 1 {
+		return nil, fmt.Errorf("bad snapshot entry tag %d", tag)
+	}
+	var e Entry
+	key, err := r.bytes()
+	if err != nil {
+		return nil, err
+	}
+	typ, err := r.bytes()
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := binary.ReadUvarint(r.br)
+	if err != nil {
+		return nil, truncated(err)
+	}
+	if e.Dump, err = r.bytes(); err != nil {
+		return nil, err
+	}
+	e.Key, e.Type, e.TTL = string(key), string(typ), int64(ttl)
+	r.n++
+	return &e, nil
+}
+
+// Close closes the file opened by Open.
+func (r *Reader) Close() error {
+	r.zr.Close()
+	if r.c != nil {
+		return r.c.Close()
+	}
+	return nil
+}
+
+func (r *Reader) bytes() ([]byte, error) {
+	n, err := binary.ReadUvarint(r.br)
+	if err != nil {
+		return nil, truncated(err)
+	}
+	if n > maxString {
+		return nil, fmt.Errorf("snapshot string of %d bytes is too long", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.br, b); err != nil {
+		return nil, truncated(err)
+	}
+	return b, nil
+}
+
+// truncated maps an early end of the data to ErrTruncated.
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	return err
+}
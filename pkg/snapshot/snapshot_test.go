@@ -0,0 +1,200 @@
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// write returns a snapshot of entries under h.
+func write(t *testing.T, h Header, entries []*Entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if err := w.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// readAll reads every entry of a snapshot.
+func readAll(r io.Reader) (Header, []*Entry, error) {
+	sr, err := NewReader(r)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	defer sr.Close()
+	var entries []*Entry
+	for {
+		e, err := sr.Next()
+		if err == io.EOF {
+			return sr.Header(), entries, nil
+		}
+		if err != nil {
+			return sr.Header(), entries, err
+		}
+		entries = append(entries, e)
+	}
+}
+
+// gzipped compresses b as a snapshot file would be.
+func gzipped(b []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(b)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// gunzipped decompresses a snapshot file.
+func gunzipped(t *testing.T, b []byte) []byte {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+var (
+	header = Header{
+		CreatedAt:    time.Date(2024, 2, 1, 21, 9, 1, 0, time.UTC),
+		Server:       "127.0.0.1:6379",
+		DB:           3,
+		Prefix:       "/app/",
+		RedisVersion: "7.2.4",
+	}
+	entries = []*Entry{
+		{Key: "/app/bin\x00\xff\r\n", Type: "string", TTL: 1500, Dump: allBytes()},
+		{Key: "/app/plain", Type: "hash", Dump: []byte("\x04\x01")},
+		{Key: "", Type: "string", Dump: []byte{}},
+	}
+)
+
+func allBytes() []byte {
+	b := make([]byte, 256)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+func TestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s"+Ext)
+	if err := os.WriteFile(path, write(t, header, entries), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := header
+	want.Version = 1
+	if got := r.Header(); !reflect.DeepEqual(got, want) {
+		t.Errorf("header %+v, want %+v", got, want)
+	}
+	for i, want := range entries {
+		got, err := r.Next()
+		if err != nil {
+			t.Fatalf("entry %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("entry %d: %+v, want %+v", i, got, want)
+		}
+	}
+	for range 2 {
+		if _, err := r.Next(); err != io.EOF {
+			t.Errorf("after the last entry: %v, want io.EOF", err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNegativeTTL(t *testing.T) {
+	// a key that expired while it was dumped is written without a TTL
+	data := write(t, header, []*Entry{{Key: "k", Type: "string", TTL: -2, Dump: []byte("x")}})
+	_, got, err := readAll(bytes.NewReader(data))
+	if err != nil || len(got) != 1 || got[0].TTL != 0 {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+}
+
+// TestTruncated cuts the data at every offset, both inside the gzip
+// stream and before compression. Reading must fail at the cut.
+func TestTruncated(t *testing.T) {
+	data := write(t, header, entries)
+	for n := range len(data) {
+		if _, _, err := readAll(bytes.NewReader(data[:n])); err == nil {
+			t.Errorf("file cut at %d of %d: no error", n, len(data))
+		}
+	}
+
+	raw := gunzipped(t, data)
+	for n := range len(raw) {
+		_, _, err := readAll(bytes.NewReader(gzipped(raw[:n])))
+		want := ErrTruncated
+		if n < len(magic) {
+			want = ErrNotSnapshot
+		}
+		if !errors.Is(err, want) {
+			t.Errorf("data cut at %d of %d: %v, want %v", n, len(raw), err, want)
+		}
+	}
+}
+
+func TestBadMagic(t *testing.T) {
+	raw := gunzipped(t, write(t, header, entries))
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not gzip", raw},
+		{"other format version", gzipped(append([]byte("RWSNAP\x00\x02"), raw[len(magic):]...))},
+		{"other file", gzipped([]byte(`{"version":1,"keys":[]}`))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewReader(bytes.NewReader(tt.data)); !errors.Is(err, ErrNotSnapshot) {
+				t.Errorf("%v, want ErrNotSnapshot", err)
+			}
+		})
+	}
+}
+
+func TestBadTrailer(t *testing.T) {
+	raw := gunzipped(t, write(t, header, entries))
+	// the trailer is a 0 byte and the entry count, 3
+	if raw[len(raw)-1] != 3 {
+		t.Fatalf("unexpected trailer % x", raw[len(raw)-2:])
+	}
+	_, got, err := readAll(bytes.NewReader(gzipped(append(raw, 0))))
+	if err == nil || len(got) != len(entries) {
+		t.Errorf("data after the trailer: %d entries, %v", len(got), err)
+	}
+	raw[len(raw)-1] = 2
+	_, got, err = readAll(bytes.NewReader(gzipped(raw)))
+	if err == nil || len(got) != len(entries) {
+		t.Errorf("count off by one: %d entries, %v", len(got), err)
+	}
+}
@@ -0,0 +1,246 @@
+package theme
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nexusriot/redis-walker/pkg/config"
+)
+
+// Theme holds the colors used by the view and the key colorization rules.
+type Theme struct {
+	Name    string
+	NoColor bool
+
+	Background   tcell.Color
+	Contrast     tcell.Color // background of modals, buttons, input fields
+	Text         tcell.Color
+	Secondary    tcell.Color // form labels, secondary text
+	Border       tcell.Color
+	Title        tcell.Color
+	SelectedText tcell.Color
+	SelectedBg   tcell.Color
+	Label        tcell.Color // Details pane field names
+	Value        tcell.Color // Details pane field values
+	Header       tcell.Color
+	Footer       tcell.Color
+	Error        tcell.Color
+	Highlight    tcell.Color // built-in rule: names starting with '_'
+
+	rules []compiledRule
+}
+
+// Rule colors keys in the list; it is the color rule of the config file.
+type Rule = config.ColorRule
+
+type compiledRule struct {
+	Rule
+	re  *regexp.Regexp
+	tag string
+}
+
+var builtin = map[string]Theme{
+	"default": {
+		Background:   tcell.ColorBlack,
+		Contrast:     tcell.ColorBlue,
+		Text:         tcell.ColorWhite,
+		Secondary:    tcell.ColorYellow,
+		Border:       tcell.ColorWhite,
+		Title:        tcell.ColorWhite,
+		SelectedText: tcell.ColorBlack,
+		SelectedBg:   tcell.ColorYellow,
+		Label:        tcell.ColorGreen,
+		Value:        tcell.ColorWhite,
+		Header:       tcell.ColorGreen,
+		Footer:       tcell.ColorWhite,
+		Error:        tcell.ColorRed,
+		Highlight:    tcell.ColorYellow,
+	},
+	"light": {
+		Background:   tcell.ColorWhite,
+		Contrast:     tcell.ColorLightGray,
+		Text:         tcell.ColorBlack,
+		Secondary:    tcell.ColorNavy,
+		Border:       tcell.ColorGray,
+		Title:        tcell.ColorBlack,
+		SelectedText: tcell.ColorWhite,
+		SelectedBg:   tcell.ColorNavy,
+		Label:        tcell.ColorDarkGreen,
+		Value:        tcell.ColorBlack,
+		Header:       tcell.ColorDarkGreen,
+		Footer:       tcell.ColorBlack,
+		Error:        tcell.ColorDarkRed,
+		Highlight:    tcell.ColorDarkOrange,
+	},
+	"none": {
+		NoColor:      true,
+		Background:   tcell.ColorDefault,
+		Contrast:     tcell.ColorDefault,
+		Text:         tcell.ColorDefault,
+		Secondary:    tcell.ColorDefault,
+		Border:       tcell.ColorDefault,
+		Title:        tcell.ColorDefault,
+		SelectedText: tcell.ColorDefault,
+		SelectedBg:   tcell.ColorDefault,
+		Label:        tcell.ColorDefault,
+		Value:        tcell.ColorDefault,
+		Header:       tcell.ColorDefault,
+		Footer:       tcell.ColorDefault,
+		Error:        tcell.ColorDefault,
+		Highlight:    tcell.ColorDefault,
+	},
+}
+
+// Names lists the built-in themes.
+func Names() []string {
+	names := make([]string, 0, len(builtin))
+	for n := range builtin {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns the named built-in theme with the given color rules. A nil
+// rules slice selects the built-in rule that highlights names starting
+// with '_'.
+func New(name string, rules []Rule) (*Theme, error) {
+	if name == "" {
+		name = "default"
+	}
+	base, ok := builtin[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	t := base
+	t.Name = strings.ToLower(name)
+
+	if rules == nil {
+		rules = []Rule{{Glob: "_*", Color: t.Highlight.String()}}
+	}
+	for i, r := range rules {
+		cr, err := t.compile(r)
+		if err != nil {
+			return nil, fmt.Errorf("color rule %d: %w", i, err)
+		}
+		t.rules = append(t.rules, cr)
+	}
+	return &t, nil
+}
+
+func (t *Theme) compile(r Rule) (compiledRule, error) {
+	cr := compiledRule{Rule: r}
+	if r.Glob == "" && r.Regex == "" && r.Type == "" {
+		return cr, fmt.Errorf("needs at least one of glob, regex or type")
+	}
+	if r.Glob != "" {
+		if _, err := path.Match(r.Glob, ""); err != nil {
+			return cr, fmt.Errorf("glob %q: %w", r.Glob, err)
+		}
+	}
+	if r.Regex != "" {
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return cr, fmt.Errorf("regex %q: %w", r.Regex, err)
+		}
+		cr.re = re
+	}
+
+	color := strings.TrimSpace(strings.ToLower(r.Color))
+	if color != "" && color != "default" && tcell.GetColor(color) == tcell.ColorDefault {
+		return cr, fmt.Errorf("unknown color %q", r.Color)
+	}
+	if t.NoColor {
+		color = ""
+	}
+	attrs := ""
+	if r.Bold {
+		attrs += "b"
+	}
+	if r.Underline {
+		attrs += "u"
+	}
+	if color == "" && attrs == "" {
+		return cr, nil
+	}
+	cr.tag = "[" + color + "::" + attrs + "]"
+	return cr, nil
+}
+
+func (r *compiledRule) match(fullPath, base, redisType string) bool {
+	if r.Type != "" && !strings.EqualFold(r.Type, redisType) {
+		return false
+	}
+	if r.Glob != "" {
+		subject := base
+		if strings.Contains(r.Glob, "/") {
+			subject = fullPath
+		}
+		if ok, _ := path.Match(r.Glob, subject); !ok {
+			return false
+		}
+	}
+	if r.re != nil && !r.re.MatchString(fullPath) {
+		return false
+	}
+	return true
+}
+
+// Colorize wraps a list label in the tags of the first matching rule.
+// Directories have no Redis type, so type rules never match them.
+func (t *Theme) Colorize(label, fullPath, base, redisType string) string {
+	for i := range t.rules {
+		r := &t.rules[i]
+		if !r.match(fullPath, base, redisType) {
+			continue
+		}
+		if r.tag == "" {
+			return label
+		}
+		return r.tag + label + "[-::-]"
+	}
+	return label
+}
+
+// Tag returns a tview color tag for c, or "" in no-color mode.
+func (t *Theme) Tag(c tcell.Color) string {
+	if t.NoColor {
+		return ""
+	}
+	if c == tcell.ColorDefault {
+		return "[-]"
+	}
+	return "[" + c.String() + "]"
+}
+
+// Apply installs the theme as tview's global style. It must be called
+// before any primitive is created.
+func (t *Theme) Apply() {
+	tview.Styles = tview.Theme{
+		PrimitiveBackgroundColor:    t.Background,
+		ContrastBackgroundColor:     t.Contrast,
+		MoreContrastBackgroundColor: t.Contrast,
+		BorderColor:                 t.Border,
+		TitleColor:                  t.Title,
+		GraphicsColor:               t.Border,
+		PrimaryTextColor:            t.Text,
+		SecondaryTextColor:          t.Secondary,
+		TertiaryTextColor:           t.Label,
+		InverseTextColor:            t.SelectedText,
+		ContrastSecondaryTextColor:  t.Secondary,
+	}
+}
+
+// SelectedStyle is the style of the highlighted list row.
+func (t *Theme) SelectedStyle() tcell.Style {
+	if t.NoColor {
+		return tcell.StyleDefault.Reverse(true)
+	}
+	return tcell.StyleDefault.Foreground(t.SelectedText).Background(t.SelectedBg)
+}
@@ -0,0 +1,429 @@
+package trash
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MaxOpBytes is the largest payload one operation may capture. Load holds
+// an operation in memory, so it is bounded to keep every entry readable.
+const MaxOpBytes = 1 << 30
+
+// Item is one key captured before it was deleted or overwritten.
+type Item struct {
+	Key  string `json:"key"`
+	Type string `json:"type"`
+	Dump []byte `json:"dump"`          // DUMP payload, base64 in the file
+	TTL  int64  `json:"ttl,omitempty"` // remaining TTL in ms at capture time, 0 = none
+}
+
+// Op groups the items of one user operation so they can be restored
+// together. Items is only filled by Load; the other fields describe them.
+type Op struct {
+	ID     string    `json:"id"`
+	Time   time.Time `json:"time"`
+	Kind   string    `json:"kind"` // del, deldir, set, mkdir, rename, import, ...
+	Server string    `json:"server"`
+	DB     int       `json:"db"`
+	Dir    string    `json:"dir,omitempty"`    // folder of a deldir or rename
+	Target string    `json:"target,omitempty"` // folder a rename moved Dir to
+	Keys   int       `json:"keys"`             // number of items; 0 if the operation only created keys
+	Bytes  int64     `json:"bytes"`            // total payload size
+	Key    string    `json:"key,omitempty"`    // the key of a single-key operation
+	Items  []Item    `json:"-"`
+}
+
+// Size returns the total payload size of the op.
+func (o *Op) Size() int64 { return o.Bytes }
+
+// header is the line that starts an operation in the file, or, with
+// Removed set, the line that drops one.
+type header struct {
+	Op
+	Len     int64  `json:"len,omitempty"`     // bytes of the item lines that follow
+	Removed string `json:"removed,omitempty"` // ID of a removed operation
+}
+
+// entry is an operation in the index: its header and where it is stored.
+type entry struct {
+	Op
+	off int64 // offset of the header line
+	n   int64 // length of the header line and items
+}
+
+// Store is an append-only file of operations, newest last, capped to a
+// maximum number of operations. Each operation is a header line followed
+// by one JSON line per item; removing one appends a line naming it. Only
+// the headers are kept in memory and items are read back by Load. The
+// file is compacted once dropped operations take more of it than live
+// ones.
+type Store struct {
+	path   string
+	maxOps int
+
+	mu   sync.Mutex
+	ops  []entry
+	size int64 // bytes in the file
+	live int64 // bytes of the operations in ops
+	seq  int64
+}
+
+// Open loads (or creates) the store at path. If the file holds more than
+// maxOps operations the oldest are dropped; maxOps <= 0 means unlimited.
+// Lines that cannot be parsed are logged and skipped, and an operation
+// cut short by a crash while it was appended is cut off the file.
+func Open(path string, maxOps int) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create trash dir: %w", err)
+	}
+	s := &Store{path: path, maxOps: maxOps}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := st.Size()
+
+	var (
+		br  = bufio.NewReader(f)
+		pos int64
+		bad int // unreadable lines
+	)
+	for pos < size {
+		start := pos
+		line, err := br.ReadBytes('\n')
+		pos += int64(len(line))
+		if errors.Is(err, io.EOF) {
+			size = s.cut(start, "trash file ends in a partial line")
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("trash %s: %w", path, err)
+		}
+		var h header
+		if err := json.Unmarshal(line, &h); err != nil || (h.ID == "" && h.Removed == "") {
+			if err == nil {
+				err = errors.New("neither an operation nor a removal")
+			}
+			log.WithError(err).WithFields(log.Fields{"trash_file": path, "offset": start}).
+				Warn("skipping unreadable trash entry")
+			bad++
+			continue
+		}
+		if h.Removed != "" {
+			s.drop(h.Removed)
+			continue
+		}
+		if h.Len < 0 || pos+h.Len > size {
+			size = s.cut(start, "trash operation cut short")
+			break
+		}
+		pos += h.Len
+		s.ops = append(s.ops, entry{Op: h.Op, off: start, n: pos - start})
+		s.live += pos - start
+		if _, err := f.Seek(pos, io.SeekStart); err != nil {
+			return nil, err
+		}
+		br.Reset(f)
+	}
+	s.size = size
+
+	if s.maxOps > 0 && len(s.ops) > s.maxOps {
+		for _, e := range s.ops[:len(s.ops)-s.maxOps] {
+			s.live -= e.n
+		}
+		s.ops = s.ops[len(s.ops)-s.maxOps:]
+	}
+	if bad > 0 || s.size-s.live > s.live {
+		if err := s.compact(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// cut truncates the file at off, dropping a damaged tail, and returns
+// the new size. It is only called by Open.
+func (s *Store) cut(off int64, why string) int64 {
+	log.WithFields(log.Fields{"trash_file": s.path, "offset": off}).Warn(why + "; dropping it")
+	if err := os.Truncate(s.path, off); err != nil {
+		log.WithError(err).WithField("trash_file", s.path).Warn("failed to truncate trash file")
+	}
+	return off
+}
+
+// Path returns the file backing the store.
+func (s *Store) Path() string { return s.path }
+
+// Add assigns an ID and time to op, fills in its description from its
+// items and appends it to the store.
+func (s *Store) Add(op *Op) error {
+	op.Keys, op.Bytes, op.Key = len(op.Items), 0, ""
+	for _, it := range op.Items {
+		op.Bytes += int64(len(it.Dump))
+	}
+	if op.Bytes > MaxOpBytes {
+		return fmt.Errorf("trash operation of %d MiB exceeds the %d MiB limit", op.Bytes>>20, MaxOpBytes>>20)
+	}
+	if len(op.Items) == 1 {
+		op.Key = op.Items[0].Key
+	}
+	var items bytes.Buffer
+	enc := json.NewEncoder(&items)
+	for i := range op.Items {
+		if err := enc.Encode(&op.Items[i]); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	op.ID = strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatInt(s.seq, 36)
+	if op.Time.IsZero() {
+		op.Time = time.Now()
+	}
+	line, err := json.Marshal(header{Op: *op, Len: int64(items.Len())})
+	if err != nil {
+		return err
+	}
+	off, err := s.write(append(line, '\n'), items.Bytes())
+	if err != nil {
+		return err
+	}
+
+	e := entry{Op: *op, off: off, n: int64(len(line) + 1 + items.Len())}
+	e.Items = nil
+	s.ops = append(s.ops, e)
+	s.live += e.n
+	if s.maxOps > 0 && len(s.ops) > s.maxOps {
+		for _, old := range s.ops[:len(s.ops)-s.maxOps] {
+			s.live -= old.n
+		}
+		s.ops = s.ops[len(s.ops)-s.maxOps:]
+		return s.compactIfSparse()
+	}
+	return nil
+}
+
+// write appends parts to the end of the file and returns the offset they
+// start at. Callers hold mu.
+func (s *Store) write(parts ...[]byte) (int64, error) {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	off := st.Size()
+	w := bufio.NewWriter(f)
+	for _, p := range parts {
+		if _, err := w.Write(p); err != nil {
+			f.Close()
+			return 0, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	for _, p := range parts {
+		off += int64(len(p))
+	}
+	s.size = off
+	return st.Size(), nil
+}
+
+// List returns the operations that captured keys, newest first, without
+// their items.
+func (s *Store) List() []Op {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Op, 0, len(s.ops))
+	for i := len(s.ops) - 1; i >= 0; i-- {
+		if s.ops[i].Keys > 0 {
+			out = append(out, s.ops[i].Op)
+		}
+	}
+	return out
+}
+
+// Get returns the operation with the given ID, without its items.
+func (s *Store) Get(id string) (Op, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.find(id); i >= 0 {
+		return s.ops[i].Op, true
+	}
+	return Op{}, false
+}
+
+// Load returns the operation with the given ID and its items, read from
+// the file.
+func (s *Store) Load(id string) (Op, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.find(id)
+	if i < 0 {
+		return Op{}, fmt.Errorf("trash entry %s not found", id)
+	}
+	e := s.ops[i]
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return Op{}, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(io.NewSectionReader(f, e.off, e.n))
+	line, err := br.ReadBytes('\n')
+	var h header
+	if err == nil {
+		err = json.Unmarshal(line, &h)
+	}
+	if err != nil || h.ID != id {
+		return Op{}, fmt.Errorf("trash entry %s: file changed since it was opened", id)
+	}
+	op := e.Op
+	op.Items = make([]Item, op.Keys)
+	dec := json.NewDecoder(br)
+	for j := range op.Items {
+		if err := dec.Decode(&op.Items[j]); err != nil {
+			return Op{}, fmt.Errorf("trash entry %s, item %d: %w", id, j+1, err)
+		}
+	}
+	return op, nil
+}
+
+// Last returns the newest operation recorded for server/db, without its
+// items.
+func (s *Store) Last(server string, db int) (Op, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.ops) - 1; i >= 0; i-- {
+		if s.ops[i].Server == server && s.ops[i].DB == db {
+			return s.ops[i].Op, true
+		}
+	}
+	return Op{}, false
+}
+
+// Remove drops an operation from the store.
+func (s *Store) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.find(id) < 0 {
+		return fmt.Errorf("trash entry %s not found", id)
+	}
+	line, err := json.Marshal(struct {
+		Removed string `json:"removed"`
+	}{id})
+	if err != nil {
+		return err
+	}
+	if _, err := s.write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.drop(id)
+	return s.compactIfSparse()
+}
+
+// find returns the index of the operation with the given ID, or -1.
+// Callers hold mu.
+func (s *Store) find(id string) int {
+	for i := range s.ops {
+		if s.ops[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// drop removes an operation from the index. Callers hold mu.
+func (s *Store) drop(id string) {
+	if i := s.find(id); i >= 0 {
+		s.live -= s.ops[i].n
+		s.ops = append(s.ops[:i], s.ops[i+1:]...)
+	}
+}
+
+// compactIfSparse compacts the file once dropped operations take more of
+// it than live ones. Callers hold mu.
+func (s *Store) compactIfSparse() error {
+	if s.size-s.live > s.live {
+		return s.compact()
+	}
+	return nil
+}
+
+// compact copies the live operations to a new file and replaces the old
+// one with it. Callers hold mu.
+func (s *Store) compact() error {
+	src, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".trash-*")
+	if err != nil {
+		return err
+	}
+	fail := func(err error) error {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	ops := make([]entry, len(s.ops))
+	var off int64
+	for i, e := range s.ops {
+		if _, err := io.Copy(w, io.NewSectionReader(src, e.off, e.n)); err != nil {
+			return fail(err)
+		}
+		ops[i] = e
+		ops[i].off = off
+		off += e.n
+	}
+	if err := w.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	s.ops, s.size, s.live = ops, off, off
+	return nil
+}
@@ -1,8 +1,15 @@
 package view
 
 import (
+	"fmt"
+	"math"
+	"strings"
+
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+
+	"github.com/nexusriot/redis-walker/pkg/keymap"
+	"github.com/nexusriot/redis-walker/pkg/theme"
 )
 
 // View ...
@@ -12,19 +19,23 @@ type View struct {
 	Pages     *tview.Pages
 	List      *tview.List
 	Details   *tview.TextView
+	Keys      *keymap.Keymap
+	Theme     *theme.Theme
 	ModalEdit func(p tview.Primitive, width, height int) tview.Primitive
+
+	editor *tview.Pages // full-screen editor and its dialogs; nil when closed
 }
 
 // NewView ...
-func NewView() *View {
+func NewView(keys *keymap.Keymap, th *theme.Theme) *View {
+	th.Apply()
 	app := tview.NewApplication()
 
 	list := tview.NewList().
 		ShowSecondaryText(false)
 	list.SetBorder(true).
 		SetTitleAlign(tview.AlignLeft)
-	list.SetSelectedTextColor(tcell.ColorBlack).
-		SetSelectedBackgroundColor(tcell.ColorYellow)
+	list.SetSelectedStyle(th.SelectedStyle())
 
 	tv := tview.NewTextView().
 		SetDynamicColors(true).
@@ -54,10 +65,10 @@ func NewView() *View {
 
 	frame := tview.NewFrame(pages)
 	frame.AddText(
-		"[::b][↓,↑][::-] Down/Up  [::b][Enter/Backspace][::-]Open/Up [::b][Ctrl+N][::-]New(Create) [::b][Del[][::-]Delete [::b][Ctrl+E][::-]Edit [::b][/,Ctrl+S][::-]Search [::b][Ctrl+J][::-]Jump [::b][F1/?][::-]Hotkeys [::b][Ctrl+Q][::-]Quit",
+		footerText(keys),
 		false,
 		tview.AlignCenter,
-		tcell.ColorWhite,
+		th.Footer,
 	)
 
 	app.SetRoot(frame, true)
@@ -68,12 +79,29 @@ func NewView() *View {
 		pages,
 		list,
 		tv,
+		keys,
+		th,
 		modal,
+		nil,
 	}
 
 	return &v
 }
 
+// footerText renders the one-line shortcut summary from the active keymap.
+func footerText(keys *keymap.Keymap) string {
+	items := []string{"[::b][↓,↑][::-] Down/Up", "[::b][Enter][::-] Open"}
+	for _, in := range keymap.Actions() {
+		label := keys.Label(in.Action)
+		if in.Footer == "" || label == "" {
+			continue
+		}
+		label = strings.ReplaceAll(label, ", ", "/")
+		items = append(items, "[::b]"+tview.Escape("["+label+"]")+"[::-]"+in.Footer)
+	}
+	return strings.Join(items, " ")
+}
+
 func (v *View) NewCreateForm(header string) *tview.Form {
 	form := tview.NewForm().
 		AddInputField("Key name", "", 32, nil, nil).
@@ -88,8 +116,8 @@ func (v *View) NewCreateForm(header string) *tview.Form {
 
 	form.SetBorderPadding(1, 1, 2, 2)
 
-	form.SetLabelColor(tcell.ColorYellow)
-	form.SetFieldTextColor(tcell.ColorWhite)
+	form.SetLabelColor(v.Theme.Secondary)
+	form.SetFieldTextColor(v.Theme.Text)
 	form.SetFieldBackgroundColor(tcell.ColorDefault)
 	form.SetButtonsAlign(tview.AlignCenter)
 
@@ -125,7 +153,7 @@ func (v *View) NewEditValueForm(header string, value string) *tview.Form {
 func (v *View) NewSearch() *tview.InputField {
 	search := tview.NewInputField().
 		SetPlaceholder("search").
-		SetFieldTextColor(tcell.ColorWhite)
+		SetFieldTextColor(v.Theme.Text)
 	return search
 }
 
@@ -142,33 +170,51 @@ func (v *View) NewDeleteQ(header string) *tview.Modal {
 	return deleteQ
 }
 
+// NewConflictQ asks how to resolve a concurrent modification.
+func (v *View) NewConflictQ(text string) *tview.Modal {
+	conflictQ := tview.NewModal()
+	conflictQ.SetText(text).AddButtons([]string{"Overwrite", "Reload", "Diff", "Cancel"})
+	return conflictQ
+}
+
 func (v *View) NewErrorMessageQ(header string, details string) *tview.Modal {
 	errorQ := tview.NewModal()
-	errorQ.SetText(header + ": " + details).SetBackgroundColor(tcell.ColorRed).AddButtons([]string{"ok"})
+	errorQ.SetText(header + ": " + details).SetBackgroundColor(v.Theme.Error).AddButtons([]string{"ok"})
 	return errorQ
 }
 
+func (v *View) NewInfoMessage(header string, details string) *tview.Modal {
+	infoQ := tview.NewModal()
+	infoQ.SetText(header + ": " + details).AddButtons([]string{"ok"})
+	return infoQ
+}
+
+// HotkeysText renders the help text from the active keymap, grouped by
+// section in registry order.
+func (v *View) HotkeysText() string {
+	var b strings.Builder
+	section := ""
+	b.WriteString("\n")
+	for _, in := range keymap.Actions() {
+		if in.Section != section {
+			section = in.Section
+			fmt.Fprintf(&b, "  [::b]%s[::-]\n", section)
+			if section == "Navigation" {
+				fmt.Fprintf(&b, "    %-16s%s\n", "Enter", "Open dir / select")
+			}
+		}
+		label := v.Keys.Label(in.Action)
+		if label == "" {
+			label = "(unbound)"
+		}
+		fmt.Fprintf(&b, "    %-16s%s\n", tview.Escape(label), in.Description)
+	}
+	b.WriteString("\n  [::d]Press any key to close.[::-]\n")
+	return b.String()
+}
+
 func (v *View) NewHotkeysModal() *tview.TextView {
-	helpText := `
-		[::b]Navigation[::-]
-		  Enter         Open dir / select
-		  Backspace     Up ([..])
-		[::b]Actions[::-]
-		  Ctrl+N        Create key/dir
-		  Ctrl+E        Edit (value multiline/ rename dir)
-		  Del           Delete (recursive for dirs)
-		  Ctrl+J        Jump to key/dir(dir ends with '/')
-		[::b]Search[::-]
-		  /, Ctrl+S     Search by name (in current level)
-		[::b]Editor[::-]
-		  Ctrl+S        Save
-		  Esc/Ctrl+Q    Cancel/Cancel+Quit
-		[::b]Misc[::-]
-		  F1 or ?   This help
-		  Ctrl+Q        Quit
-		
-		[dim]Press any key to close.[-]
-	`
+	helpText := v.HotkeysText()
 	tv := tview.NewTextView()
 	tv.SetDynamicColors(true)
 	tv.SetTextAlign(tview.AlignLeft)
@@ -185,7 +231,8 @@ func (v *View) NewMultilineEditor(title, initial string) *tview.TextArea {
 		SetText(initial, false).
 		SetPlaceholder("")
 	ta.SetBorder(true).
-		SetTitle(title + "  [Ctrl+S=Save | Esc=Cancel]")
+		SetTitle(title + "  " + tview.Escape(fmt.Sprintf("[%s=Save | %s=Cancel]",
+			v.Keys.Label(keymap.Save), v.Keys.Label(keymap.Cancel))))
 	return ta
 }
 
@@ -193,11 +240,119 @@ func (v *View) OpenEditor(p tview.Primitive) {
 	editor := tview.NewFlex().
 		SetDirection(tview.FlexRow).
 		AddItem(p, 0, 1, true)
-	v.App.SetRoot(editor, true)
+	v.editor = tview.NewPages().AddPage("editor", editor, true, true)
+	v.App.SetRoot(v.editor, true)
 	v.App.SetFocus(p)
 }
 
 func (v *View) CloseEditor() {
+	v.editor = nil
 	v.App.SetRoot(v.Frame, true)
 	v.App.SetFocus(v.List)
 }
+
+// Overlay returns the pages dialogs are added to: the editor's while it
+// is open, the main pages otherwise.
+func (v *View) Overlay() *tview.Pages {
+	if v.editor != nil {
+		return v.editor
+	}
+	return v.Pages
+}
+
+// NewLogView creates the full-screen log pane.
+func (v *View) NewLogView() *tview.TextView {
+	tv := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(true).
+		SetWordWrap(false).
+		SetScrollable(true)
+	tv.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft)
+	return tv
+}
+
+// NewConfirmForm shows a summary of a destructive operation. If
+// confirmText is non-empty, the form also has an input field where the
+// user must type it; the caller checks the field (item 1) before acting.
+func (v *View) NewConfirmForm(header, summary, confirmText string, summaryLines int) *tview.Form {
+	form := tview.NewForm().
+		AddTextView("", summary, 0, summaryLines, true, true)
+	if confirmText != "" {
+		form.AddInputField(fmt.Sprintf("Type %q to confirm", confirmText), "", 32, nil, nil)
+	}
+	form.SetBorder(true).
+		SetTitle(header).
+		SetTitleAlign(tview.AlignLeft)
+	form.SetBorderPadding(1, 1, 2, 2)
+	form.SetLabelColor(v.Theme.Secondary)
+	form.SetFieldTextColor(v.Theme.Text)
+	form.SetButtonsAlign(tview.AlignCenter)
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			v.Pages.RemovePage("modal")
+			return nil
+		}
+		return event
+	})
+	return form
+}
+
+// NewTable creates a bordered, row-selectable table whose first row is a
+// fixed header. Used by the full-screen browser panes.
+func (v *View) NewTable(title string, headers ...string) *tview.Table {
+	t := tview.NewTable().
+		SetBorders(false).
+		SetSelectable(true, false).
+		SetFixed(1, 0)
+	t.SetSelectedStyle(v.Theme.SelectedStyle())
+	t.SetBorder(true).
+		SetTitle(title).
+		SetTitleAlign(tview.AlignLeft)
+	for col, h := range headers {
+		t.SetCell(0, col, tview.NewTableCell(h).
+			SetTextColor(v.Theme.Secondary).
+			SetAttributes(tcell.AttrBold).
+			SetSelectable(false).
+			SetExpansion(1))
+	}
+	return t
+}
+
+// NewInputDialog creates a single-line input with a title, for prompts
+// such as "restore to path".
+func (v *View) NewInputDialog(title, initial string) *tview.InputField {
+	inp := tview.NewInputField().
+		SetText(initial).
+		SetFieldTextColor(v.Theme.Text)
+	inp.SetBorder(true).SetTitle(title)
+	return inp
+}
+
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values as one line of block characters scaled
+// between their minimum and maximum. Values that do not scale (NaN,
+// infinities, or a range too wide for float64) are drawn as the lowest
+// bar.
+func Sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsInf(v, 0) && !math.IsNaN(v) {
+			lo, hi = min(lo, v), max(hi, v)
+		}
+	}
+	top := len(sparkBars) - 1
+	out := make([]rune, len(values))
+	for i, v := range values {
+		idx := 0
+		if f := (v - lo) / (hi - lo) * float64(top); hi > lo && f >= 0 && f <= float64(top) {
+			idx = int(f)
+		}
+		out[i] = sparkBars[idx]
+	}
+	return string(out)
+}